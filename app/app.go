@@ -0,0 +1,316 @@
+package app
+
+import (
+	"book-manage/config"
+	"book-manage/database"
+	"book-manage/handlers"
+	"book-manage/metrics"
+	"book-manage/middleware"
+	"book-manage/repository"
+	"book-manage/services"
+	"book-manage/tracing"
+	"book-manage/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Options 可替换的外部依赖，为空时按配置创建（测试时替换为内存实现）
+type Options struct {
+	Mailer services.Mailer     // 邮件发送器
+	Images services.ImageStore // 图书封面存储
+}
+
+// App 应用实例
+type App struct {
+	Router  *gin.Engine // 包含全部接口的Gin路由
+	Workers *Lifecycle  // 后台任务（验证码清理、借阅权限巡检、邮件发件箱），由调用方启动和停止
+}
+
+// New 初始化数据库和各项服务，创建包含全部接口的Gin路由并注册后台任务
+func New(cfg *config.Config, opts Options) (*App, error) {
+	// 设置JWT密钥
+	utils.SetJWTSecret(cfg.JWT.Secret)
+
+	// 初始化数据库（执行未执行的迁移）
+	if err := database.InitDB(cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// 初始化数据访问层
+	db := database.GetDB()
+	bookRepo := repository.NewGormBookRepo(db)
+	userRepo := repository.NewGormUserRepo(db)
+	loanRepo := repository.NewGormLoanRepo(db)
+	roleRepo := repository.NewGormRoleRepo(db)
+	apiKeyRepo := repository.NewGormAPIKeyRepo(db)
+	invitationRepo := repository.NewGormInvitationRepo(db)
+	tokenRepo := repository.NewGormTokenRepo(db)
+	outboxRepo := repository.NewGormOutboxRepo(db)
+	templateRepo := repository.NewGormTemplateRepo(db)
+	codeRecordRepo := repository.NewGormCodeRecordRepo(db)
+
+	// 初始化管理员服务
+	adminService := services.NewAdminService(cfg, userRepo)
+
+	// 初始化角色权限服务（写入内置权限和角色）
+	rbacService, err := services.NewRBACService(context.Background(), cfg, roleRepo, userRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize RBAC service: %w", err)
+	}
+
+	// 初始化API密钥服务
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+
+	// 初始化token吊销服务
+	tokenService := services.NewTokenService(tokenRepo)
+
+	// 初始化账户服务
+	accountService := services.NewAccountService(userRepo, loanRepo, codeRecordRepo, apiKeyRepo, tokenService)
+
+	// 初始化借阅权限暂停服务（后台按规则自动暂停和解除）
+	suspensionService := services.NewSuspensionService(&cfg.Suspension, userRepo, loanRepo)
+
+	// 初始化密码哈希和密码策略
+	hasher, err := services.NewPasswordHasher(&cfg.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize password hasher: %w", err)
+	}
+	policy := services.NewPasswordPolicy(&cfg.PasswordPolicy)
+
+	// 初始化注册服务（注册模式、邀请）
+	registrationService, err := services.NewRegistrationService(&cfg.Registration, invitationRepo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize registration service: %w", err)
+	}
+
+	// 初始化验证码存储
+	codeRepo, err := repository.NewCodeRepo(&cfg.CodeStore, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize code store: %w", err)
+	}
+
+	// 初始化邮件模板服务
+	templateService := services.NewTemplateService(templateRepo)
+
+	// 初始化邮件发送器（未替换时按配置创建）
+	mailer := opts.Mailer
+	if mailer == nil {
+		mailer, err = services.NewMailer(&cfg.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+		}
+	}
+
+	// 初始化邮件发件箱（后台异步发送并自动重试）
+	outbox := services.NewOutboxService(outboxRepo, mailer)
+
+	// 初始化邮件服务
+	emailService := services.NewEmailService(&cfg.Email, outbox, templateService, codeRepo, codeRecordRepo,
+		cfg.CodeStore.MaxAttempts)
+
+	// 注册后台任务
+	workers := &Lifecycle{}
+	workers.Add("email-code-cleanup", emailService.CleanupExpiredCodes)
+	workers.Add("suspension-sweep", suspensionService.Run)
+	workers.Add("email-outbox", outbox.Run)
+
+	// 初始化图片存储（未替换时使用R2服务）
+	images := opts.Images
+	if images == nil {
+		r2Service, err := services.NewR2Service(&cfg.CloudflareR2)
+		if err != nil {
+			slog.Warn("R2服务初始化失败，图片上传功能将不可用", "error", err)
+		}
+		images = r2Service
+	}
+
+	// 初始化认证和权限校验中间件
+	auth := middleware.NewAuth(tokenService, apiKeyService, adminService, rbacService)
+
+	// 创建业务服务
+	authService := services.NewAuthService(userRepo, hasher, policy, rbacService, adminService)
+	userService := services.NewUserService(userRepo, loanRepo, suspensionService)
+	loanService := services.NewLoanService(bookRepo, loanRepo, userRepo, suspensionService)
+	importService := services.NewImportService(userRepo, hasher, policy, emailService, registrationService)
+
+	// 创建接口
+	bookHandler := handlers.NewBookHandler(services.NewBookService(bookRepo, loanRepo), images)
+	borrowHandler := handlers.NewBorrowHandler(loanService)
+	userHandler := handlers.NewUserHandler(userService)
+	authHandler := handlers.NewAuthHandler(authService, emailService, registrationService, accountService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	adminHandler := handlers.NewAdminHandler(emailService, outbox)
+	roleHandler := handlers.NewRoleHandler(rbacService)
+	templateHandler := handlers.NewEmailTemplateHandler(templateService)
+	userAdminHandler := handlers.NewUserAdminHandler(userService, suspensionService, importService)
+	invitationHandler := handlers.NewInvitationHandler(registrationService, emailService, rbacService, authService)
+
+	// 创建Gin路由
+	r := gin.New()
+
+	// 添加请求ID、链路追踪、访问日志、panic恢复、请求指标和CORS中间件
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(tracing.Middleware())
+	r.Use(middleware.AccessLogMiddleware())
+	r.Use(middleware.RecoveryMiddleware())
+	r.Use(metrics.Middleware())
+	r.Use(middleware.CORSMiddleware())
+
+	// 健康检查（无需登录）
+	healthHandler := handlers.NewHealthHandler(services.NewHealthService(db, images, mailer, &cfg.Email))
+	r.GET("/healthz", healthHandler.Healthz)
+	r.GET("/readyz", healthHandler.Readyz)
+	r.GET("/version", healthHandler.Version)
+
+	// Prometheus指标（配置 metrics.token 时需携带 Authorization: Bearer <token>）
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database pool: %w", err)
+	}
+	if err := metrics.RegisterDBStats(sqlDB, cfg.Database.Driver); err != nil {
+		return nil, fmt.Errorf("failed to register database metrics: %w", err)
+	}
+	if err := metrics.RegisterOverdueLoans(func() (int64, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		return loanService.OverdueCount(ctx)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to register loan metrics: %w", err)
+	}
+	r.GET("/metrics", metrics.Handler(cfg.Metrics.Token))
+
+	// 用户管理模块（无需登录）
+	userGroup := r.Group("/api/user")
+	{
+		userGroup.POST("/register", authHandler.Register)
+		userGroup.POST("/login", authHandler.Login)
+		userGroup.POST("/loginByCode", authHandler.LoginByCode)
+		userGroup.POST("/loginByLink", authHandler.LoginByLink)
+		userGroup.POST("/sendEmailCode", authHandler.SendEmailCode)
+		userGroup.POST("/forgetPassword", authHandler.ForgetPassword)
+		userGroup.POST("/setPassword", authHandler.SetPassword)
+	}
+
+	// 用户管理模块（需要登录）
+	userAuthGroup := r.Group("/api/user")
+	userAuthGroup.Use(auth.AuthMiddleware())
+	{
+		userAuthGroup.POST("/profile", userHandler.Profile)
+		userAuthGroup.POST("/updateProfile", userHandler.UpdateProfile)
+		userAuthGroup.POST("/changePassword", authHandler.ChangePassword)
+		userAuthGroup.POST("/changeEmail", authHandler.ChangeEmail)
+		userAuthGroup.POST("/exportData", authHandler.ExportData)
+		userAuthGroup.POST("/deleteAccount", authHandler.DeleteAccount)
+		userAuthGroup.POST("/borrowRecords", borrowHandler.BorrowRecords)
+		userAuthGroup.POST("/apiKeys/create", apiKeyHandler.CreateAPIKey)
+		userAuthGroup.POST("/apiKeys/list", apiKeyHandler.ListAPIKeys)
+		userAuthGroup.POST("/apiKeys/revoke", apiKeyHandler.RevokeAPIKey)
+	}
+
+	// 图书管理模块（需要登录）
+	bookGroup := r.Group("/api/book")
+	bookGroup.Use(auth.AuthMiddleware())
+	{
+		bookGroup.POST("/detail", bookHandler.BookDetail)
+		bookGroup.POST("/search", bookHandler.BookSearch)
+	}
+
+	// 图书管理模块（需要图书编辑权限）
+	bookAdminGroup := r.Group("/api/book")
+	bookAdminGroup.Use(auth.AuthMiddleware())
+	bookAdminGroup.Use(auth.RequirePermission(services.PermBookEdit))
+	{
+		bookAdminGroup.POST("/add", bookHandler.AddBook)
+		bookAdminGroup.POST("/edit", bookHandler.EditBook)
+		bookAdminGroup.POST("/delete", bookHandler.DeleteBook)
+		bookAdminGroup.POST("/uploadCover", bookHandler.UploadCover)
+		bookAdminGroup.POST("/deleteCover", bookHandler.DeleteCover)
+	}
+
+	// 借阅管理模块（需要登录）
+	borrowGroup := r.Group("/api/borrow")
+	borrowGroup.Use(auth.AuthMiddleware())
+	{
+		borrowGroup.POST("/borrow", borrowHandler.Borrow)
+		borrowGroup.POST("/return", borrowHandler.Return)
+		borrowGroup.POST("/records", borrowHandler.BorrowRecords)
+	}
+
+	// 借阅管理模块（需要借阅管理权限）
+	borrowAdminGroup := r.Group("/api/borrow")
+	borrowAdminGroup.Use(auth.AuthMiddleware())
+	borrowAdminGroup.Use(auth.RequirePermission(services.PermBorrowManage))
+	{
+		borrowAdminGroup.POST("/allRecords", borrowHandler.AllRecords)
+	}
+
+	// 管理员模块：验证码记录（需要验证码查看权限）
+	emailCodeAdminGroup := r.Group("/api/admin")
+	emailCodeAdminGroup.Use(auth.AuthMiddleware())
+	emailCodeAdminGroup.Use(auth.RequirePermission(services.PermEmailCodeView))
+	{
+		emailCodeAdminGroup.POST("/emailCodeList", adminHandler.EmailCodeList)
+		emailCodeAdminGroup.POST("/emailCodeStats", adminHandler.EmailCodeStats)
+	}
+
+	// 管理员模块：角色权限（需要角色管理权限）
+	roleAdminGroup := r.Group("/api/admin")
+	roleAdminGroup.Use(auth.AuthMiddleware())
+	roleAdminGroup.Use(auth.RequirePermission(services.PermRoleManage))
+	{
+		roleAdminGroup.POST("/permissions/list", roleHandler.PermissionList)
+		roleAdminGroup.POST("/roles/list", roleHandler.RoleList)
+		roleAdminGroup.POST("/roles/create", roleHandler.CreateRole)
+		roleAdminGroup.POST("/roles/update", roleHandler.UpdateRole)
+		roleAdminGroup.POST("/roles/delete", roleHandler.DeleteRole)
+		roleAdminGroup.POST("/users/setRole", roleHandler.SetUserRole)
+	}
+
+	// 管理员模块：邮件模板（需要邮件模板管理权限）
+	templateAdminGroup := r.Group("/api/admin")
+	templateAdminGroup.Use(auth.AuthMiddleware())
+	templateAdminGroup.Use(auth.RequirePermission(services.PermTemplateManage))
+	{
+		templateAdminGroup.POST("/emailTemplates/list", templateHandler.EmailTemplateList)
+		templateAdminGroup.POST("/emailTemplates/save", templateHandler.SaveEmailTemplate)
+		templateAdminGroup.POST("/emailTemplates/reset", templateHandler.ResetEmailTemplate)
+		templateAdminGroup.POST("/emailTemplates/preview", templateHandler.PreviewEmailTemplate)
+	}
+
+	// 管理员模块：邮件发件箱（需要发件箱管理权限）
+	outboxAdminGroup := r.Group("/api/admin")
+	outboxAdminGroup.Use(auth.AuthMiddleware())
+	outboxAdminGroup.Use(auth.RequirePermission(services.PermOutboxManage))
+	{
+		outboxAdminGroup.POST("/emailOutbox/list", adminHandler.EmailOutboxList)
+		outboxAdminGroup.POST("/emailOutbox/retry", adminHandler.EmailOutboxRetry)
+	}
+
+	// 管理员模块：读者管理（需要读者管理权限）
+	userAdminGroup := r.Group("/api/admin")
+	userAdminGroup.Use(auth.AuthMiddleware())
+	userAdminGroup.Use(auth.RequirePermission(services.PermUserManage))
+	{
+		userAdminGroup.POST("/users/list", userAdminHandler.UserList)
+		userAdminGroup.POST("/users/update", userAdminHandler.UpdateUser)
+		userAdminGroup.POST("/users/suspend", userAdminHandler.SuspendUser)
+		userAdminGroup.POST("/users/unsuspend", userAdminHandler.UnsuspendUser)
+		userAdminGroup.POST("/users/import", userAdminHandler.ImportUsers)
+	}
+
+	// 管理员模块：注册邀请（需要邀请管理权限）
+	invitationAdminGroup := r.Group("/api/admin")
+	invitationAdminGroup.Use(auth.AuthMiddleware())
+	invitationAdminGroup.Use(auth.RequirePermission(services.PermInviteManage))
+	{
+		invitationAdminGroup.POST("/invitations/create", invitationHandler.CreateInvitation)
+		invitationAdminGroup.POST("/invitations/list", invitationHandler.InvitationList)
+		invitationAdminGroup.POST("/invitations/revoke", invitationHandler.RevokeInvitation)
+	}
+
+	return &App{Router: r, Workers: workers}, nil
+}
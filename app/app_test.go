@@ -0,0 +1,751 @@
+package app_test
+
+import (
+	"book-manage/app"
+	"book-manage/config"
+	"book-manage/database"
+	"book-manage/models"
+	"book-manage/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminEmail   = "admin@example.com"
+	testPassword = "Shelf-Pass-2026"
+)
+
+var (
+	router *gin.Engine
+	mailer *services.LogMailer
+	images *fakeImageStore
+)
+
+// 服务为进程内单例，整个测试二进制共用一个应用实例和内存数据库
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	mailer = services.NewLogMailer("")
+	images = newFakeImageStore()
+
+	application, err := app.New(testConfig(), app.Options{Mailer: mailer, Images: images})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init app: %v\n", err)
+		os.Exit(1)
+	}
+	router = application.Router
+
+	// 发件箱worker负责发送验证码邮件
+	application.Workers.Start(context.Background())
+	code := m.Run()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := application.Workers.Stop(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "stop workers: %v\n", err)
+		code = 1
+	}
+	database.Close()
+	os.Exit(code)
+}
+
+// testConfig 使用SQLite内存数据库和日志邮件发送器的配置
+func testConfig() *config.Config {
+	return &config.Config{
+		Database:       config.DatabaseConfig{Driver: "sqlite", Path: ":memory:"},
+		JWT:            config.JWTConfig{Secret: "e2e-test-secret"},
+		Email:          config.EmailConfig{Provider: "log"},
+		AdminEmails:    []string{adminEmail},
+		CodeStore:      config.CodeStoreConfig{Driver: "postgres", MaxAttempts: 5},
+		PasswordPolicy: config.PasswordPolicyConfig{MinLength: 8, MinCharClasses: 2, HistorySize: 5},
+		PasswordHash:   config.PasswordHashConfig{Algorithm: "bcrypt", BcryptCost: 4},
+		Registration:   config.RegistrationConfig{Mode: "open", InviteTTLDays: 7},
+		Suspension:     config.SuspensionConfig{OverdueDays: 30, CheckIntervalMinutes: 60},
+	}
+}
+
+// fakeImageStore 内存图片存储
+type fakeImageStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	seq     int
+}
+
+func newFakeImageStore() *fakeImageStore {
+	return &fakeImageStore{objects: make(map[string][]byte)}
+}
+
+func (s *fakeImageStore) IsEnabled() bool { return true }
+
+func (s *fakeImageStore) UploadImage(ctx context.Context, bookID int, imageData []byte, filename string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	url := fmt.Sprintf("https://images.test/book-covers/%d_%d_%s", bookID, s.seq, filename)
+	s.objects[url] = append([]byte(nil), imageData...)
+	return url, nil
+}
+
+func (s *fakeImageStore) DeleteImage(ctx context.Context, imageURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[imageURL]; !ok {
+		return fmt.Errorf("object not found: %s", imageURL)
+	}
+	delete(s.objects, imageURL)
+	return nil
+}
+
+func (s *fakeImageStore) CheckBucket(ctx context.Context) error { return nil }
+
+func (s *fakeImageStore) has(imageURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[imageURL]
+	return ok
+}
+
+// apiResponse 统一响应结构
+type apiResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decode 将响应数据解析到 v
+func (r apiResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", r.Data, err)
+	}
+}
+
+// serve 发送请求并解析统一响应结构
+func serve(t *testing.T, req *http.Request) apiResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: http status = %d, body = %s", req.URL.Path, w.Code, w.Body.String())
+	}
+	var resp apiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s: decode response %q: %v", req.URL.Path, w.Body.String(), err)
+	}
+	return resp
+}
+
+// post 发送JSON请求，token 为空时不携带认证信息
+func post(t *testing.T, path, token string, body interface{}) apiResponse {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("marshal body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serve(t, req)
+}
+
+// expectCode 断言业务状态码
+func expectCode(t *testing.T, resp apiResponse, code int) {
+	t.Helper()
+	if resp.Code != code {
+		t.Fatalf("code = %d (%s), want %d", resp.Code, resp.Message, code)
+	}
+}
+
+var codePattern = regexp.MustCompile(`\b(\d{6})\b`)
+
+// sendCode 请求验证码并从发件箱中取出，发件箱由后台worker异步发送
+func sendCode(t *testing.T, email, action string) string {
+	t.Helper()
+	sent := len(messagesTo(email))
+	expectCode(t, post(t, "/api/user/sendEmailCode", "", map[string]string{"email": email, "action": action}), 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if msgs := messagesTo(email); len(msgs) > sent {
+			match := codePattern.FindStringSubmatch(msgs[len(msgs)-1].Text)
+			if match == nil {
+				t.Fatalf("no code in email: %q", msgs[len(msgs)-1].Text)
+			}
+			return match[1]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s email delivered to %s", action, email)
+	return ""
+}
+
+// messagesTo 发送给指定邮箱的邮件
+func messagesTo(email string) []services.Message {
+	var msgs []services.Message
+	for _, msg := range mailer.Messages() {
+		for _, to := range msg.To {
+			if to == email {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	return msgs
+}
+
+// registerAndLogin 通过验证码注册并登录，返回token
+func registerAndLogin(t *testing.T, email string) string {
+	t.Helper()
+	code := sendCode(t, email, "register")
+	expectCode(t, post(t, "/api/user/register", "", map[string]string{
+		"email":            email,
+		"password":         testPassword,
+		"confirm_password": testPassword,
+		"code":             code,
+	}), 0)
+	return login(t, email, testPassword)
+}
+
+// login 密码登录，返回token
+func login(t *testing.T, email, password string) string {
+	t.Helper()
+	resp := post(t, "/api/user/login", "", map[string]string{"email": email, "password": password})
+	expectCode(t, resp, 0)
+	var data struct {
+		Token string `json:"token"`
+	}
+	resp.decode(t, &data)
+	return data.Token
+}
+
+var (
+	adminOnce  sync.Once
+	adminToken string
+)
+
+// admin 获取管理员token（邮箱在管理员白名单中）
+func admin(t *testing.T) string {
+	t.Helper()
+	adminOnce.Do(func() {
+		adminToken = registerAndLogin(t, adminEmail)
+	})
+	if adminToken == "" {
+		t.Fatal("admin login failed")
+	}
+	return adminToken
+}
+
+// addBook 添加图书并按书名查找ID（书名需唯一）
+func addBook(t *testing.T, title string, quantity int) int {
+	t.Helper()
+	token := admin(t)
+	expectCode(t, post(t, "/api/book/add", token, map[string]interface{}{
+		"title":          title,
+		"author":         "E2E",
+		"isbn":           "isbn-" + title,
+		"category":       "test",
+		"total_quantity": quantity,
+	}), 0)
+
+	resp := post(t, "/api/book/search", token, map[string]interface{}{"keyword": title})
+	expectCode(t, resp, 0)
+	var data struct {
+		Books []struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+		} `json:"books"`
+	}
+	resp.decode(t, &data)
+	for _, book := range data.Books {
+		if book.Title == title {
+			return book.ID
+		}
+	}
+	t.Fatalf("book %q not found after add", title)
+	return 0
+}
+
+type bookDetail struct {
+	ID                int    `json:"id"`
+	Title             string `json:"title"`
+	TotalQuantity     int    `json:"total_quantity"`
+	AvailableQuantity int    `json:"available_quantity"`
+	CoverImageURL     string `json:"cover_image_url"`
+}
+
+// getBook 获取图书详情
+func getBook(t *testing.T, token string, id int) bookDetail {
+	t.Helper()
+	resp := post(t, "/api/book/detail", token, map[string]int{"id": id})
+	expectCode(t, resp, 0)
+	var data struct {
+		Book bookDetail `json:"book"`
+	}
+	resp.decode(t, &data)
+	return data.Book
+}
+
+func TestRegisterLoginAndCodeFlows(t *testing.T) {
+	const email = "reader-auth@example.com"
+
+	// 未请求验证码或验证码错误时不能注册
+	expectCode(t, post(t, "/api/user/register", "", map[string]string{
+		"email": email, "password": testPassword, "confirm_password": testPassword, "code": "000000",
+	}), 10004)
+
+	code := sendCode(t, email, "register")
+	expectCode(t, post(t, "/api/user/register", "", map[string]string{
+		"email": email, "password": testPassword, "confirm_password": testPassword + "x", "code": code,
+	}), 10005)
+	expectCode(t, post(t, "/api/user/register", "", map[string]string{
+		"email": email, "password": testPassword, "confirm_password": testPassword, "code": code,
+	}), 0)
+
+	// 验证码只能使用一次
+	expectCode(t, post(t, "/api/user/register", "", map[string]string{
+		"email": email, "password": testPassword, "confirm_password": testPassword, "code": code,
+	}), 10004)
+
+	// 已注册邮箱不能再次请求注册验证码
+	expectCode(t, post(t, "/api/user/sendEmailCode", "", map[string]string{"email": email, "action": "register"}), 10003)
+
+	expectCode(t, post(t, "/api/user/login", "", map[string]string{"email": email, "password": "Wrong-Pass-2026"}), 10007)
+	token := login(t, email, testPassword)
+
+	resp := post(t, "/api/user/profile", token, map[string]string{})
+	expectCode(t, resp, 0)
+	if !strings.Contains(string(resp.Data), email) {
+		t.Errorf("profile = %s, want email %s", resp.Data, email)
+	}
+	expectCode(t, post(t, "/api/user/profile", "", map[string]string{}), 10001)
+	expectCode(t, post(t, "/api/user/profile", "not-a-token", map[string]string{}), 10001)
+
+	// 验证码登录
+	expectCode(t, post(t, "/api/user/sendEmailCode", "", map[string]string{"email": "nobody@example.com", "action": "login"}), 10008)
+	loginCode := sendCode(t, email, "login")
+	expectCode(t, post(t, "/api/user/loginByCode", "", map[string]string{"email": email, "code": "000000"}), 10004)
+	resp = post(t, "/api/user/loginByCode", "", map[string]string{"email": email, "code": loginCode})
+	expectCode(t, resp, 0)
+	var data struct {
+		Token string `json:"token"`
+	}
+	resp.decode(t, &data)
+	expectCode(t, post(t, "/api/user/profile", data.Token, map[string]string{}), 0)
+}
+
+func TestBookCRUD(t *testing.T) {
+	token := admin(t)
+	reader := registerAndLogin(t, "reader-books@example.com")
+
+	// 普通读者可以查询，但不能编辑图书
+	expectCode(t, post(t, "/api/book/add", reader, map[string]interface{}{
+		"title": "Forbidden", "author": "E2E", "isbn": "isbn-forbidden", "category": "test", "total_quantity": 1,
+	}), 10009)
+
+	id := addBook(t, "Crud Book", 3)
+	expectCode(t, post(t, "/api/book/add", token, map[string]interface{}{
+		"title": "Crud Copy", "author": "E2E", "isbn": "isbn-Crud Book", "category": "test", "total_quantity": 1,
+	}), 10017)
+
+	book := getBook(t, reader, id)
+	if book.Title != "Crud Book" || book.TotalQuantity != 3 || book.AvailableQuantity != 3 {
+		t.Errorf("detail = %+v", book)
+	}
+	expectCode(t, post(t, "/api/book/detail", reader, map[string]int{"id": 99999}), 10010)
+	expectCode(t, post(t, "/api/book/search", reader, map[string]string{"keyword": "x"}), 10019)
+	expectCode(t, post(t, "/api/book/search", reader, map[string]string{"keyword": "No Such Title"}), 10020)
+
+	expectCode(t, post(t, "/api/book/edit", reader, map[string]interface{}{"id": id, "title": "Hijacked"}), 10009)
+	expectCode(t, post(t, "/api/book/edit", token, map[string]interface{}{"id": id, "title": "Crud Book 2", "total_quantity": 4}), 0)
+	book = getBook(t, reader, id)
+	if book.Title != "Crud Book 2" || book.TotalQuantity != 4 || book.AvailableQuantity != 4 {
+		t.Errorf("detail after edit = %+v", book)
+	}
+
+	// 借出后总数量不能小于已借出数量，也不能删除
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": id}), 0)
+	expectCode(t, post(t, "/api/borrow/borrow", token, map[string]int{"book_id": id}), 0)
+	expectCode(t, post(t, "/api/book/edit", token, map[string]interface{}{"id": id, "total_quantity": 1}), 10018)
+	expectCode(t, post(t, "/api/book/delete", token, map[string]int{"id": id}), 10014)
+
+	expectCode(t, post(t, "/api/book/delete", reader, map[string]int{"id": id}), 10009)
+	other := addBook(t, "Crud Delete", 1)
+	expectCode(t, post(t, "/api/book/delete", token, map[string]int{"id": other}), 0)
+	expectCode(t, post(t, "/api/book/detail", reader, map[string]int{"id": other}), 10010)
+}
+
+func TestBorrowAndReturnLimits(t *testing.T) {
+	reader := registerAndLogin(t, "reader-loans@example.com")
+	other := registerAndLogin(t, "reader-other@example.com")
+
+	single := addBook(t, "Loan Single", 1)
+	expectCode(t, post(t, "/api/borrow/borrow", other, map[string]int{"book_id": single}), 0)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": single}), 10011)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": 99999}), 10010)
+
+	var ids []int
+	for i := 0; i <= services.MaxOpenLoans; i++ {
+		ids = append(ids, addBook(t, fmt.Sprintf("Loan Limit %d", i), 2))
+	}
+	for _, id := range ids[:services.MaxOpenLoans] {
+		expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": id}), 0)
+	}
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": ids[0]}), 10012)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": ids[services.MaxOpenLoans]}), 10012)
+	if book := getBook(t, reader, ids[0]); book.AvailableQuantity != 1 {
+		t.Errorf("available after borrow = %d, want 1", book.AvailableQuantity)
+	}
+
+	expectCode(t, post(t, "/api/borrow/return", reader, map[string]int{"book_id": ids[0]}), 0)
+	expectCode(t, post(t, "/api/borrow/return", reader, map[string]int{"book_id": ids[0]}), 10015)
+	if book := getBook(t, reader, ids[0]); book.AvailableQuantity != 2 {
+		t.Errorf("available after return = %d, want 2", book.AvailableQuantity)
+	}
+
+	// 归还后可以继续借阅，同一本书未归还时不能重复借阅
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": ids[services.MaxOpenLoans]}), 0)
+	expectCode(t, post(t, "/api/borrow/return", reader, map[string]int{"book_id": ids[1]}), 0)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": ids[2]}), 10013)
+
+	resp := post(t, "/api/borrow/records", reader, map[string]string{"status": "all"})
+	expectCode(t, resp, 0)
+	var records struct {
+		Total int `json:"total"`
+	}
+	resp.decode(t, &records)
+	if records.Total != services.MaxOpenLoans+1 {
+		t.Errorf("records total = %d, want %d", records.Total, services.MaxOpenLoans+1)
+	}
+	resp = post(t, "/api/borrow/records", reader, map[string]string{"status": "borrowed"})
+	expectCode(t, resp, 0)
+	resp.decode(t, &records)
+	if records.Total != services.MaxOpenLoans-1 {
+		t.Errorf("open records total = %d, want %d", records.Total, services.MaxOpenLoans-1)
+	}
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	token := admin(t)
+	reader := registerAndLogin(t, "reader-admin@example.com")
+
+	adminOnly := []string{
+		"/api/borrow/allRecords",
+		"/api/admin/users/list",
+		"/api/admin/roles/list",
+		"/api/admin/emailCodeList",
+		"/api/admin/emailOutbox/list",
+		"/api/admin/invitations/list",
+	}
+	for _, path := range adminOnly {
+		t.Run(path, func(t *testing.T) {
+			page := map[string]int{"page": 1, "limit": 10}
+			expectCode(t, post(t, path, "", page), 10001)
+			expectCode(t, post(t, path, reader, page), 10009)
+			expectCode(t, post(t, path, token, page), 0)
+		})
+	}
+
+	// 管理员查询全部借阅记录可按读者邮箱筛选
+	id := addBook(t, "Admin Records", 1)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": id}), 0)
+	resp := post(t, "/api/borrow/allRecords", token, map[string]string{"user_email": "reader-admin@example.com"})
+	expectCode(t, resp, 0)
+	var records struct {
+		Total int `json:"total"`
+	}
+	resp.decode(t, &records)
+	if records.Total != 1 {
+		t.Errorf("records total = %d, want 1", records.Total)
+	}
+}
+
+// uploadCover 以multipart表单上传封面
+func uploadCover(t *testing.T, token string, bookID int, filename string, data []byte) apiResponse {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("book_id", fmt.Sprint(bookID))
+	part, err := form.CreateFormFile("image", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/book/uploadCover", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return serve(t, req)
+}
+
+func TestCoverUpload(t *testing.T) {
+	token := admin(t)
+	reader := registerAndLogin(t, "reader-cover@example.com")
+	id := addBook(t, "Cover Book", 1)
+	image := []byte("\x89PNG fake image")
+
+	expectCode(t, uploadCover(t, reader, id, "cover.png", image), 10009)
+	expectCode(t, uploadCover(t, token, id, "cover.txt", image), 10021)
+	expectCode(t, uploadCover(t, token, 99999, "cover.png", image), 10010)
+
+	resp := uploadCover(t, token, id, "cover.png", image)
+	expectCode(t, resp, 0)
+	var uploaded struct {
+		ImageURL string `json:"image_url"`
+	}
+	resp.decode(t, &uploaded)
+	if !images.has(uploaded.ImageURL) {
+		t.Fatalf("image %s not stored", uploaded.ImageURL)
+	}
+	if book := getBook(t, reader, id); book.CoverImageURL != uploaded.ImageURL {
+		t.Errorf("cover = %q, want %q", book.CoverImageURL, uploaded.ImageURL)
+	}
+
+	// 重新上传时删除旧封面
+	resp = uploadCover(t, token, id, "cover.jpg", image)
+	expectCode(t, resp, 0)
+	var replaced struct {
+		ImageURL string `json:"image_url"`
+	}
+	resp.decode(t, &replaced)
+	if images.has(uploaded.ImageURL) || !images.has(replaced.ImageURL) {
+		t.Errorf("old cover kept or new cover missing: old=%s new=%s", uploaded.ImageURL, replaced.ImageURL)
+	}
+
+	expectCode(t, post(t, "/api/book/deleteCover", reader, map[string]int{"book_id": id}), 10009)
+	expectCode(t, post(t, "/api/book/deleteCover", token, map[string]int{"book_id": id}), 0)
+	if images.has(replaced.ImageURL) {
+		t.Errorf("cover %s not deleted from store", replaced.ImageURL)
+	}
+	if book := getBook(t, reader, id); book.CoverImageURL != "" {
+		t.Errorf("cover after delete = %q", book.CoverImageURL)
+	}
+	expectCode(t, post(t, "/api/book/deleteCover", token, map[string]int{"book_id": id}), 10024)
+}
+
+// getJSON 发送GET请求，返回HTTP状态码和解析后的响应
+func getJSON(t *testing.T, path string, v interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: decode response %q: %v", path, w.Body.String(), err)
+	}
+	return w.Code
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if code := getJSON(t, "/healthz", &health); code != http.StatusOK || health.Status != "ok" {
+		t.Errorf("healthz = %d %+v", code, health)
+	}
+
+	var ready struct {
+		Status string                          `json:"status"`
+		Checks map[string]services.HealthCheck `json:"checks"`
+	}
+	if code := getJSON(t, "/readyz", &ready); code != http.StatusOK || ready.Status != "ok" {
+		t.Errorf("readyz = %d %+v", code, ready)
+	}
+	for _, name := range []string{"database", "migrations", "storage", "mailer"} {
+		if check, ok := ready.Checks[name]; !ok || check.Status != services.HealthOK {
+			t.Errorf("check %s = %+v", name, check)
+		}
+	}
+
+	var version struct {
+		Version   string `json:"version"`
+		GoVersion string `json:"go_version"`
+	}
+	if code := getJSON(t, "/version", &version); code != http.StatusOK || version.Version == "" || version.GoVersion == "" {
+		t.Errorf("version = %d %+v", code, version)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	reader := registerAndLogin(t, "reader-metrics@example.com")
+	id := addBook(t, "Metrics Book", 1)
+	expectCode(t, post(t, "/api/borrow/borrow", reader, map[string]int{"book_id": id}), 0)
+	expectCode(t, post(t, "/api/borrow/return", reader, map[string]int{"book_id": id}), 0)
+	expectCode(t, post(t, "/api/book/detail", reader, map[string]int{"id": 99999}), 10010)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics status = %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`book_manage_http_request_duration_seconds_count{code="10010",method="POST",route="/api/book/detail",status="200"}`,
+		`book_manage_borrows_total`,
+		`book_manage_returns_total`,
+		`book_manage_overdue_loans 0`,
+		`book_manage_emails_total{category="code_register",result="sent"}`,
+		`go_sql_open_connections{db_name="sqlite"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	// 沿用客户端传入的合法请求ID
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("X-Request-ID", "client-req.1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "client-req.1" {
+		t.Errorf("X-Request-ID = %q, want client-req.1", got)
+	}
+
+	// 未传入或格式不合法时重新生成
+	for _, header := range []string{"", "bad id\nwith newline"} {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		if header != "" {
+			req.Header.Set("X-Request-ID", header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Header().Get("X-Request-ID"); got == "" || got == header {
+			t.Errorf("X-Request-ID = %q for request header %q", got, header)
+		}
+	}
+}
+
+func TestOutboxClearsSentBodies(t *testing.T) {
+	email := "outbox-body@example.com"
+	sendCode(t, email, "register")
+
+	// 邮件发送成功后正文（含验证码）被清除
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var item models.EmailOutbox
+		if err := database.GetDB().Where("to_email = ?", email).First(&item).Error; err != nil {
+			t.Fatalf("outbox item: %v", err)
+		}
+		if item.Status == models.OutboxStatusSent {
+			if item.HTMLBody != "" || item.TextBody != "" {
+				t.Fatalf("sent email body should be cleared")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("email was not marked as sent, status %s", item.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInvitationRoleCannotExceedInviter(t *testing.T) {
+	token := admin(t)
+	email := "inviter@example.com"
+	registerAndLogin(t, email)
+	var inviter models.User
+	if err := database.GetDB().Where("email = ?", email).First(&inviter).Error; err != nil {
+		t.Fatalf("find inviter: %v", err)
+	}
+
+	expectCode(t, post(t, "/api/admin/roles/create", token, map[string]interface{}{
+		"name":        "inviter",
+		"permissions": []string{services.PermInviteManage},
+	}), 0)
+	expectCode(t, post(t, "/api/admin/users/setRole", token, map[string]interface{}{
+		"user_id": inviter.ID,
+		"role":    "inviter",
+	}), 0)
+	reader := login(t, email, testPassword)
+
+	// 仅有邀请权限时不能邀请管理员
+	expectCode(t, post(t, "/api/admin/invitations/create", reader, map[string]string{
+		"email": "invitee-admin@example.com",
+		"role":  services.RoleAdmin,
+	}), 10009)
+	expectCode(t, post(t, "/api/admin/invitations/create", reader, map[string]string{
+		"email": "invitee-user@example.com",
+	}), 0)
+	expectCode(t, post(t, "/api/admin/invitations/create", token, map[string]string{
+		"email": "invitee-admin@example.com",
+		"role":  services.RoleAdmin,
+	}), 0)
+}
+
+func TestRoleChangeTakesEffectImmediately(t *testing.T) {
+	token := admin(t)
+	email := "demoted@example.com"
+	reader := registerAndLogin(t, email)
+	var user models.User
+	if err := database.GetDB().Where("email = ?", email).First(&user).Error; err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	page := map[string]int{"page": 1, "limit": 10}
+
+	expectCode(t, post(t, "/api/admin/roles/create", token, map[string]interface{}{
+		"name":        "auditor",
+		"permissions": []string{services.PermOutboxManage},
+	}), 0)
+	var role models.Role
+	if err := database.GetDB().Where("name = ?", "auditor").First(&role).Error; err != nil {
+		t.Fatalf("find role: %v", err)
+	}
+
+	// 已签发的token无需重新登录即按新角色鉴权
+	expectCode(t, post(t, "/api/admin/emailOutbox/list", reader, page), 10009)
+	expectCode(t, post(t, "/api/admin/users/setRole", token, map[string]interface{}{"user_id": user.ID, "role": "auditor"}), 0)
+	expectCode(t, post(t, "/api/admin/emailOutbox/list", reader, page), 0)
+
+	// 收回角色权限后立即失效
+	expectCode(t, post(t, "/api/admin/roles/update", token, map[string]interface{}{"id": role.ID, "permissions": []string{}}), 0)
+	expectCode(t, post(t, "/api/admin/emailOutbox/list", reader, page), 10009)
+
+	expectCode(t, post(t, "/api/admin/roles/update", token, map[string]interface{}{"id": role.ID, "permissions": []string{services.PermOutboxManage}}), 0)
+	expectCode(t, post(t, "/api/admin/emailOutbox/list", reader, page), 0)
+	expectCode(t, post(t, "/api/admin/users/setRole", token, map[string]interface{}{"user_id": user.ID, "role": services.RoleUser}), 0)
+	expectCode(t, post(t, "/api/admin/emailOutbox/list", reader, page), 10009)
+}
+
+func TestUpdateUserRejectsTakenIdentifiers(t *testing.T) {
+	token := admin(t)
+	ids := make([]uint, 0, 2)
+	for _, email := range []string{"card-a@example.com", "card-b@example.com"} {
+		registerAndLogin(t, email)
+		var user models.User
+		if err := database.GetDB().Where("email = ?", email).First(&user).Error; err != nil {
+			t.Fatalf("find user: %v", err)
+		}
+		ids = append(ids, user.ID)
+	}
+
+	expectCode(t, post(t, "/api/admin/users/update", token, map[string]interface{}{
+		"user_id": ids[0], "card_number": "CARD-001", "student_id": "S-001",
+	}), 0)
+	// 同一用户重复保存不视为冲突
+	expectCode(t, post(t, "/api/admin/users/update", token, map[string]interface{}{
+		"user_id": ids[0], "card_number": "CARD-001", "student_id": "S-001",
+	}), 0)
+	expectCode(t, post(t, "/api/admin/users/update", token, map[string]interface{}{
+		"user_id": ids[1], "card_number": "CARD-001",
+	}), 10041)
+	expectCode(t, post(t, "/api/admin/users/update", token, map[string]interface{}{
+		"user_id": ids[1], "student_id": "S-001",
+	}), 10042)
+	expectCode(t, post(t, "/api/admin/users/update", token, map[string]interface{}{
+		"user_id": ids[1], "card_number": "CARD-002",
+	}), 0)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"book-manage/logging"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// lifecycleLog 后台任务日志
+var lifecycleLog = logging.For("lifecycle")
+
+// Worker 后台任务，ctx 取消后应尽快返回
+type Worker func(ctx context.Context)
+
+// namedWorker 带名称的后台任务（用于日志）
+type namedWorker struct {
+	name string
+	run  Worker
+}
+
+// Lifecycle 管理后台任务的启动和停止
+// Start 为每个任务启动一个goroutine，Stop 取消 ctx 并等待全部任务返回
+type Lifecycle struct {
+	mu      sync.Mutex
+	workers []namedWorker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Add 注册后台任务，需在 Start 之前调用
+func (l *Lifecycle) Add(name string, run Worker) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.workers = append(l.workers, namedWorker{name: name, run: run})
+}
+
+// Start 启动全部后台任务，重复调用无效
+func (l *Lifecycle) Start(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		return
+	}
+
+	ctx, l.cancel = context.WithCancel(ctx)
+	for _, w := range l.workers {
+		l.wg.Add(1)
+		go func(w namedWorker) {
+			defer l.wg.Done()
+			w.run(ctx)
+			lifecycleLog.Info("后台任务已停止", "worker", w.name)
+		}(w)
+	}
+	lifecycleLog.Info("后台任务已启动", "count", len(l.workers))
+}
+
+// Stop 通知全部后台任务停止并等待返回，ctx 超时后不再等待并返回错误
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	cancel := l.cancel
+	l.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not stop in time: %w", ctx.Err())
+	}
+}
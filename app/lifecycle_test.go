@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"book-manage/app"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLifecycleStopsWorkers(t *testing.T) {
+	var running int32
+	workers := &app.Lifecycle{}
+	for _, name := range []string{"a", "b"} {
+		workers.Add(name, func(ctx context.Context) {
+			atomic.AddInt32(&running, 1)
+			<-ctx.Done()
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	workers.Start(context.Background())
+	workers.Start(context.Background()) // 重复启动无效
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&running) != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&running); got != 2 {
+		t.Fatalf("running = %d, want 2", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := workers.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if got := atomic.LoadInt32(&running); got != 0 {
+		t.Errorf("running after stop = %d, want 0", got)
+	}
+}
+
+func TestLifecycleStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	workers := &app.Lifecycle{}
+	workers.Add("stuck", func(ctx context.Context) {
+		<-release // 忽略 ctx 取消
+	})
+	workers.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := workers.Stop(ctx); err == nil {
+		t.Error("stop err = nil, want timeout")
+	}
+}
@@ -0,0 +1,51 @@
+// Package buildinfo 构建信息，发布时通过 -ldflags 注入，如：
+//
+//	go build -ldflags "-X book-manage/buildinfo.Version=v1.2.0 -X book-manage/buildinfo.Commit=$(git rev-parse HEAD) -X book-manage/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 通过 -ldflags "-X" 注入的构建信息
+var (
+	Version   = "dev" // 版本号
+	Commit    = ""    // 提交哈希
+	BuildTime = ""    // 构建时间（UTC，RFC 3339）
+)
+
+// Info 构建信息
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get 获取构建信息
+// 未注入提交哈希或构建时间时，使用 Go 工具链记录的版本控制信息（在 git 仓库中 go build 时自动记录）
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -10,26 +11,38 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Database   DatabaseConfig   `yaml:"database"`
-	Server     ServerConfig     `yaml:"server"`
-	JWT        JWTConfig        `yaml:"jwt"`
-	Email      EmailConfig      `yaml:"email"`
-	AdminEmails []string        `yaml:"admin_emails"`
-	CloudflareR2 CloudflareR2Config `yaml:"cloudflare_r2"`
+	Database       DatabaseConfig       `yaml:"database"`
+	Server         ServerConfig         `yaml:"server"`
+	JWT            JWTConfig            `yaml:"jwt"`
+	Email          EmailConfig          `yaml:"email"`
+	AdminEmails    []string             `yaml:"admin_emails"`
+	CloudflareR2   CloudflareR2Config   `yaml:"cloudflare_r2"`
+	CodeStore      CodeStoreConfig      `yaml:"code_store"`
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy"`
+	PasswordHash   PasswordHashConfig   `yaml:"password_hash"`
+	Registration   RegistrationConfig   `yaml:"registration"`
+	Suspension     SuspensionConfig     `yaml:"suspension"`
+	Metrics        MetricsConfig        `yaml:"metrics"`
+	Log            LogConfig            `yaml:"log"`
+	Tracing        TracingConfig        `yaml:"tracing"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Host     string `yaml:"host"`
-	Port     string `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Database string `yaml:"database"`
+	Driver         string `yaml:"driver"` // postgres（默认）或 sqlite
+	Path           string `yaml:"path"`   // sqlite 数据库文件路径，默认：book-manage.db，:memory: 表示内存数据库
+	Host           string `yaml:"host"`
+	Port           string `yaml:"port"`
+	User           string `yaml:"user"`
+	Password       string `yaml:"password"`
+	Database       string `yaml:"database"`
+	SkipMigrations bool   `yaml:"skip_migrations"` // 启动时不执行迁移（由部署流程执行 migrate up），默认：false
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port                   string `yaml:"port"`
+	ShutdownTimeoutSeconds int    `yaml:"shutdown_timeout_seconds"` // 收到退出信号后等待请求和后台任务结束的最长时间（秒），默认：15
 }
 
 // JWTConfig JWT配置
@@ -38,11 +51,19 @@ type JWTConfig struct {
 }
 
 // EmailConfig 邮箱配置
+// Provider 为 resend 时使用 ResendAPIKey（未配置时兼容旧配置读取 SMTPPassword，已弃用）；
+// 为 smtp 时使用 SMTPHost/SMTPPort/SMTPUser/SMTPPassword 连接自建邮件服务器
 type EmailConfig struct {
+	Provider     string `yaml:"provider"`       // resend、smtp 或 log，未配置时有 resend_api_key 或 smtp_password 则为 resend，否则为 log
+	From         string `yaml:"from"`           // 发件人邮箱，未配置时使用 smtp_user
+	ResendAPIKey string `yaml:"resend_api_key"` // Resend API Key（格式：re_xxxxx）
 	SMTPHost     string `yaml:"smtp_host"`
 	SMTPPort     string `yaml:"smtp_port"`
 	SMTPUser     string `yaml:"smtp_user"`
 	SMTPPassword string `yaml:"smtp_password"`
+	SMTPTLS      string `yaml:"smtp_tls"`       // starttls（默认）、tls（隐式TLS，通常为465端口）或 none
+	OutboxDir    string `yaml:"outbox_dir"`     // log 模式下将邮件写入该目录（.eml），为空时仅打印到控制台
+	LoginLinkURL string `yaml:"login_link_url"` // 一次性登录链接的前端页面地址，如：https://example.com/login/link，为空时登录邮件仅包含验证码
 }
 
 // CloudflareR2Config R2配置（使用S3兼容API）
@@ -50,10 +71,76 @@ type CloudflareR2Config struct {
 	AccountID       string `yaml:"account_id"`        // 从S3端点URL提取，格式：https://{account-id}.r2.cloudflarestorage.com
 	AccessKeyID     string `yaml:"access_key_id"`     // S3 Access Key ID
 	SecretAccessKey string `yaml:"secret_access_key"` // S3 Secret Access Key
-	BucketName      string `yaml:"bucket_name"`      // 存储桶名称，如：my-object-bucket
+	BucketName      string `yaml:"bucket_name"`       // 存储桶名称，如：my-object-bucket
 	PublicURL       string `yaml:"public_url"`        // 公开访问URL，如：https://pub-xxxxx.r2.dev
 	Endpoint        string `yaml:"endpoint"`          // S3端点URL，如：https://{account-id}.r2.cloudflarestorage.com
-	Region          string `yaml:"region"`             // 区域，默认：auto
+	Region          string `yaml:"region"`            // 区域，默认：auto
+}
+
+// CodeStoreConfig 验证码存储配置
+// 多实例部署时验证码的频率限制和校验依赖共享存储
+type CodeStoreConfig struct {
+	Driver        string `yaml:"driver"`         // postgres（默认）或 redis
+	RedisAddr     string `yaml:"redis_addr"`     // Redis地址，如：localhost:6379
+	RedisPassword string `yaml:"redis_password"` // Redis密码（可选）
+	RedisDB       int    `yaml:"redis_db"`       // Redis数据库编号，默认：0
+	MaxAttempts   int    `yaml:"max_attempts"`   // 单个验证码允许的错误次数，默认：5
+}
+
+// PasswordPolicyConfig 密码策略配置（注册、修改密码、找回密码时校验）
+type PasswordPolicyConfig struct {
+	MinLength         int  `yaml:"min_length"`          // 最小长度（按字符计），默认：8
+	MinCharClasses    int  `yaml:"min_char_classes"`    // 至少包含的字符类别数（大写字母、小写字母、数字、符号），默认：2
+	AllowEmail        bool `yaml:"allow_email"`         // 是否允许密码中包含邮箱用户名，默认：false
+	HistorySize       int  `yaml:"history_size"`        // 不能与最近几次使用过的密码相同，默认：5，小于0表示不检查
+	SkipBreachedCheck bool `yaml:"skip_breached_check"` // 是否跳过常见/已泄露密码检查，默认：false
+}
+
+// PasswordHashConfig 密码哈希配置
+// 修改算法或参数后，旧密码仍可校验，用户下次登录成功时自动按新配置重新哈希
+type PasswordHashConfig struct {
+	Algorithm         string `yaml:"algorithm"`          // bcrypt（默认）或 argon2id
+	BcryptCost        int    `yaml:"bcrypt_cost"`        // bcrypt 计算成本（4-31），默认：10
+	Argon2Memory      uint32 `yaml:"argon2_memory"`      // argon2id 内存（KiB），默认：65536
+	Argon2Iterations  uint32 `yaml:"argon2_iterations"`  // argon2id 迭代次数，默认：3
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism"` // argon2id 并行度，默认：2
+}
+
+// RegistrationConfig 注册配置
+type RegistrationConfig struct {
+	Mode           string   `yaml:"mode"`             // open（默认，任意邮箱）、domain（仅限 allowed_domains）或 invite（仅限受邀）
+	AllowedDomains []string `yaml:"allowed_domains"`  // domain 模式下允许的邮箱域名（包含子域名），如：example.edu.cn
+	InviteURL      string   `yaml:"invite_url"`       // 邀请邮件中注册页面地址，如：https://example.com/register，为空时邮件中只包含邀请码
+	InviteTTLDays  int      `yaml:"invite_ttl_days"`  // 邀请默认有效天数，默认：7
+	SetPasswordURL string   `yaml:"set_password_url"` // 批量导入读者时欢迎邮件中设置密码页面地址，如：https://example.com/setPassword
+}
+
+// SuspensionConfig 借阅权限自动暂停规则配置
+type SuspensionConfig struct {
+	OverdueDays          int     `yaml:"overdue_days"`           // 图书逾期超过多少天暂停借阅，默认：30，小于0表示不检查
+	FineLimit            float64 `yaml:"fine_limit"`             // 未缴罚款超过多少元暂停借阅，默认：0（不检查）；系统暂未记录罚款，该规则暂不会触发
+	CheckIntervalMinutes int     `yaml:"check_interval_minutes"` // 后台巡检间隔（分钟），默认：60
+}
+
+// MetricsConfig Prometheus指标配置
+type MetricsConfig struct {
+	Token string `yaml:"token"` // 访问 /metrics 需携带的 Bearer token，为空时不校验（应由网络层限制访问）
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string            `yaml:"level"`  // 默认日志级别：debug、info（默认）、warn、error
+	Format string            `yaml:"format"` // json（默认）或 text
+	Levels map[string]string `yaml:"levels"` // 按组件设置日志级别，如 gorm: warn、outbox: debug
+}
+
+// TracingConfig OpenTelemetry链路追踪配置，通过 OTLP/HTTP 导出到 collector
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`      // 是否启用，默认：false
+	Endpoint    string  `yaml:"endpoint"`     // collector 地址（host:port），默认：localhost:4318
+	Insecure    bool    `yaml:"insecure"`     // 使用HTTP而非HTTPS连接 collector（本地 collector 使用）
+	ServiceName string  `yaml:"service_name"` // 服务名称，默认：book-manage
+	SampleRatio float64 `yaml:"sample_ratio"` // 采样比例（0-1]，默认：1（全部采样）
 }
 
 // LoadConfig 加载配置
@@ -114,11 +201,35 @@ func LoadConfig() (*Config, error) {
 	if database := os.Getenv("DB_NAME"); database != "" {
 		config.Database.Database = database
 	}
+	if driver := os.Getenv("DB_DRIVER"); driver != "" {
+		config.Database.Driver = driver
+	}
+	config.Database.Driver = strings.ToLower(config.Database.Driver)
+	if config.Database.Driver == "" {
+		config.Database.Driver = "postgres" // 默认值
+	}
+	if path := os.Getenv("DB_PATH"); path != "" {
+		config.Database.Path = path
+	}
+	if config.Database.Driver == "sqlite" && config.Database.Path == "" {
+		config.Database.Path = "book-manage.db" // 默认值
+	}
+	if skipMigrations := os.Getenv("DB_SKIP_MIGRATIONS"); skipMigrations != "" {
+		config.Database.SkipMigrations = skipMigrations == "true"
+	}
 
 	// 服务器配置
 	if port := os.Getenv("PORT"); port != "" {
 		config.Server.Port = port
 	}
+	if shutdownTimeout := os.Getenv("SHUTDOWN_TIMEOUT_SECONDS"); shutdownTimeout != "" {
+		if n, err := strconv.Atoi(shutdownTimeout); err == nil {
+			config.Server.ShutdownTimeoutSeconds = n
+		}
+	}
+	if config.Server.ShutdownTimeoutSeconds <= 0 {
+		config.Server.ShutdownTimeoutSeconds = 15 // 默认值
+	}
 
 	// JWT 配置
 	if secret := os.Getenv("JWT_SECRET"); secret != "" {
@@ -138,6 +249,32 @@ func LoadConfig() (*Config, error) {
 	if smtpPassword := os.Getenv("SMTP_PASSWORD"); smtpPassword != "" {
 		config.Email.SMTPPassword = smtpPassword
 	}
+	if resendAPIKey := os.Getenv("RESEND_API_KEY"); resendAPIKey != "" {
+		config.Email.ResendAPIKey = resendAPIKey
+	}
+	if smtpTLS := os.Getenv("SMTP_TLS"); smtpTLS != "" {
+		config.Email.SMTPTLS = smtpTLS
+	}
+	if from := os.Getenv("EMAIL_FROM"); from != "" {
+		config.Email.From = from
+	}
+	if outboxDir := os.Getenv("EMAIL_OUTBOX_DIR"); outboxDir != "" {
+		config.Email.OutboxDir = outboxDir
+	}
+	if loginLinkURL := os.Getenv("LOGIN_LINK_URL"); loginLinkURL != "" {
+		config.Email.LoginLinkURL = loginLinkURL
+	}
+	if provider := os.Getenv("EMAIL_PROVIDER"); provider != "" {
+		config.Email.Provider = provider
+	} else if config.Email.Provider == "" {
+		// 配置了 Resend API Key 则使用 Resend（兼容旧配置：smtp_password 中存储 Resend API Key）
+		if config.Email.ResendAPIKey != "" || config.Email.SMTPPassword != "" {
+			config.Email.Provider = "resend"
+		} else {
+			config.Email.Provider = "log"
+		}
+	}
+	config.Email.Provider = strings.ToLower(config.Email.Provider)
 
 	// 管理员邮箱（从环境变量读取，用逗号分隔）
 	if adminEmails := os.Getenv("ADMIN_EMAILS"); adminEmails != "" {
@@ -173,6 +310,170 @@ func LoadConfig() (*Config, error) {
 		config.CloudflareR2.Region = "auto" // 默认值
 	}
 
+	// 验证码存储配置
+	if driver := os.Getenv("CODE_STORE_DRIVER"); driver != "" {
+		config.CodeStore.Driver = driver
+	} else if config.CodeStore.Driver == "" {
+		config.CodeStore.Driver = "postgres" // 默认值
+	}
+	if redisAddr := os.Getenv("REDIS_ADDR"); redisAddr != "" {
+		config.CodeStore.RedisAddr = redisAddr
+	}
+	if redisPassword := os.Getenv("REDIS_PASSWORD"); redisPassword != "" {
+		config.CodeStore.RedisPassword = redisPassword
+	}
+	if redisDB := os.Getenv("REDIS_DB"); redisDB != "" {
+		if db, err := strconv.Atoi(redisDB); err == nil {
+			config.CodeStore.RedisDB = db
+		}
+	}
+
+	if maxAttempts := os.Getenv("CODE_MAX_ATTEMPTS"); maxAttempts != "" {
+		if n, err := strconv.Atoi(maxAttempts); err == nil {
+			config.CodeStore.MaxAttempts = n
+		}
+	}
+	if config.CodeStore.MaxAttempts <= 0 {
+		config.CodeStore.MaxAttempts = 5 // 默认值
+	}
+
+	// 密码策略配置
+	if minLength := os.Getenv("PASSWORD_MIN_LENGTH"); minLength != "" {
+		if n, err := strconv.Atoi(minLength); err == nil {
+			config.PasswordPolicy.MinLength = n
+		}
+	}
+	if config.PasswordPolicy.MinLength <= 0 {
+		config.PasswordPolicy.MinLength = 8 // 默认值
+	}
+	if minCharClasses := os.Getenv("PASSWORD_MIN_CHAR_CLASSES"); minCharClasses != "" {
+		if n, err := strconv.Atoi(minCharClasses); err == nil {
+			config.PasswordPolicy.MinCharClasses = n
+		}
+	}
+	if config.PasswordPolicy.MinCharClasses <= 0 {
+		config.PasswordPolicy.MinCharClasses = 2 // 默认值
+	}
+	if historySize := os.Getenv("PASSWORD_HISTORY_SIZE"); historySize != "" {
+		if n, err := strconv.Atoi(historySize); err == nil {
+			config.PasswordPolicy.HistorySize = n
+		}
+	}
+	if config.PasswordPolicy.HistorySize == 0 {
+		config.PasswordPolicy.HistorySize = 5 // 默认值
+	}
+
+	// 密码哈希配置
+	if algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm != "" {
+		config.PasswordHash.Algorithm = algorithm
+	} else if config.PasswordHash.Algorithm == "" {
+		config.PasswordHash.Algorithm = "bcrypt" // 默认值
+	}
+	if bcryptCost := os.Getenv("BCRYPT_COST"); bcryptCost != "" {
+		if n, err := strconv.Atoi(bcryptCost); err == nil {
+			config.PasswordHash.BcryptCost = n
+		}
+	}
+
+	// 注册配置
+	if mode := os.Getenv("REGISTRATION_MODE"); mode != "" {
+		config.Registration.Mode = mode
+	} else if config.Registration.Mode == "" {
+		config.Registration.Mode = "open" // 默认值
+	}
+	config.Registration.Mode = strings.ToLower(config.Registration.Mode)
+	if domains := os.Getenv("REGISTRATION_ALLOWED_DOMAINS"); domains != "" {
+		config.Registration.AllowedDomains = strings.Split(domains, ",")
+	}
+	for i, domain := range config.Registration.AllowedDomains {
+		config.Registration.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	}
+	if inviteURL := os.Getenv("REGISTRATION_INVITE_URL"); inviteURL != "" {
+		config.Registration.InviteURL = inviteURL
+	}
+	if setPasswordURL := os.Getenv("REGISTRATION_SET_PASSWORD_URL"); setPasswordURL != "" {
+		config.Registration.SetPasswordURL = setPasswordURL
+	}
+	if config.Registration.InviteTTLDays <= 0 {
+		config.Registration.InviteTTLDays = 7 // 默认值
+	}
+
+	// 借阅权限暂停规则配置
+	if overdueDays := os.Getenv("SUSPENSION_OVERDUE_DAYS"); overdueDays != "" {
+		if n, err := strconv.Atoi(overdueDays); err == nil {
+			config.Suspension.OverdueDays = n
+		}
+	}
+	if config.Suspension.OverdueDays == 0 {
+		config.Suspension.OverdueDays = 30 // 默认值
+	}
+	if fineLimit := os.Getenv("SUSPENSION_FINE_LIMIT"); fineLimit != "" {
+		if n, err := strconv.ParseFloat(fineLimit, 64); err == nil {
+			config.Suspension.FineLimit = n
+		}
+	}
+	if config.Suspension.CheckIntervalMinutes <= 0 {
+		config.Suspension.CheckIntervalMinutes = 60 // 默认值
+	}
+
+	// 日志配置
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.Log.Level = level
+	}
+	if config.Log.Level == "" {
+		config.Log.Level = "info" // 默认值
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Log.Format = format
+	}
+	if config.Log.Format == "" {
+		config.Log.Format = "json" // 默认值
+	}
+	// LOG_LEVELS 格式：组件=级别，逗号分隔，如 gorm=info,outbox=debug
+	if levels := os.Getenv("LOG_LEVELS"); levels != "" {
+		if config.Log.Levels == nil {
+			config.Log.Levels = make(map[string]string)
+		}
+		for _, item := range strings.Split(levels, ",") {
+			if component, level, ok := strings.Cut(strings.TrimSpace(item), "="); ok {
+				config.Log.Levels[strings.TrimSpace(component)] = strings.TrimSpace(level)
+			}
+		}
+	}
+
+	// 链路追踪配置
+	if enabled := os.Getenv("TRACING_ENABLED"); enabled != "" {
+		config.Tracing.Enabled = enabled == "true"
+	}
+	if endpoint := os.Getenv("TRACING_ENDPOINT"); endpoint != "" {
+		config.Tracing.Endpoint = endpoint
+	}
+	if config.Tracing.Endpoint == "" {
+		config.Tracing.Endpoint = "localhost:4318" // 默认值
+	}
+	if insecure := os.Getenv("TRACING_INSECURE"); insecure != "" {
+		config.Tracing.Insecure = insecure == "true"
+	}
+	if serviceName := os.Getenv("TRACING_SERVICE_NAME"); serviceName != "" {
+		config.Tracing.ServiceName = serviceName
+	}
+	if config.Tracing.ServiceName == "" {
+		config.Tracing.ServiceName = "book-manage" // 默认值
+	}
+	if sampleRatio := os.Getenv("TRACING_SAMPLE_RATIO"); sampleRatio != "" {
+		if ratio, err := strconv.ParseFloat(sampleRatio, 64); err == nil {
+			config.Tracing.SampleRatio = ratio
+		}
+	}
+	if config.Tracing.SampleRatio <= 0 || config.Tracing.SampleRatio > 1 {
+		config.Tracing.SampleRatio = 1 // 默认值
+	}
+
+	// 指标配置
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		config.Metrics.Token = token
+	}
+
 	return &config, nil
 }
 
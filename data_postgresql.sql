@@ -4,19 +4,39 @@
 CREATE TABLE IF NOT EXISTS "user" (
     "id" SERIAL PRIMARY KEY,
     "email" VARCHAR(100) NOT NULL UNIQUE,
-    "password" VARCHAR(100) NOT NULL,
-    "role" VARCHAR(10) NOT NULL DEFAULT 'user' CHECK ("role" IN ('admin', 'user')),
+    "password" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(50) NOT NULL DEFAULT 'user',
     "register_time" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    "status" VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK ("status" IN ('normal', 'disabled'))
+    "status" VARCHAR(10) NOT NULL DEFAULT 'normal' CHECK ("status" IN ('normal', 'suspended', 'disabled')),
+    "name" VARCHAR(100) NOT NULL DEFAULT '',
+    "student_id" VARCHAR(50) NULL UNIQUE,
+    "user_group" VARCHAR(100) NOT NULL DEFAULT '',
+    "phone" VARCHAR(30) NOT NULL DEFAULT '',
+    "card_number" VARCHAR(50) NULL UNIQUE,
+    "membership_expires_at" TIMESTAMP NULL,
+    "suspended_reason" VARCHAR(255) NOT NULL DEFAULT '',
+    "suspended_until" TIMESTAMP NULL,
+    "suspension_rule" VARCHAR(50) NOT NULL DEFAULT '',
+    "anonymized_at" TIMESTAMP NULL
 );
 
 COMMENT ON TABLE "user" IS '用户信息表';
 COMMENT ON COLUMN "user"."id" IS '用户ID';
 COMMENT ON COLUMN "user"."email" IS '注册邮箱（唯一）';
-COMMENT ON COLUMN "user"."password" IS '加密后的密码（bcrypt算法）';
-COMMENT ON COLUMN "user"."role" IS '角色（管理员/普通用户）';
+COMMENT ON COLUMN "user"."password" IS '密码哈希（bcrypt或argon2id，哈希中包含算法和参数）';
+COMMENT ON COLUMN "user"."role" IS '角色名称（对应 role 表，内置 admin/user）';
 COMMENT ON COLUMN "user"."register_time" IS '注册时间';
-COMMENT ON COLUMN "user"."status" IS '账户状态';
+COMMENT ON COLUMN "user"."status" IS '账户状态（normal正常、suspended暂停借阅、disabled禁用）';
+COMMENT ON COLUMN "user"."name" IS '姓名';
+COMMENT ON COLUMN "user"."student_id" IS '学号（可为空，非空时唯一）';
+COMMENT ON COLUMN "user"."user_group" IS '分组（如院系、班级）';
+COMMENT ON COLUMN "user"."phone" IS '联系电话';
+COMMENT ON COLUMN "user"."card_number" IS '借书卡号（可为空，非空时唯一）';
+COMMENT ON COLUMN "user"."membership_expires_at" IS '读者资格到期日（为空表示长期有效）';
+COMMENT ON COLUMN "user"."suspended_reason" IS '借阅权限暂停原因';
+COMMENT ON COLUMN "user"."suspended_until" IS '手动暂停的截止日期（含当天）';
+COMMENT ON COLUMN "user"."suspension_rule" IS '触发暂停的规则（manual 表示管理员手动暂停）';
+COMMENT ON COLUMN "user"."anonymized_at" IS '注销时间（注销后个人信息被清除，NULL表示未注销）';
 
 -- 2. 图书表（存储图书基本信息）
 CREATE TABLE IF NOT EXISTS "book" (
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles 版本化迁移脚本，按数据库类型分目录，文件名格式：{版本号}_{名称}.up.sql / {版本号}_{名称}.down.sql
+// 两个目录的版本号保持一致，同一版本在不同数据库上完成相同的结构变更
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey 执行迁移时持有的 PostgreSQL advisory lock 键，多个实例同时启动时只有一个实例执行迁移
+const migrationLockKey int64 = 4_812_730_551_209
+
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 数据库迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移执行状态
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"` // 为空表示未执行
+}
+
+// schemaMigration schema_migrations 表记录
+type schemaMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// LoadMigrations 读取指定数据库类型（postgres、sqlite）的内置迁移脚本，按版本号排序
+func LoadMigrations(dialect string) ([]Migration, error) {
+	migrationDir := "migrations/" + dialect
+	entries, err := fs.ReadDir(migrationFiles, migrationDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFileRegex.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(matches[1], 10, 64)
+		content, err := fs.ReadFile(migrationFiles, migrationDir+"/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s, %s", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp 执行全部未执行的迁移，返回本次执行的迁移
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		done, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			dbLog.Info("执行迁移", "version", m.Version, "name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+					m.Version, m.Name, time.Now()).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %v", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown 按版本号倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		done, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			dbLog.Info("回滚迁移", "version", m.Version, "name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rollback %04d_%s failed: %v", m.Version, m.Name, err)
+			}
+			rolledBack = append(rolledBack, m)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// GetMigrationStatus 获取全部迁移的执行状态
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationTable(db); err != nil {
+		return nil, err
+	}
+	done, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if record, ok := done[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// PendingMigrations 获取未执行的迁移（只读，schema_migrations 表不存在时全部视为未执行）
+// 供就绪检查等频繁调用的场景使用，不创建表也不加锁
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if !db.Migrator().HasTable("schema_migrations") {
+		return migrations, nil
+	}
+	done, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// withMigrationLock 在同一个数据库连接上持有 advisory lock 执行迁移
+// SQLite 只有单个连接，不需要加锁
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == "postgres" {
+			if err := conn.Exec(`SELECT pg_advisory_lock(?)`, migrationLockKey).Error; err != nil {
+				return fmt.Errorf("failed to acquire migration lock: %v", err)
+			}
+			defer conn.Exec(`SELECT pg_advisory_unlock(?)`, migrationLockKey)
+		}
+
+		if err := ensureMigrationTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// ensureMigrationTable 创建 schema_migrations 表
+func ensureMigrationTable(db *gorm.DB) error {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// appliedMigrations 查询已执行的迁移
+func appliedMigrations(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := db.Table("schema_migrations").Order("version").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %v", err)
+	}
+
+	done := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPendingMigrationsIsReadOnly(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+
+	// 未执行迁移时全部视为未执行，且不创建 schema_migrations 表
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		t.Fatalf("pending before migrate: %v", err)
+	}
+	if len(pending) != len(migrations) {
+		t.Fatalf("pending before migrate = %d, want %d", len(pending), len(migrations))
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Fatalf("PendingMigrations should not create schema_migrations")
+	}
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	pending, err = PendingMigrations(db)
+	if err != nil {
+		t.Fatalf("pending after migrate: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("pending after migrate = %d, want 0", len(pending))
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "email_code_record";
+DROP TABLE IF EXISTS "borrow_record";
+DROP TABLE IF EXISTS "book";
+DROP TABLE IF EXISTS "user";
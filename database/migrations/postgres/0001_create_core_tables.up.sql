@@ -0,0 +1,59 @@
+-- 核心业务表：用户、图书、借阅记录、验证码记录
+-- 使用 IF NOT EXISTS，兼容之前由 AutoMigrate 或 data_postgresql.sql 创建的数据库
+
+CREATE TABLE IF NOT EXISTS "user" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "email" VARCHAR(100) NOT NULL,
+    "password" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(50) DEFAULT 'user',
+    "register_time" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "status" VARCHAR(10) DEFAULT 'normal'
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_email" ON "user" ("email");
+CREATE INDEX IF NOT EXISTS "idx_user_role" ON "user" ("role");
+
+CREATE TABLE IF NOT EXISTS "book" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "title" VARCHAR(200) NOT NULL,
+    "author" VARCHAR(100) NOT NULL,
+    "isbn" VARCHAR(20) NOT NULL,
+    "category" VARCHAR(50) NOT NULL,
+    "total_quantity" BIGINT NOT NULL,
+    "available_quantity" BIGINT NOT NULL,
+    "description" TEXT,
+    "create_time" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "update_time" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_book_isbn" ON "book" ("isbn");
+
+CREATE TABLE IF NOT EXISTS "borrow_record" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT NOT NULL,
+    "book_id" BIGINT NOT NULL,
+    "borrow_date" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "due_date" TIMESTAMPTZ NOT NULL,
+    "return_date" TIMESTAMPTZ,
+    "status" VARCHAR(10) DEFAULT 'borrowed',
+    CONSTRAINT "fk_borrow_record_user" FOREIGN KEY ("user_id") REFERENCES "user" ("id"),
+    CONSTRAINT "fk_borrow_record_book" FOREIGN KEY ("book_id") REFERENCES "book" ("id"),
+    CONSTRAINT "chk_borrow_record_status" CHECK ("status" IN ('borrowed', 'returned'))
+);
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_user_id" ON "borrow_record" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_book_id" ON "borrow_record" ("book_id");
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_status" ON "borrow_record" ("status");
+
+CREATE TABLE IF NOT EXISTS "email_code_record" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "email" VARCHAR(100) NOT NULL,
+    "code" VARCHAR(10) NOT NULL,
+    "action" VARCHAR(20) NOT NULL,
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "is_used" BOOLEAN DEFAULT false,
+    "used_at" TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_email" ON "email_code_record" ("email");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_action" ON "email_code_record" ("action");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_created_at" ON "email_code_record" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_expires_at" ON "email_code_record" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_is_used" ON "email_code_record" ("is_used");
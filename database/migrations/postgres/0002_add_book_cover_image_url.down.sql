@@ -0,0 +1 @@
+ALTER TABLE "book" DROP COLUMN IF EXISTS "cover_image_url";
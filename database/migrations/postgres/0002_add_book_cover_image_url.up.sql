@@ -0,0 +1,2 @@
+-- 图书封面图片（原 add_book_cover_image_url.sql）
+ALTER TABLE "book" ADD COLUMN IF NOT EXISTS "cover_image_url" VARCHAR(500) DEFAULT NULL;
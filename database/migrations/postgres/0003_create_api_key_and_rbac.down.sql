@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "role_permission";
+DROP TABLE IF EXISTS "permission";
+DROP TABLE IF EXISTS "role";
+DROP TABLE IF EXISTS "api_key";
@@ -0,0 +1,49 @@
+-- 个人API密钥、可配置的角色和权限
+-- 内置权限和角色由 services.InitRBACService 在启动时写入
+
+CREATE TABLE IF NOT EXISTS "api_key" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT NOT NULL,
+    "name" VARCHAR(50) NOT NULL,
+    "prefix" VARCHAR(16) NOT NULL,
+    "secret_hash" VARCHAR(64) NOT NULL,
+    "scopes" VARCHAR(200) NOT NULL,
+    "expires_at" TIMESTAMPTZ,
+    "last_used_at" TIMESTAMPTZ,
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "revoked_at" TIMESTAMPTZ,
+    CONSTRAINT "fk_api_key_user" FOREIGN KEY ("user_id") REFERENCES "user" ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_api_key_user_id" ON "api_key" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_prefix" ON "api_key" ("prefix");
+CREATE INDEX IF NOT EXISTS "idx_api_key_revoked_at" ON "api_key" ("revoked_at");
+
+CREATE TABLE IF NOT EXISTS "role" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "name" VARCHAR(50) NOT NULL,
+    "description" VARCHAR(200),
+    "built_in" BOOLEAN DEFAULT false,
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_role_name" ON "role" ("name");
+
+CREATE TABLE IF NOT EXISTS "permission" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "code" VARCHAR(50) NOT NULL,
+    "description" VARCHAR(200)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_permission_code" ON "permission" ("code");
+
+CREATE TABLE IF NOT EXISTS "role_permission" (
+    "role_id" BIGINT NOT NULL,
+    "permission_id" BIGINT NOT NULL,
+    PRIMARY KEY ("role_id", "permission_id"),
+    CONSTRAINT "fk_role_permission_role" FOREIGN KEY ("role_id") REFERENCES "role" ("id"),
+    CONSTRAINT "fk_role_permission_permission" FOREIGN KEY ("permission_id") REFERENCES "permission" ("id")
+);
+
+-- 角色改为可配置的RBAC角色，移除旧版本 role IN ('admin','user') 检查约束
+-- chk_user_role 为GORM创建的约束名，user_role_check 为 data_postgresql.sql 创建的约束名
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "chk_user_role";
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "user_role_check";
+ALTER TABLE "user" ALTER COLUMN "role" TYPE VARCHAR(50);
@@ -0,0 +1,3 @@
+-- 遮盖后的验证码无法还原，回滚时不处理 email_code_record.code
+ALTER TABLE "email_code_record" DROP COLUMN IF EXISTS "invalidated_at";
+DROP TABLE IF EXISTS "verification_code";
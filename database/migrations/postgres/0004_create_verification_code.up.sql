@@ -0,0 +1,22 @@
+-- 验证码存储（多实例共享频率限制和校验状态），只保存加盐哈希
+
+CREATE TABLE IF NOT EXISTS "verification_code" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "email" VARCHAR(100) NOT NULL,
+    "action" VARCHAR(20) NOT NULL,
+    "code_hash" VARCHAR(100) NOT NULL DEFAULT '',
+    "attempts" BIGINT NOT NULL DEFAULT 0,
+    "sent_at" TIMESTAMPTZ NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_verification_code_email_action" ON "verification_code" ("email", "action");
+CREATE INDEX IF NOT EXISTS "idx_verification_code_expires_at" ON "verification_code" ("expires_at");
+
+-- 移除旧版本的明文 code 列
+ALTER TABLE "verification_code" ADD COLUMN IF NOT EXISTS "code_hash" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "verification_code" DROP COLUMN IF EXISTS "code";
+
+-- 验证码记录仅保存遮盖后的验证码，如 1****6
+ALTER TABLE "email_code_record" ADD COLUMN IF NOT EXISTS "invalidated_at" TIMESTAMPTZ;
+UPDATE "email_code_record" SET "code" = substr("code", 1, 1) || '****' || substr("code", length("code"), 1)
+WHERE "code" NOT LIKE '%*%' AND length("code") > 2;
@@ -0,0 +1,36 @@
+-- 可编辑的邮件模板、邮件发件箱（异步发送并自动重试）
+
+CREATE TABLE IF NOT EXISTS "email_template" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "template_key" VARCHAR(50) NOT NULL,
+    "locale" VARCHAR(10) NOT NULL,
+    "subject" VARCHAR(200) NOT NULL,
+    "html_body" TEXT NOT NULL,
+    "text_body" TEXT NOT NULL,
+    "updated_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_email_template_key_locale" ON "email_template" ("template_key", "locale");
+
+CREATE TABLE IF NOT EXISTS "email_outbox" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "category" VARCHAR(50) NOT NULL,
+    "from_email" VARCHAR(200) NOT NULL,
+    "to_email" VARCHAR(500) NOT NULL,
+    "subject" VARCHAR(200) NOT NULL,
+    "html_body" TEXT,
+    "text_body" TEXT,
+    "status" VARCHAR(10) NOT NULL DEFAULT 'pending',
+    "attempts" BIGINT NOT NULL DEFAULT 0,
+    "max_attempts" BIGINT NOT NULL,
+    "next_attempt_at" TIMESTAMPTZ NOT NULL,
+    "locked_at" TIMESTAMPTZ,
+    "last_error" TEXT,
+    "provider" VARCHAR(20),
+    "provider_message_id" VARCHAR(200),
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "sent_at" TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS "idx_email_outbox_category" ON "email_outbox" ("category");
+CREATE INDEX IF NOT EXISTS "idx_email_outbox_to_email" ON "email_outbox" ("to_email");
+CREATE INDEX IF NOT EXISTS "idx_email_outbox_status_next" ON "email_outbox" ("status", "next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_email_outbox_created_at" ON "email_outbox" ("created_at");
@@ -0,0 +1,4 @@
+ALTER TABLE "user" DROP COLUMN IF EXISTS "anonymized_at";
+DROP TABLE IF EXISTS "invitation";
+DROP TABLE IF EXISTS "password_history";
+DROP TABLE IF EXISTS "token_revocation";
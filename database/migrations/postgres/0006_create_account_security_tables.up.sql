@@ -0,0 +1,34 @@
+-- 账户安全：token吊销、账户注销、密码历史、注册邀请
+
+CREATE TABLE IF NOT EXISTS "token_revocation" (
+    "email" VARCHAR(100) PRIMARY KEY,
+    "revoked_at" TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "password_history" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" BIGINT NOT NULL,
+    "password_hash" VARCHAR(255) NOT NULL,
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS "idx_password_history_user_id" ON "password_history" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "invitation" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "email" VARCHAR(100) NOT NULL,
+    "token_hash" VARCHAR(64) NOT NULL,
+    "role" VARCHAR(50) NOT NULL DEFAULT 'user',
+    "user_group" VARCHAR(100),
+    "created_by" BIGINT NOT NULL,
+    "created_at" TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "used_at" TIMESTAMPTZ,
+    "used_by" BIGINT,
+    "revoked_at" TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS "idx_invitation_email" ON "invitation" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invitation_token_hash" ON "invitation" ("token_hash");
+
+-- 密码哈希自带算法和参数（argon2id 哈希比 bcrypt 长）
+ALTER TABLE "user" ALTER COLUMN "password" TYPE VARCHAR(255);
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "anonymized_at" TIMESTAMPTZ;
@@ -0,0 +1,16 @@
+UPDATE "user" SET "status" = 'normal' WHERE "status" = 'suspended';
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "chk_user_status";
+ALTER TABLE "user" ADD CONSTRAINT "chk_user_status" CHECK ("status" IN ('normal', 'disabled'));
+
+DROP INDEX IF EXISTS "idx_user_card_number";
+DROP INDEX IF EXISTS "idx_user_user_group";
+DROP INDEX IF EXISTS "idx_user_student_id";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "suspension_rule";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "suspended_until";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "suspended_reason";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "membership_expires_at";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "card_number";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "phone";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "user_group";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "student_id";
+ALTER TABLE "user" DROP COLUMN IF EXISTS "name";
@@ -0,0 +1,20 @@
+-- 读者资料（姓名、学号、分组、联系电话、借书卡号、资格到期日）和借阅权限暂停
+
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "name" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "student_id" VARCHAR(50);
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "user_group" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "phone" VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "card_number" VARCHAR(50);
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "membership_expires_at" TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "suspended_reason" VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "suspended_until" TIMESTAMPTZ;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS "suspension_rule" VARCHAR(50) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_student_id" ON "user" ("student_id");
+CREATE INDEX IF NOT EXISTS "idx_user_user_group" ON "user" ("user_group");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_card_number" ON "user" ("card_number");
+
+-- 用户状态新增 suspended
+-- chk_user_status 为GORM创建的约束名，user_status_check 为 data_postgresql.sql 创建的约束名
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "chk_user_status";
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS "user_status_check";
+ALTER TABLE "user" ADD CONSTRAINT "chk_user_status" CHECK ("status" IN ('normal', 'suspended', 'disabled'));
@@ -0,0 +1,60 @@
+-- 核心业务表：用户、图书、借阅记录、验证码记录
+-- SQLite 不支持修改检查约束，user.status 直接使用最终的取值范围（见 0007）
+
+CREATE TABLE IF NOT EXISTS "user" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "email" VARCHAR(100) NOT NULL,
+    "password" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(50) DEFAULT 'user',
+    "register_time" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "status" VARCHAR(10) DEFAULT 'normal',
+    CONSTRAINT "chk_user_status" CHECK ("status" IN ('normal', 'suspended', 'disabled'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_email" ON "user" ("email");
+CREATE INDEX IF NOT EXISTS "idx_user_role" ON "user" ("role");
+
+CREATE TABLE IF NOT EXISTS "book" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "title" VARCHAR(200) NOT NULL,
+    "author" VARCHAR(100) NOT NULL,
+    "isbn" VARCHAR(20) NOT NULL,
+    "category" VARCHAR(50) NOT NULL,
+    "total_quantity" INTEGER NOT NULL,
+    "available_quantity" INTEGER NOT NULL,
+    "description" TEXT,
+    "create_time" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "update_time" DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_book_isbn" ON "book" ("isbn");
+
+CREATE TABLE IF NOT EXISTS "borrow_record" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id" INTEGER NOT NULL,
+    "book_id" INTEGER NOT NULL,
+    "borrow_date" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "due_date" DATETIME NOT NULL,
+    "return_date" DATETIME,
+    "status" VARCHAR(10) DEFAULT 'borrowed',
+    CONSTRAINT "fk_borrow_record_user" FOREIGN KEY ("user_id") REFERENCES "user" ("id"),
+    CONSTRAINT "fk_borrow_record_book" FOREIGN KEY ("book_id") REFERENCES "book" ("id"),
+    CONSTRAINT "chk_borrow_record_status" CHECK ("status" IN ('borrowed', 'returned'))
+);
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_user_id" ON "borrow_record" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_book_id" ON "borrow_record" ("book_id");
+CREATE INDEX IF NOT EXISTS "idx_borrow_record_status" ON "borrow_record" ("status");
+
+CREATE TABLE IF NOT EXISTS "email_code_record" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "email" VARCHAR(100) NOT NULL,
+    "code" VARCHAR(10) NOT NULL,
+    "action" VARCHAR(20) NOT NULL,
+    "created_at" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" DATETIME NOT NULL,
+    "is_used" BOOLEAN DEFAULT false,
+    "used_at" DATETIME
+);
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_email" ON "email_code_record" ("email");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_action" ON "email_code_record" ("action");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_created_at" ON "email_code_record" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_expires_at" ON "email_code_record" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_email_code_record_is_used" ON "email_code_record" ("is_used");
@@ -0,0 +1 @@
+ALTER TABLE "book" DROP COLUMN "cover_image_url";
@@ -0,0 +1,2 @@
+-- 图书封面图片
+ALTER TABLE "book" ADD COLUMN "cover_image_url" VARCHAR(500) DEFAULT NULL;
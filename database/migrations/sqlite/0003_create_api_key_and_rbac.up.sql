@@ -0,0 +1,43 @@
+-- 个人API密钥、可配置的角色和权限
+-- 内置权限和角色由 services.InitRBACService 在启动时写入
+
+CREATE TABLE IF NOT EXISTS "api_key" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id" INTEGER NOT NULL,
+    "name" VARCHAR(50) NOT NULL,
+    "prefix" VARCHAR(16) NOT NULL,
+    "secret_hash" VARCHAR(64) NOT NULL,
+    "scopes" VARCHAR(200) NOT NULL,
+    "expires_at" DATETIME,
+    "last_used_at" DATETIME,
+    "created_at" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "revoked_at" DATETIME,
+    CONSTRAINT "fk_api_key_user" FOREIGN KEY ("user_id") REFERENCES "user" ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_api_key_user_id" ON "api_key" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_key_prefix" ON "api_key" ("prefix");
+CREATE INDEX IF NOT EXISTS "idx_api_key_revoked_at" ON "api_key" ("revoked_at");
+
+CREATE TABLE IF NOT EXISTS "role" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "name" VARCHAR(50) NOT NULL,
+    "description" VARCHAR(200),
+    "built_in" BOOLEAN DEFAULT false,
+    "created_at" DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_role_name" ON "role" ("name");
+
+CREATE TABLE IF NOT EXISTS "permission" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "code" VARCHAR(50) NOT NULL,
+    "description" VARCHAR(200)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_permission_code" ON "permission" ("code");
+
+CREATE TABLE IF NOT EXISTS "role_permission" (
+    "role_id" INTEGER NOT NULL,
+    "permission_id" INTEGER NOT NULL,
+    PRIMARY KEY ("role_id", "permission_id"),
+    CONSTRAINT "fk_role_permission_role" FOREIGN KEY ("role_id") REFERENCES "role" ("id"),
+    CONSTRAINT "fk_role_permission_permission" FOREIGN KEY ("permission_id") REFERENCES "permission" ("id")
+);
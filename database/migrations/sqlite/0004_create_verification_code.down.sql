@@ -0,0 +1,2 @@
+ALTER TABLE "email_code_record" DROP COLUMN "invalidated_at";
+DROP TABLE IF EXISTS "verification_code";
@@ -0,0 +1,15 @@
+-- 验证码存储（频率限制和校验状态），只保存加盐哈希
+
+CREATE TABLE IF NOT EXISTS "verification_code" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "email" VARCHAR(100) NOT NULL,
+    "action" VARCHAR(20) NOT NULL,
+    "code_hash" VARCHAR(100) NOT NULL DEFAULT '',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "sent_at" DATETIME NOT NULL,
+    "expires_at" DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_verification_code_email_action" ON "verification_code" ("email", "action");
+CREATE INDEX IF NOT EXISTS "idx_verification_code_expires_at" ON "verification_code" ("expires_at");
+
+ALTER TABLE "email_code_record" ADD COLUMN "invalidated_at" DATETIME;
@@ -0,0 +1,32 @@
+-- 账户安全：token吊销、账户注销、密码历史、注册邀请
+
+CREATE TABLE IF NOT EXISTS "token_revocation" (
+    "email" VARCHAR(100) PRIMARY KEY,
+    "revoked_at" DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "password_history" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "user_id" INTEGER NOT NULL,
+    "password_hash" VARCHAR(255) NOT NULL,
+    "created_at" DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS "idx_password_history_user_id" ON "password_history" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "invitation" (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "email" VARCHAR(100) NOT NULL,
+    "token_hash" VARCHAR(64) NOT NULL,
+    "role" VARCHAR(50) NOT NULL DEFAULT 'user',
+    "user_group" VARCHAR(100),
+    "created_by" INTEGER NOT NULL,
+    "created_at" DATETIME DEFAULT CURRENT_TIMESTAMP,
+    "expires_at" DATETIME NOT NULL,
+    "used_at" DATETIME,
+    "used_by" INTEGER,
+    "revoked_at" DATETIME
+);
+CREATE INDEX IF NOT EXISTS "idx_invitation_email" ON "invitation" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invitation_token_hash" ON "invitation" ("token_hash");
+
+ALTER TABLE "user" ADD COLUMN "anonymized_at" DATETIME;
@@ -0,0 +1,15 @@
+-- user.status 的检查约束在 0001 中定义，回滚时不修改
+UPDATE "user" SET "status" = 'normal' WHERE "status" = 'suspended';
+
+DROP INDEX IF EXISTS "idx_user_card_number";
+DROP INDEX IF EXISTS "idx_user_user_group";
+DROP INDEX IF EXISTS "idx_user_student_id";
+ALTER TABLE "user" DROP COLUMN "suspension_rule";
+ALTER TABLE "user" DROP COLUMN "suspended_until";
+ALTER TABLE "user" DROP COLUMN "suspended_reason";
+ALTER TABLE "user" DROP COLUMN "membership_expires_at";
+ALTER TABLE "user" DROP COLUMN "card_number";
+ALTER TABLE "user" DROP COLUMN "phone";
+ALTER TABLE "user" DROP COLUMN "user_group";
+ALTER TABLE "user" DROP COLUMN "student_id";
+ALTER TABLE "user" DROP COLUMN "name";
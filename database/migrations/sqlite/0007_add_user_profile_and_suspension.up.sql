@@ -0,0 +1,14 @@
+-- 读者资料（姓名、学号、分组、联系电话、借书卡号、资格到期日）和借阅权限暂停
+
+ALTER TABLE "user" ADD COLUMN "name" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN "student_id" VARCHAR(50);
+ALTER TABLE "user" ADD COLUMN "user_group" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN "phone" VARCHAR(30) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN "card_number" VARCHAR(50);
+ALTER TABLE "user" ADD COLUMN "membership_expires_at" DATETIME;
+ALTER TABLE "user" ADD COLUMN "suspended_reason" VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE "user" ADD COLUMN "suspended_until" DATETIME;
+ALTER TABLE "user" ADD COLUMN "suspension_rule" VARCHAR(50) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_student_id" ON "user" ("student_id");
+CREATE INDEX IF NOT EXISTS "idx_user_user_group" ON "user" ("user_group");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_card_number" ON "user" ("card_number");
@@ -1,69 +1,59 @@
 package handlers
 
 import (
-	"book-manage/database"
 	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/services"
 	"book-manage/utils"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // EmailCodeListRequest 验证码列表请求
 type EmailCodeListRequest struct {
-	Page     int    `json:"page" binding:"required,min=1"`
-	Limit    int    `json:"limit" binding:"required,min=1,max=100"`
-	Email    string `json:"email"`    // 可选：按邮箱筛选
-	Action   string `json:"action"`   // 可选：按用途筛选 (register, forget)
-	IsUsed   *bool  `json:"is_used"`  // 可选：按是否使用筛选
-	Keyword  string `json:"keyword"`  // 可选：关键词搜索（邮箱或验证码）
+	Page    int    `json:"page" binding:"required,min=1"`
+	Limit   int    `json:"limit" binding:"required,min=1,max=100"`
+	Email   string `json:"email"`   // 可选：按邮箱筛选
+	Action  string `json:"action"`  // 可选：按用途筛选 (register, forget, login, change_email, set_password)
+	IsUsed  *bool  `json:"is_used"` // 可选：按是否使用筛选
+	Keyword string `json:"keyword"` // 可选：关键词搜索（邮箱）
 }
 
 // EmailCodeListResponse 验证码列表响应
 type EmailCodeListResponse struct {
-	Total int64                  `json:"total"`
+	Total int64                    `json:"total"`
 	List  []models.EmailCodeRecord `json:"list"`
 }
 
+// AdminHandler 验证码记录和邮件发件箱管理接口
+type AdminHandler struct {
+	emails *services.EmailService
+	outbox *services.OutboxService
+}
+
+// NewAdminHandler 创建验证码记录和邮件发件箱管理接口
+func NewAdminHandler(emails *services.EmailService, outbox *services.OutboxService) *AdminHandler {
+	return &AdminHandler{emails: emails, outbox: outbox}
+}
+
 // EmailCodeList 管理员查看验证码记录列表
-func EmailCodeList(c *gin.Context) {
+func (h *AdminHandler) EmailCodeList(c *gin.Context) {
 	var req EmailCodeListRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误: "+err.Error())
 		return
 	}
 
-	db := database.GetDB()
-	query := db.Model(&models.EmailCodeRecord{})
-
-	// 按邮箱筛选
-	if req.Email != "" {
-		query = query.Where("email = ?", req.Email)
-	}
-
-	// 按用途筛选
-	if req.Action != "" {
-		query = query.Where("action = ?", req.Action)
-	}
-
-	// 按是否使用筛选
-	if req.IsUsed != nil {
-		query = query.Where("is_used = ?", *req.IsUsed)
-	}
-
-	// 关键词搜索（邮箱或验证码）
-	if req.Keyword != "" {
-		query = query.Where("email LIKE ? OR code LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
-	}
-
-	// 获取总数
-	var total int64
-	query.Count(&total)
-
-	// 分页查询
-	var records []models.EmailCodeRecord
-	offset := (req.Page - 1) * req.Limit
-	if err := query.Order("created_at DESC").Offset(offset).Limit(req.Limit).Find(&records).Error; err != nil {
+	// 验证码仅以遮盖形式返回
+	records, total, err := h.emails.ListCodeRecords(c.Request.Context(), repository.CodeRecordFilter{
+		Email:   req.Email,
+		Action:  req.Action,
+		IsUsed:  req.IsUsed,
+		Keyword: req.Keyword,
+		Page:    req.Page,
+		Limit:   req.Limit,
+	})
+	if err != nil {
 		utils.Error(c, 10001, "查询验证码记录失败")
 		return
 	}
@@ -75,39 +65,80 @@ func EmailCodeList(c *gin.Context) {
 }
 
 // EmailCodeStats 验证码统计信息
-func EmailCodeStats(c *gin.Context) {
-	db := database.GetDB()
-
-	var stats struct {
-		TotalCount    int64 `json:"total_count"`
-		UsedCount     int64 `json:"used_count"`
-		UnusedCount   int64 `json:"unused_count"`
-		ExpiredCount  int64 `json:"expired_count"`
-		RegisterCount int64 `json:"register_count"`
-		ForgetCount   int64 `json:"forget_count"`
+func (h *AdminHandler) EmailCodeStats(c *gin.Context) {
+	stats, err := h.emails.CodeStats(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 10001, "查询验证码统计失败")
+		return
 	}
 
-	// 总数
-	db.Model(&models.EmailCodeRecord{}).Count(&stats.TotalCount)
+	utils.Success(c, stats)
+}
 
-	// 已使用数量
-	db.Model(&models.EmailCodeRecord{}).Where("is_used = ?", true).Count(&stats.UsedCount)
+// EmailOutboxListRequest 发件箱列表请求
+type EmailOutboxListRequest struct {
+	Page     int    `json:"page" binding:"required,min=1"`
+	Limit    int    `json:"limit" binding:"required,min=1,max=100"`
+	Status   string `json:"status"`   // 可选：按状态筛选 (pending, sending, sent, failed)
+	Email    string `json:"email"`    // 可选：按收件人筛选
+	Category string `json:"category"` // 可选：按邮件类型筛选
+}
 
-	// 未使用数量
-	db.Model(&models.EmailCodeRecord{}).Where("is_used = ?", false).Count(&stats.UnusedCount)
+// EmailOutboxRetryRequest 重新发送邮件请求
+type EmailOutboxRetryRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
 
-	// 已过期数量（未使用且已过期）
-	now := time.Now()
-	db.Model(&models.EmailCodeRecord{}).
-		Where("is_used = ? AND expires_at < ?", false, now).
-		Count(&stats.ExpiredCount)
+// EmailOutboxList 管理员查看发件箱（含发送失败的邮件）
+func (h *AdminHandler) EmailOutboxList(c *gin.Context) {
+	var req EmailOutboxListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误: "+err.Error())
+		return
+	}
 
-	// 注册验证码数量
-	db.Model(&models.EmailCodeRecord{}).Where("action = ?", "register").Count(&stats.RegisterCount)
+	records, total, err := h.outbox.List(c.Request.Context(), repository.OutboxFilter{
+		Status:   req.Status,
+		Email:    req.Email,
+		Category: req.Category,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		utils.Error(c, 10001, "查询发件箱失败")
+		return
+	}
 
-	// 忘记密码验证码数量
-	db.Model(&models.EmailCodeRecord{}).Where("action = ?", "forget").Count(&stats.ForgetCount)
+	// 各状态数量，便于管理员发现积压或失败
+	statusCounts, err := h.outbox.StatusCounts(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 10001, "查询发件箱失败")
+		return
+	}
 
-	utils.Success(c, stats)
+	utils.Success(c, map[string]interface{}{
+		"total":         total,
+		"list":          records,
+		"status_counts": statusCounts,
+	})
 }
 
+// EmailOutboxRetry 重新发送发送失败的邮件
+func (h *AdminHandler) EmailOutboxRetry(c *gin.Context) {
+	var req EmailOutboxRetryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	if err := h.outbox.Retry(c.Request.Context(), req.ID); err != nil {
+		if err == services.ErrOutboxRetryNotAllowed {
+			utils.Error(c, 10034, "邮件不存在、未处于失败状态或内容已清除")
+		} else {
+			utils.Error(c, 10001, "重新发送失败")
+		}
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
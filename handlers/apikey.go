@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"book-manage/services"
+	"book-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	Token         string   `json:"token"` // token可选，中间件会处理
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days"` // 可选：有效天数，0表示永不过期
+}
+
+// RevokeAPIKeyRequest 吊销API密钥请求
+type RevokeAPIKeyRequest struct {
+	Token string `json:"token"` // token可选，中间件会处理
+	ID    uint   `json:"id" binding:"required"`
+}
+
+// APIKeyHandler API密钥接口
+type APIKeyHandler struct {
+	keys *services.APIKeyService
+}
+
+// NewAPIKeyHandler 创建API密钥接口
+func NewAPIKeyHandler(keys *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey 创建API密钥
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > 50 {
+		utils.Error(c, 10001, "密钥名称长度应为1-50个字符")
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !services.IsValidScope(scope) {
+			utils.Error(c, 10025, "API密钥权限范围无效: "+scope)
+			return
+		}
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > 365 {
+		utils.Error(c, 10001, "有效天数应为0-365")
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	key, rawKey, err := h.keys.Create(c.Request.Context(), userIDUint, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		utils.Error(c, 10001, "创建API密钥失败")
+		return
+	}
+
+	// 明文密钥仅在创建时返回一次
+	utils.Success(c, map[string]interface{}{
+		"api_key": rawKey,
+		"key":     key,
+	})
+}
+
+// ListAPIKeys 获取当前用户的API密钥列表
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+
+	keys, err := h.keys.List(c.Request.Context(), userIDUint)
+	if err != nil {
+		utils.Error(c, 10001, "查询API密钥失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"list": keys,
+	})
+}
+
+// RevokeAPIKey 吊销API密钥
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	var req RevokeAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+
+	if err := h.keys.Revoke(c.Request.Context(), userIDUint, req.ID); err != nil {
+		if err == services.ErrAPIKeyNotFound {
+			utils.Error(c, 10026, "API密钥不存在或已吊销")
+		} else {
+			utils.Error(c, 10001, "吊销API密钥失败")
+		}
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
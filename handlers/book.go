@@ -1,19 +1,21 @@
 package handlers
 
 import (
-	"book-manage/database"
+	"book-manage/logging"
 	"book-manage/models"
+	"book-manage/repository"
 	"book-manage/services"
 	"book-manage/utils"
 	"io"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
+// bookLog 图书日志
+var bookLog = logging.For("book")
+
 // AddBookRequest 添加图书请求
 type AddBookRequest struct {
 	Token         string `json:"token"` // token可选，中间件会处理
@@ -58,38 +60,41 @@ type BookSearchRequest struct {
 	Limit    int    `json:"limit"`
 }
 
+// BookHandler 图书管理接口
+type BookHandler struct {
+	books  *services.BookService
+	images services.ImageStore // 未启用时封面上传不可用
+}
+
+// NewBookHandler 创建图书管理接口
+func NewBookHandler(books *services.BookService, images services.ImageStore) *BookHandler {
+	return &BookHandler{books: books, images: images}
+}
+
 // AddBook 添加图书
-func AddBook(c *gin.Context) {
+func (h *BookHandler) AddBook(c *gin.Context) {
 	var req AddBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 检查ISBN是否已存在
-	var existingBook models.Book
-	if err := db.Where("isbn = ?", req.ISBN).First(&existingBook).Error; err == nil {
-		utils.Error(c, 10017, "ISBN已存在")
-		return
-	}
-
 	// 创建图书
 	book := models.Book{
-		Title:             req.Title,
-		Author:            req.Author,
-		ISBN:              req.ISBN,
-		Category:          req.Category,
-		TotalQuantity:     req.TotalQuantity,
-		AvailableQuantity: req.TotalQuantity,
-		Description:       req.Description,
-		CreateTime:        time.Now(),
-		UpdateTime:        time.Now(),
-	}
-
-	if err := db.Create(&book).Error; err != nil {
-		utils.Error(c, 10001, "添加图书失败")
+		Title:         req.Title,
+		Author:        req.Author,
+		ISBN:          req.ISBN,
+		Category:      req.Category,
+		TotalQuantity: req.TotalQuantity,
+		Description:   req.Description,
+	}
+
+	if err := h.books.Create(c.Request.Context(), &book); err != nil {
+		if err == services.ErrISBNExists {
+			utils.Error(c, 10017, "ISBN已存在")
+		} else {
+			utils.Error(c, 10001, "添加图书失败")
+		}
 		return
 	}
 
@@ -97,71 +102,32 @@ func AddBook(c *gin.Context) {
 }
 
 // EditBook 编辑图书
-func EditBook(c *gin.Context) {
+func (h *BookHandler) EditBook(c *gin.Context) {
 	var req EditBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 查找图书
-	var book models.Book
-	if err := db.First(&book, req.ID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	_, err := h.books.Update(c.Request.Context(), uint(req.ID), services.BookChanges{
+		Title:         req.Title,
+		Author:        req.Author,
+		ISBN:          req.ISBN,
+		Category:      req.Category,
+		TotalQuantity: req.TotalQuantity,
+		Description:   req.Description,
+	})
+	if err != nil {
+		switch err {
+		case services.ErrBookNotFound:
 			utils.Error(c, 10010, "图书不存在")
-		} else {
-			utils.Error(c, 10001, "查询图书失败")
-		}
-		return
-	}
-
-	// 如果修改了ISBN，检查新ISBN是否已存在
-	if req.ISBN != "" && req.ISBN != book.ISBN {
-		var existingBook models.Book
-		if err := db.Where("isbn = ? AND id != ?", req.ISBN, req.ID).First(&existingBook).Error; err == nil {
+		case services.ErrISBNExists:
 			utils.Error(c, 10017, "ISBN已存在")
-			return
-		}
-		book.ISBN = req.ISBN
-	}
-
-	// 更新字段
-	if req.Title != "" {
-		book.Title = req.Title
-	}
-	if req.Author != "" {
-		book.Author = req.Author
-	}
-	if req.Category != "" {
-		book.Category = req.Category
-	}
-	if req.Description != "" {
-		book.Description = req.Description
-	}
-
-	// 如果修改了总数量
-	if req.TotalQuantity > 0 {
-		// 计算已借出数量
-		var borrowedCount int64
-		db.Model(&models.BorrowRecord{}).Where("book_id = ? AND status = ?", req.ID, "borrowed").Count(&borrowedCount)
-
-		// 检查总数量是否小于已借出数量
-		if req.TotalQuantity < int(borrowedCount) {
+		case services.ErrQuantityBelowBorrowed:
 			utils.Error(c, 10018, "总数量不能小于已借出数量")
-			return
+		default:
+			utils.Error(c, 10001, "更新图书失败")
 		}
-
-		book.TotalQuantity = req.TotalQuantity
-		// 更新可借数量 = 总数量 - 已借出数量
-		book.AvailableQuantity = req.TotalQuantity - int(borrowedCount)
-	}
-
-	book.UpdateTime = time.Now()
-
-	if err := db.Save(&book).Error; err != nil {
-		utils.Error(c, 10001, "更新图书失败")
 		return
 	}
 
@@ -169,26 +135,20 @@ func EditBook(c *gin.Context) {
 }
 
 // DeleteBook 删除图书
-func DeleteBook(c *gin.Context) {
+func (h *BookHandler) DeleteBook(c *gin.Context) {
 	var req DeleteBookRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 检查是否存在未归还的借阅记录
-	var borrowCount int64
-	db.Model(&models.BorrowRecord{}).Where("book_id = ? AND status = ?", req.ID, "borrowed").Count(&borrowCount)
-	if borrowCount > 0 {
-		utils.Error(c, 10014, "图书不可删除")
-		return
-	}
-
-	// 删除图书
-	if err := db.Delete(&models.Book{}, req.ID).Error; err != nil {
-		utils.Error(c, 10001, "删除图书失败")
+	// 存在未归还的借阅记录时不可删除
+	if err := h.books.Delete(c.Request.Context(), uint(req.ID)); err != nil {
+		if err == services.ErrBookOnLoan {
+			utils.Error(c, 10014, "图书不可删除")
+		} else {
+			utils.Error(c, 10001, "删除图书失败")
+		}
 		return
 	}
 
@@ -196,45 +156,37 @@ func DeleteBook(c *gin.Context) {
 }
 
 // BookDetail 获取图书详情
-func BookDetail(c *gin.Context) {
+func (h *BookHandler) BookDetail(c *gin.Context) {
 	var req BookDetailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 查找图书
-	var book models.Book
-	if err := db.First(&book, req.ID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.Error(c, 10010, "图书不存在")
-		} else {
-			utils.Error(c, 10001, "查询图书失败")
-		}
+	book, ok := h.findBook(c, req.ID)
+	if !ok {
 		return
 	}
 
-		utils.Success(c, map[string]interface{}{
+	utils.Success(c, map[string]interface{}{
 		"book": map[string]interface{}{
-			"id":                book.ID,
-			"title":             book.Title,
-			"author":            book.Author,
-			"isbn":              book.ISBN,
-			"category":          book.Category,
-			"total_quantity":    book.TotalQuantity,
+			"id":                 book.ID,
+			"title":              book.Title,
+			"author":             book.Author,
+			"isbn":               book.ISBN,
+			"category":           book.Category,
+			"total_quantity":     book.TotalQuantity,
 			"available_quantity": book.AvailableQuantity,
-			"description":       book.Description,
-			"cover_image_url":   book.CoverImageURL,
-			"create_time":       book.CreateTime.Format("2006-01-02 15:04:05"),
-			"update_time":       book.UpdateTime.Format("2006-01-02 15:04:05"),
+			"description":        book.Description,
+			"cover_image_url":    book.CoverImageURL,
+			"create_time":        book.CreateTime.Format("2006-01-02 15:04:05"),
+			"update_time":        book.UpdateTime.Format("2006-01-02 15:04:05"),
 		},
 	})
 }
 
 // BookSearch 图书搜索
-func BookSearch(c *gin.Context) {
+func (h *BookHandler) BookSearch(c *gin.Context) {
 	var req BookSearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
@@ -255,54 +207,37 @@ func BookSearch(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-
-	// 构建查询
-	query := db.Model(&models.Book{})
-
-	// 关键词搜索（书名或作者）
-	if req.Keyword != "" {
-		keyword := "%" + req.Keyword + "%"
-		query = query.Where("title LIKE ? OR author LIKE ?", keyword, keyword)
-	}
-
-	// 分类筛选
-	if req.Category != "" {
-		query = query.Where("category = ?", req.Category)
+	books, total, err := h.books.Search(c.Request.Context(), repository.BookFilter{
+		Keyword:  req.Keyword,
+		Category: req.Category,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		utils.Error(c, 10001, "查询图书失败")
+		return
 	}
 
-	// 获取总数
-	var total int64
-	query.Count(&total)
-
 	// 如果没有结果
 	if total == 0 && req.Keyword != "" {
 		utils.Error(c, 10020, "搜索无结果")
 		return
 	}
 
-	// 分页查询
-	var books []models.Book
-	offset := (req.Page - 1) * req.Limit
-	if err := query.Offset(offset).Limit(req.Limit).Order("create_time DESC").Find(&books).Error; err != nil {
-		utils.Error(c, 10001, "查询图书失败")
-		return
-	}
-
 	// 格式化结果
 	bookList := make([]map[string]interface{}, len(books))
 	for i, book := range books {
 		bookList[i] = map[string]interface{}{
-			"id":                book.ID,
-			"title":             book.Title,
-			"author":            book.Author,
-			"isbn":              book.ISBN,
-			"category":          book.Category,
-			"total_quantity":    book.TotalQuantity,
+			"id":                 book.ID,
+			"title":              book.Title,
+			"author":             book.Author,
+			"isbn":               book.ISBN,
+			"category":           book.Category,
+			"total_quantity":     book.TotalQuantity,
 			"available_quantity": book.AvailableQuantity,
-			"description":       book.Description,
-			"cover_image_url":   book.CoverImageURL,
-			"create_time":       book.CreateTime.Format("2006-01-02 15:04:05"),
+			"description":        book.Description,
+			"cover_image_url":    book.CoverImageURL,
+			"create_time":        book.CreateTime.Format("2006-01-02 15:04:05"),
 		}
 	}
 
@@ -320,7 +255,7 @@ type UploadCoverRequest struct {
 }
 
 // UploadCover 上传图书封面
-func UploadCover(c *gin.Context) {
+func (h *BookHandler) UploadCover(c *gin.Context) {
 	var req UploadCoverRequest
 	if err := c.ShouldBind(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
@@ -364,43 +299,35 @@ func UploadCover(c *gin.Context) {
 	}
 
 	// 检查图书是否存在
-	db := database.GetDB()
-	var book models.Book
-	if err := db.First(&book, req.BookID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.Error(c, 10010, "图书不存在")
-		} else {
-			utils.Error(c, 10001, "查询图书失败")
-		}
+	book, ok := h.findBook(c, req.BookID)
+	if !ok {
 		return
 	}
 
 	// 检查R2服务是否可用
-	r2Service := services.GetR2Service()
-	if !r2Service.IsEnabled() {
+	r2Service := h.images
+	if r2Service == nil || !r2Service.IsEnabled() {
 		utils.Error(c, 10023, "图片存储服务未配置")
 		return
 	}
 
 	// 如果已有图片，先删除旧图片
 	if book.CoverImageURL != "" {
-		if err := r2Service.DeleteImage(book.CoverImageURL); err != nil {
+		if err := r2Service.DeleteImage(c.Request.Context(), book.CoverImageURL); err != nil {
 			// 记录错误但不阻止上传新图片
-			// log.Printf("Failed to delete old image: %v", err)
+			bookLog.WarnContext(c.Request.Context(), "删除旧封面失败", "error", err, "book_id", book.ID, "image_url", book.CoverImageURL)
 		}
 	}
 
 	// 上传到R2
-	imageURL, err := r2Service.UploadImage(req.BookID, imageData, file.Filename)
+	imageURL, err := r2Service.UploadImage(c.Request.Context(), req.BookID, imageData, file.Filename)
 	if err != nil {
 		utils.Error(c, 10023, "图片上传失败")
 		return
 	}
 
 	// 更新数据库
-	book.CoverImageURL = imageURL
-	book.UpdateTime = time.Now()
-	if err := db.Save(&book).Error; err != nil {
+	if err := h.books.SetCover(c.Request.Context(), book, imageURL); err != nil {
 		utils.Error(c, 10001, "更新图书记录失败")
 		return
 	}
@@ -417,23 +344,16 @@ type DeleteCoverRequest struct {
 }
 
 // DeleteCover 删除图书封面
-func DeleteCover(c *gin.Context) {
+func (h *BookHandler) DeleteCover(c *gin.Context) {
 	var req DeleteCoverRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
 	// 查找图书
-	var book models.Book
-	if err := db.First(&book, req.BookID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			utils.Error(c, 10010, "图书不存在")
-		} else {
-			utils.Error(c, 10001, "查询图书失败")
-		}
+	book, ok := h.findBook(c, req.BookID)
+	if !ok {
 		return
 	}
 
@@ -444,19 +364,17 @@ func DeleteCover(c *gin.Context) {
 	}
 
 	// 检查R2服务是否可用
-	r2Service := services.GetR2Service()
-	if r2Service.IsEnabled() {
+	r2Service := h.images
+	if r2Service != nil && r2Service.IsEnabled() {
 		// 从R2删除图片
-		if err := r2Service.DeleteImage(book.CoverImageURL); err != nil {
+		if err := r2Service.DeleteImage(c.Request.Context(), book.CoverImageURL); err != nil {
 			// 记录错误但不阻止删除数据库记录
-			// log.Printf("Failed to delete image from R2: %v", err)
+			bookLog.WarnContext(c.Request.Context(), "从R2删除封面失败", "error", err, "book_id", book.ID, "image_url", book.CoverImageURL)
 		}
 	}
 
 	// 清空数据库记录
-	book.CoverImageURL = ""
-	book.UpdateTime = time.Now()
-	if err := db.Save(&book).Error; err != nil {
+	if err := h.books.SetCover(c.Request.Context(), book, ""); err != nil {
 		utils.Error(c, 10001, "更新图书记录失败")
 		return
 	}
@@ -464,6 +382,20 @@ func DeleteCover(c *gin.Context) {
 	utils.Success(c, map[string]interface{}{})
 }
 
+// findBook 查找图书，不存在时已写入错误响应
+func (h *BookHandler) findBook(c *gin.Context, id int) (*models.Book, bool) {
+	book, err := h.books.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if err == services.ErrBookNotFound {
+			utils.Error(c, 10010, "图书不存在")
+		} else {
+			utils.Error(c, 10001, "查询图书失败")
+		}
+		return nil, false
+	}
+	return book, true
+}
+
 // contains 检查字符串是否在切片中
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
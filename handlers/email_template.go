@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"book-manage/services"
+	"book-manage/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailTemplateRequest 邮件模板请求（保存、预览、恢复默认）
+type EmailTemplateRequest struct {
+	Token    string `json:"token"` // token可选，中间件会处理
+	Key      string `json:"key" binding:"required"`
+	Locale   string `json:"locale" binding:"required"`
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body"`
+}
+
+// validateTemplateKey 校验模板标识和语言
+func validateTemplateKey(c *gin.Context, req *EmailTemplateRequest) bool {
+	if !services.IsTemplateKey(req.Key) {
+		utils.Error(c, 10032, "邮件模板不存在")
+		return false
+	}
+	if !services.IsSupportedLocale(req.Locale) {
+		utils.Error(c, 10001, "不支持的语言")
+		return false
+	}
+	return true
+}
+
+// EmailTemplateHandler 邮件模板管理接口
+type EmailTemplateHandler struct {
+	templates *services.TemplateService
+}
+
+// NewEmailTemplateHandler 创建邮件模板管理接口
+func NewEmailTemplateHandler(templates *services.TemplateService) *EmailTemplateHandler {
+	return &EmailTemplateHandler{templates: templates}
+}
+
+// EmailTemplateList 获取邮件模板列表（含内置模板及自定义模板）
+func (h *EmailTemplateHandler) EmailTemplateList(c *gin.Context) {
+	list, err := h.templates.List(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 10001, "查询邮件模板失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"list":    list,
+		"locales": services.SupportedLocales,
+	})
+}
+
+// SaveEmailTemplate 保存自定义邮件模板
+func (h *EmailTemplateHandler) SaveEmailTemplate(c *gin.Context) {
+	var req EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+	if !validateTemplateKey(c, &req) {
+		return
+	}
+	if req.Subject == "" || req.HTMLBody == "" || req.TextBody == "" {
+		utils.Error(c, 10001, "邮件主题、HTML内容和纯文本内容不能为空")
+		return
+	}
+
+	err := h.templates.Save(c.Request.Context(), &services.EmailTemplateContent{
+		Key:      req.Key,
+		Locale:   req.Locale,
+		Subject:  req.Subject,
+		HTMLBody: req.HTMLBody,
+		TextBody: req.TextBody,
+	})
+	if err != nil {
+		utils.Error(c, 10033, "邮件模板格式错误: "+err.Error())
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
+
+// ResetEmailTemplate 删除自定义邮件模板，恢复为内置模板
+func (h *EmailTemplateHandler) ResetEmailTemplate(c *gin.Context) {
+	var req EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+	if !validateTemplateKey(c, &req) {
+		return
+	}
+
+	if err := h.templates.Reset(c.Request.Context(), req.Key, req.Locale); err != nil {
+		utils.Error(c, 10001, "恢复邮件模板失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
+
+// PreviewEmailTemplate 使用示例数据预览邮件模板
+// 传入 subject/html_body/text_body 时预览未保存的内容，否则预览当前生效的模板
+func (h *EmailTemplateHandler) PreviewEmailTemplate(c *gin.Context) {
+	var req EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+	if !validateTemplateKey(c, &req) {
+		return
+	}
+
+	var content *services.EmailTemplateContent
+	if req.Subject != "" || req.HTMLBody != "" || req.TextBody != "" {
+		content = &services.EmailTemplateContent{
+			Subject:  req.Subject,
+			HTMLBody: req.HTMLBody,
+			TextBody: req.TextBody,
+		}
+	}
+
+	rendered, err := h.templates.Preview(c.Request.Context(), req.Key, req.Locale, content)
+	if err != nil {
+		utils.Error(c, 10033, "邮件模板格式错误: "+err.Error())
+		return
+	}
+
+	utils.Success(c, rendered)
+}
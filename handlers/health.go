@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"book-manage/buildinfo"
+	"book-manage/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 健康检查接口（供部署平台探测，不使用统一响应结构，通过HTTP状态码表示结果）
+type HealthHandler struct {
+	health *services.HealthService
+}
+
+// NewHealthHandler 创建健康检查接口
+func NewHealthHandler(health *services.HealthService) *HealthHandler {
+	return &HealthHandler{health: health}
+}
+
+// Healthz 存活检查：进程能处理请求即返回200，不检查外部依赖
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": services.HealthOK})
+}
+
+// Readyz 就绪检查：检查数据库、迁移、图片存储和邮件配置，全部正常返回200，否则返回503
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ready, checks := h.health.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	result := services.HealthOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+		result = services.HealthError
+	}
+	c.JSON(status, gin.H{
+		"status": result,
+		"checks": checks,
+	})
+}
+
+// Version 构建信息
+func (h *HealthHandler) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
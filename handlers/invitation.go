@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"book-manage/logging"
+	"book-manage/middleware"
+	"book-manage/repository"
+	"book-manage/services"
+	"book-manage/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// invitationLog 注册邀请日志
+var invitationLog = logging.For("invitation")
+
+// CreateInvitationRequest 创建注册邀请请求
+type CreateInvitationRequest struct {
+	Email         string `json:"email" binding:"required"`
+	Role          string `json:"role"`            // 可选：注册后分配的角色，默认 user
+	Group         string `json:"group"`           // 可选：注册后分配的分组
+	ExpiresInDays int    `json:"expires_in_days"` // 可选：有效天数（1-90），默认使用配置值
+	Locale        string `json:"locale"`          // 可选：邀请邮件语言（zh-CN、en），默认取 Accept-Language
+}
+
+// InvitationListRequest 邀请列表请求
+type InvitationListRequest struct {
+	Page   int    `json:"page" binding:"required,min=1"`
+	Limit  int    `json:"limit" binding:"required,min=1,max=100"`
+	Status string `json:"status"` // 可选：pending、used、revoked、expired
+	Email  string `json:"email"`  // 可选：按邮箱筛选
+}
+
+// RevokeInvitationRequest 吊销邀请请求
+type RevokeInvitationRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// InvitationHandler 注册邀请管理接口
+type InvitationHandler struct {
+	registration *services.RegistrationService
+	emails       *services.EmailService
+	rbac         *services.RBACService
+	auth         *services.AuthService
+}
+
+// NewInvitationHandler 创建注册邀请管理接口
+func NewInvitationHandler(registration *services.RegistrationService, emails *services.EmailService,
+	rbac *services.RBACService, auth *services.AuthService) *InvitationHandler {
+	return &InvitationHandler{registration: registration, emails: emails, rbac: rbac, auth: auth}
+}
+
+// CreateInvitation 创建注册邀请并发送邀请邮件
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if !utils.ValidateEmail(req.Email) {
+		utils.Error(c, 10002, "邮箱格式错误")
+		return
+	}
+
+	req.Role = strings.ToLower(strings.TrimSpace(req.Role))
+	if req.Role == "" {
+		req.Role = services.RoleUser
+	}
+	exists, err := h.rbac.RoleExists(c.Request.Context(), req.Role)
+	if err != nil {
+		utils.Error(c, 10001, "创建邀请失败")
+		return
+	}
+	if !exists {
+		utils.Error(c, 10029, "角色不存在")
+		return
+	}
+
+	// 只能分配权限不超出自身权限的角色
+	permissions, _ := middleware.CurrentPermissions(c)
+	allowed, err := h.rbac.CanAssignRole(c.Request.Context(), permissions, req.Role)
+	if err != nil {
+		utils.Error(c, 10001, "创建邀请失败")
+		return
+	}
+	if !allowed {
+		utils.Error(c, 10009, "不能分配超出自身权限的角色")
+		return
+	}
+
+	req.Group = strings.TrimSpace(req.Group)
+	if len([]rune(req.Group)) > 100 {
+		utils.Error(c, 10001, "分组名称不能超过100个字符")
+		return
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > 90 {
+		utils.Error(c, 10001, "有效天数应为1-90")
+		return
+	}
+
+	// 检查邮箱是否已注册
+	taken, err := h.auth.EmailTaken(c.Request.Context(), req.Email)
+	if err != nil {
+		utils.Error(c, 10001, "创建邀请失败")
+		return
+	}
+	if taken {
+		utils.Error(c, 10003, "邮箱已被注册")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	invitation, token, err := h.registration.CreateInvitation(c.Request.Context(), userID.(uint), req.Email, req.Role, req.Group,
+		time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		invitationLog.ErrorContext(c.Request.Context(), "创建邀请失败", "error", err)
+		utils.Error(c, 10001, "创建邀请失败")
+		return
+	}
+
+	// 发送邀请邮件（失败时管理员仍可将邀请码告知受邀人）
+	locale := req.Locale
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+	inviteURL := h.registration.InviteURL(token)
+	emailSent := true
+	if err := h.emails.SendInvitation(c.Request.Context(), invitation, token, inviteURL, locale); err != nil {
+		invitationLog.ErrorContext(c.Request.Context(), "发送邀请邮件失败", "error", err, "invitation_id", invitation.ID)
+		emailSent = false
+	}
+
+	// 明文邀请码仅在创建时返回一次
+	utils.Success(c, map[string]interface{}{
+		"invitation": invitation,
+		"token":      token,
+		"invite_url": inviteURL,
+		"email_sent": emailSent,
+	})
+}
+
+// InvitationList 获取注册邀请列表
+func (h *InvitationHandler) InvitationList(c *gin.Context) {
+	var req InvitationListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误: "+err.Error())
+		return
+	}
+
+	invitations, total, err := h.registration.ListInvitations(c.Request.Context(), repository.InvitationFilter{
+		Status: req.Status,
+		Email:  req.Email,
+		Page:   req.Page,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		utils.Error(c, 10001, "查询邀请失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"total": total,
+		"list":  invitations,
+	})
+}
+
+// RevokeInvitation 吊销未使用的注册邀请
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	var req RevokeInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	if err := h.registration.RevokeInvitation(c.Request.Context(), req.ID); err != nil {
+		if err == services.ErrInvitationInvalid {
+			utils.Error(c, 10039, "邀请无效或已过期")
+		} else {
+			utils.Error(c, 10001, "吊销邀请失败")
+		}
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"book-manage/services"
+	"book-manage/utils"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// roleNameRegex 角色名称格式：小写字母开头，允许小写字母、数字、下划线和短横线
+var roleNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_\-]{1,49}$`)
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Token       string   `json:"token"` // token可选，中间件会处理
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest 更新角色请求
+type UpdateRoleRequest struct {
+	Token       string    `json:"token"` // token可选，中间件会处理
+	ID          uint      `json:"id" binding:"required"`
+	Description *string   `json:"description"`
+	Permissions *[]string `json:"permissions"` // 传入时整体替换角色权限
+}
+
+// DeleteRoleRequest 删除角色请求
+type DeleteRoleRequest struct {
+	Token string `json:"token"` // token可选，中间件会处理
+	ID    uint   `json:"id" binding:"required"`
+}
+
+// SetUserRoleRequest 设置用户角色请求
+type SetUserRoleRequest struct {
+	Token  string `json:"token"` // token可选，中间件会处理
+	UserID uint   `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+}
+
+// RoleHandler 角色权限管理接口
+type RoleHandler struct {
+	rbac *services.RBACService
+}
+
+// NewRoleHandler 创建角色权限管理接口
+func NewRoleHandler(rbac *services.RBACService) *RoleHandler {
+	return &RoleHandler{rbac: rbac}
+}
+
+// PermissionList 获取全部权限
+func (h *RoleHandler) PermissionList(c *gin.Context) {
+	permissions, err := h.rbac.ListPermissions(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 10001, "查询权限失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"list": permissions,
+	})
+}
+
+// RoleList 获取角色列表
+func (h *RoleHandler) RoleList(c *gin.Context) {
+	roles, err := h.rbac.ListRoles(c.Request.Context())
+	if err != nil {
+		utils.Error(c, 10001, "查询角色失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"list": roles,
+	})
+}
+
+// CreateRole 创建角色
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	if !roleNameRegex.MatchString(req.Name) {
+		utils.Error(c, 10001, "角色名称格式错误")
+		return
+	}
+
+	role, err := h.rbac.CreateRole(c.Request.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		respondRoleError(c, err, "创建角色失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"role": role,
+	})
+}
+
+// UpdateRole 更新角色描述或权限（admin 角色始终拥有全部权限，不允许修改权限）
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	role, err := h.rbac.UpdateRole(c.Request.Context(), req.ID, req.Description, req.Permissions)
+	if err != nil {
+		respondRoleError(c, err, "更新角色失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"role": role,
+	})
+}
+
+// DeleteRole 删除角色（内置角色及仍有用户使用的角色不可删除）
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	var req DeleteRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	if err := h.rbac.DeleteRole(c.Request.Context(), req.ID); err != nil {
+		respondRoleError(c, err, "删除角色失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
+
+// SetUserRole 设置用户角色
+// 权限按请求时的角色解析，新角色对用户已签发的token立即生效
+func (h *RoleHandler) SetUserRole(c *gin.Context) {
+	var req SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	req.Role = strings.ToLower(strings.TrimSpace(req.Role))
+	if err := h.rbac.SetUserRole(c.Request.Context(), req.UserID, req.Role); err != nil {
+		if err == services.ErrUserNotFound {
+			utils.Error(c, 10001, "用户不存在")
+		} else {
+			respondRoleError(c, err, "设置用户角色失败")
+		}
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
+
+// respondRoleError 将角色服务的错误转换为错误响应，未知错误使用 message
+func respondRoleError(c *gin.Context, err error, message string) {
+	switch err {
+	case services.ErrRoleExists:
+		utils.Error(c, 10027, "角色已存在")
+	case services.ErrUnknownPermission:
+		utils.Error(c, 10028, "权限不存在")
+	case services.ErrRoleNotFound:
+		utils.Error(c, 10029, "角色不存在")
+	case services.ErrBuiltinRole:
+		utils.Error(c, 10030, "内置角色不可修改")
+	case services.ErrRoleInUse:
+		utils.Error(c, 10031, "角色仍有用户使用，无法删除")
+	default:
+		utils.Error(c, 10001, message)
+	}
+}
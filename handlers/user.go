@@ -1,24 +1,25 @@
 package handlers
 
 import (
-	"book-manage/database"
+	"book-manage/logging"
 	"book-manage/models"
 	"book-manage/services"
 	"book-manage/utils"
-	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
+// authLog 用户认证日志
+var authLog = logging.For("auth")
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Email           string `json:"email" binding:"required"`
 	Password        string `json:"password" binding:"required"`
 	ConfirmPassword string `json:"confirm_password" binding:"required"`
-	Code            string `json:"code" binding:"required"`
+	Code            string `json:"code"`         // 邮箱验证码，使用邀请注册时可不填
+	InviteToken     string `json:"invite_token"` // 邀请码（invite 模式下必填）
 }
 
 // LoginRequest 登录请求
@@ -27,10 +28,22 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// LoginByCodeRequest 验证码登录请求
+type LoginByCodeRequest struct {
+	Email string `json:"email" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// LoginByLinkRequest 登录链接登录请求
+type LoginByLinkRequest struct {
+	Token string `json:"token" binding:"required"` // 登录链接中的token参数
+}
+
 // SendEmailCodeRequest 发送验证码请求
 type SendEmailCodeRequest struct {
 	Email  string `json:"email" binding:"required"`
 	Action string `json:"action" binding:"required"`
+	Locale string `json:"locale"` // 可选：邮件语言（zh-CN、en），默认取 Accept-Language
 }
 
 // ForgetPasswordRequest 密码找回请求
@@ -41,6 +54,14 @@ type ForgetPasswordRequest struct {
 	ConfirmNewPassword string `json:"confirm_new_password" binding:"required"`
 }
 
+// SetPasswordRequest 通过欢迎邮件中的链接设置密码请求
+type SetPasswordRequest struct {
+	Email              string `json:"email" binding:"required"`
+	Token              string `json:"token" binding:"required"` // 设置密码链接中的token参数
+	NewPassword        string `json:"new_password" binding:"required"`
+	ConfirmNewPassword string `json:"confirm_new_password" binding:"required"`
+}
+
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
 	Token              string `json:"token"` // token可选，中间件会处理
@@ -49,16 +70,49 @@ type ChangePasswordRequest struct {
 	ConfirmNewPassword string `json:"confirm_new_password" binding:"required"`
 }
 
+// ChangeEmailRequest 修改邮箱请求
+type ChangeEmailRequest struct {
+	Token    string `json:"token"` // token可选，中间件会处理
+	NewEmail string `json:"new_email" binding:"required"`
+	Password string `json:"password" binding:"required"` // 当前密码
+	Code     string `json:"code" binding:"required"`     // 发送到新邮箱的验证码（action为change_email）
+	Locale   string `json:"locale"`                      // 可选：通知邮件语言（zh-CN、en），默认取 Accept-Language
+}
+
+// DeleteAccountRequest 注销账户请求
+type DeleteAccountRequest struct {
+	Token    string `json:"token"`                       // token可选，中间件会处理
+	Password string `json:"password" binding:"required"` // 当前密码
+}
+
+// UpdateProfileRequest 修改个人资料请求（未传入的字段保持不变）
+type UpdateProfileRequest struct {
+	Token string  `json:"token"` // token可选，中间件会处理
+	Name  *string `json:"name"`
+	Phone *string `json:"phone"`
+}
+
 // ProfileRequest 获取个人信息请求
 type ProfileRequest struct {
 	Token string `json:"token"` // token可选，中间件会处理
 }
 
+// AuthHandler 注册、登录和账户安全接口
+type AuthHandler struct {
+	auth         *services.AuthService
+	emails       *services.EmailService
+	registration *services.RegistrationService
+	accounts     *services.AccountService
+}
+
+// NewAuthHandler 创建注册、登录和账户安全接口
+func NewAuthHandler(auth *services.AuthService, emails *services.EmailService,
+	registration *services.RegistrationService, accounts *services.AccountService) *AuthHandler {
+	return &AuthHandler{auth: auth, emails: emails, registration: registration, accounts: accounts}
+}
 
 // Register 用户注册
-func Register(c *gin.Context) {
-	startTime := time.Now()
-	
+func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
@@ -71,9 +125,8 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// 验证密码长度
-	if !utils.ValidatePassword(req.Password) {
-		utils.Error(c, 10006, "密码长度不足")
+	// 验证密码策略
+	if !h.checkPasswordPolicy(c, req.Password, req.Email) {
 		return
 	}
 
@@ -83,70 +136,60 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// 验证验证码
-	emailService := services.GetEmailService()
-	if !emailService.VerifyCode(req.Email, "register", req.Code) {
-		utils.Error(c, 10004, "验证码错误或已过期")
-		return
-	}
-
-	db := database.GetDB()
-
-	// 检查邮箱是否已注册
-	var existingUser models.User
-	checkStart := time.Now()
-	if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		fmt.Printf("[Register] 邮箱检查耗时: %v\n", time.Since(checkStart))
-		utils.Error(c, 10003, "邮箱已被注册")
-		return
-	}
-	fmt.Printf("[Register] 邮箱检查耗时: %v\n", time.Since(checkStart))
-
-	// 加密密码
-	bcryptStart := time.Now()
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		fmt.Printf("[Register] 密码加密失败: %v\n", err)
-		utils.Error(c, 10001, "密码加密失败")
+	// 检查注册模式：邀请注册不受邮箱域名限制
+	var invitation *models.Invitation
+	if req.InviteToken != "" {
+		inv, err := h.registration.FindInvitation(c.Request.Context(), req.InviteToken, req.Email)
+		if err != nil {
+			if err == services.ErrInvitationInvalid {
+				utils.Error(c, 10039, "邀请无效或已过期")
+			} else {
+				utils.Error(c, 10001, "注册失败")
+			}
+			return
+		}
+		invitation = inv
+	} else if !h.checkRegistrationAllowed(c, req.Email) {
 		return
 	}
-	fmt.Printf("[Register] 密码加密耗时: %v\n", time.Since(bcryptStart))
 
-	// 创建用户（默认角色为user，可通过配置文件修改）
-	user := models.User{
-		Email:        req.Email,
-		Password:     string(hashedPassword),
-		Role:         "user",
-		RegisterTime: time.Now(),
-		Status:       "normal",
+	// 验证验证码（邀请码已通过邮件发送到该邮箱，使用邀请注册时无需验证码）
+	if invitation == nil {
+		if req.Code == "" || !h.emails.VerifyCode(c.Request.Context(), req.Email, "register", req.Code) {
+			utils.Error(c, 10004, "验证码错误或已过期")
+			return
+		}
 	}
 
-	createStart := time.Now()
-	if err := db.Create(&user).Error; err != nil {
-		fmt.Printf("[Register] 创建用户失败: %v, 耗时: %v\n", err, time.Since(createStart))
-		utils.Error(c, 10001, "注册失败")
+	// 创建用户（默认角色为user，使用邀请注册时使用邀请预先分配的角色和分组）
+	if _, err := h.auth.Register(c.Request.Context(), req.Email, req.Password, invitation); err != nil {
+		switch err {
+		case services.ErrEmailTaken:
+			utils.Error(c, 10003, "邮箱已被注册")
+		case services.ErrInvitationInvalid:
+			utils.Error(c, 10039, "邀请无效或已过期")
+		default:
+			authLog.ErrorContext(c.Request.Context(), "创建用户失败", "error", err)
+			utils.Error(c, 10001, "注册失败")
+		}
 		return
 	}
-	fmt.Printf("[Register] 创建用户耗时: %v\n", time.Since(createStart))
-	fmt.Printf("[Register] 总耗时: %v\n", time.Since(startTime))
 
 	utils.Success(c, map[string]interface{}{})
 }
 
 // Login 用户登录
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 查找用户
-	var user models.User
-	if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	// 查找用户并验证密码（哈希算法或参数已更新时重新哈希）
+	user, err := h.auth.Authenticate(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		if err == services.ErrUserNotFound || err == services.ErrWrongPassword {
 			utils.Error(c, 10007, "邮箱或密码错误")
 		} else {
 			utils.Error(c, 10001, "登录失败")
@@ -154,36 +197,68 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 验证密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		utils.Error(c, 10007, "邮箱或密码错误")
+	h.respondLogin(c, user)
+}
+
+// LoginByCode 使用邮箱验证码登录（无需密码）
+func (h *AuthHandler) LoginByCode(c *gin.Context) {
+	var req LoginByCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
 		return
 	}
 
-	// 检查账户状态
-	if user.Status != "normal" {
-		utils.Error(c, 10001, "账户已被禁用")
+	// 验证验证码
+	if !h.emails.VerifyCode(c.Request.Context(), req.Email, "login", req.Code) {
+		utils.Error(c, 10004, "验证码错误或已过期")
 		return
 	}
 
-	// 确定用户角色（优先检查邮箱白名单，再检查数据库role字段）
-	adminService := services.GetAdminService()
-	userRole := "user"
-	if adminService != nil {
-		role, err := adminService.GetUserRole(user.Email)
-		if err == nil {
-			userRole = role
+	h.loginByEmail(c, req.Email)
+}
+
+// LoginByLink 使用邮件中的一次性登录链接登录（无需密码）
+func (h *AuthHandler) LoginByLink(c *gin.Context) {
+	var req LoginByLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	email, ok := h.emails.VerifyLoginLink(c.Request.Context(), req.Token)
+	if !ok {
+		utils.Error(c, 10035, "登录链接无效或已过期")
+		return
+	}
+
+	h.loginByEmail(c, email)
+}
+
+// loginByEmail 邮箱校验通过后查找用户并签发token
+func (h *AuthHandler) loginByEmail(c *gin.Context, email string) {
+	user, err := h.auth.FindByEmail(c.Request.Context(), email)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.Error(c, 10008, "邮箱未注册")
 		} else {
-			// 如果获取角色失败，使用数据库中的role字段
-			userRole = user.Role
+			utils.Error(c, 10001, "登录失败")
 		}
-	} else {
-		// 如果adminService未初始化，使用数据库中的role字段
-		userRole = user.Role
+		return
 	}
 
-	// 生成token（使用确定的角色）
-	token, err := utils.GenerateToken(user.ID, user.Email, userRole)
+	h.respondLogin(c, user)
+}
+
+// respondLogin 检查账户状态并签发登录token（密码登录和免密登录共用）
+func (h *AuthHandler) respondLogin(c *gin.Context, user *models.User) {
+	// 检查账户状态（借阅权限暂停的用户仍可登录查看原因）
+	if user.Status == models.UserStatusDisabled {
+		utils.Error(c, 10001, "账户已被禁用")
+		return
+	}
+
+	// 确定用户角色并生成token（权限仅用于返回给客户端展示，接口鉴权时按当前角色重新解析）
+	session, err := h.auth.NewSession(c.Request.Context(), user)
 	if err != nil {
 		utils.Error(c, 10001, "生成token失败")
 		return
@@ -193,16 +268,17 @@ func Login(c *gin.Context) {
 		"user_info": map[string]interface{}{
 			"id":            user.ID,
 			"email":         user.Email,
-			"role":          userRole,
+			"role":          session.Role,
+			"permissions":   session.Permissions,
 			"register_time": user.RegisterTime.Format("2006-01-02 15:04:05"),
 			"status":        user.Status,
 		},
-		"token": token,
+		"token": session.Token,
 	})
 }
 
 // SendEmailCode 发送邮箱验证码
-func SendEmailCode(c *gin.Context) {
+func (h *AuthHandler) SendEmailCode(c *gin.Context) {
 	var req SendEmailCodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
@@ -216,51 +292,58 @@ func SendEmailCode(c *gin.Context) {
 	}
 
 	// 验证action
-	if req.Action != "register" && req.Action != "forget" {
+	if req.Action != "register" && req.Action != "forget" && req.Action != "login" && req.Action != "change_email" {
 		utils.Error(c, 10001, "action参数错误")
 		return
 	}
 
-	db := database.GetDB()
+	// 注册验证码受注册模式限制（邀请注册无需验证码）
+	if req.Action == "register" && !h.checkRegistrationAllowed(c, req.Email) {
+		return
+	}
 
-	// 如果是注册操作，检查邮箱是否已注册
-	if req.Action == "register" {
-		var existingUser models.User
-		if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-			utils.Error(c, 10003, "邮箱已被注册")
-			return
-		}
+	// 修改邮箱同样受邮箱域名限制
+	if req.Action == "change_email" && !h.registration.DomainAllowed(req.Email) {
+		utils.Error(c, 10037, "该邮箱域名不允许注册")
+		return
 	}
 
-	// 如果是忘记密码操作，检查邮箱是否已注册
-	if req.Action == "forget" {
-		var existingUser models.User
-		if err := db.Where("email = ?", req.Email).First(&existingUser).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				utils.Error(c, 10008, "邮箱未注册")
-			} else {
-				utils.Error(c, 10001, "发送验证码失败")
-			}
-			return
-		}
+	taken, err := h.auth.EmailTaken(c.Request.Context(), req.Email)
+	if err != nil {
+		utils.Error(c, 10001, "发送验证码失败")
+		return
+	}
+
+	// 如果是注册或修改邮箱操作，检查邮箱是否已注册
+	if (req.Action == "register" || req.Action == "change_email") && taken {
+		utils.Error(c, 10003, "邮箱已被注册")
+		return
+	}
+
+	// 如果是忘记密码或验证码登录操作，检查邮箱是否已注册
+	if (req.Action == "forget" || req.Action == "login") && !taken {
+		utils.Error(c, 10008, "邮箱未注册")
+		return
 	}
 
 	// 发送验证码
 	sendStart := time.Now()
-	emailService := services.GetEmailService()
-	_, err := emailService.SendCode(req.Email, req.Action)
-	if err != nil {
-		fmt.Printf("[SendEmailCode] 发送验证码失败 (耗时: %v): %v\n", time.Since(sendStart), err)
+	locale := req.Locale
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+	if _, err := h.emails.SendCode(c.Request.Context(), req.Email, req.Action, locale); err != nil {
+		authLog.WarnContext(c.Request.Context(), "发送验证码失败", "error", err, "action", req.Action, "elapsed_ms", time.Since(sendStart).Milliseconds())
 		utils.Error(c, 10001, err.Error())
 		return
 	}
-	fmt.Printf("[SendEmailCode] 发送验证码成功 (耗时: %v)\n", time.Since(sendStart))
+	authLog.InfoContext(c.Request.Context(), "发送验证码成功", "action", req.Action, "elapsed_ms", time.Since(sendStart).Milliseconds())
 
 	utils.Success(c, map[string]interface{}{})
 }
 
 // ForgetPassword 密码找回
-func ForgetPassword(c *gin.Context) {
+func (h *AuthHandler) ForgetPassword(c *gin.Context) {
 	var req ForgetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
@@ -273,9 +356,8 @@ func ForgetPassword(c *gin.Context) {
 		return
 	}
 
-	// 验证密码长度
-	if !utils.ValidatePassword(req.NewPassword) {
-		utils.Error(c, 10006, "密码长度不足")
+	// 验证密码策略
+	if !h.checkPasswordPolicy(c, req.NewPassword, req.Email) {
 		return
 	}
 
@@ -286,18 +368,15 @@ func ForgetPassword(c *gin.Context) {
 	}
 
 	// 验证验证码
-	emailService := services.GetEmailService()
-	if !emailService.VerifyCode(req.Email, "forget", req.Code) {
+	if !h.emails.VerifyCode(c.Request.Context(), req.Email, "forget", req.Code) {
 		utils.Error(c, 10004, "验证码错误或已过期")
 		return
 	}
 
-	db := database.GetDB()
-
 	// 查找用户
-	var user models.User
-	if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	user, err := h.auth.FindByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if err == services.ErrUserNotFound {
 			utils.Error(c, 10008, "邮箱未注册")
 		} else {
 			utils.Error(c, 10001, "密码重置失败")
@@ -305,15 +384,13 @@ func ForgetPassword(c *gin.Context) {
 		return
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
-		utils.Error(c, 10001, "密码加密失败")
+	// 检查是否与最近使用过的密码相同
+	if !h.checkPasswordReuse(c, user, req.NewPassword) {
 		return
 	}
 
 	// 更新密码
-	if err := db.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := h.auth.SetPassword(c.Request.Context(), user, req.NewPassword); err != nil {
 		utils.Error(c, 10001, "密码重置失败")
 		return
 	}
@@ -321,42 +398,133 @@ func ForgetPassword(c *gin.Context) {
 	utils.Success(c, map[string]interface{}{})
 }
 
+// SetPassword 通过欢迎邮件中的链接设置密码（批量导入的读者）
+func (h *AuthHandler) SetPassword(c *gin.Context) {
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	// 验证密码策略
+	if !h.checkPasswordPolicy(c, req.NewPassword, req.Email) {
+		return
+	}
+
+	// 验证密码一致性
+	if req.NewPassword != req.ConfirmNewPassword {
+		utils.Error(c, 10005, "密码不一致")
+		return
+	}
+
+	// 验证链接中的token
+	if !h.emails.VerifySetPasswordToken(c.Request.Context(), req.Email, req.Token) {
+		utils.Error(c, 10040, "设置密码链接无效或已过期")
+		return
+	}
+
+	// 查找用户
+	user, err := h.auth.FindByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.Error(c, 10008, "邮箱未注册")
+		} else {
+			utils.Error(c, 10001, "设置密码失败")
+		}
+		return
+	}
+
+	// 检查是否与最近使用过的密码相同
+	if !h.checkPasswordReuse(c, user, req.NewPassword) {
+		return
+	}
+
+	// 更新密码
+	if err := h.auth.SetPassword(c.Request.Context(), user, req.NewPassword); err != nil {
+		utils.Error(c, 10001, "设置密码失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
+
+// checkRegistrationAllowed 检查未使用邀请时是否允许该邮箱注册
+func (h *AuthHandler) checkRegistrationAllowed(c *gin.Context, email string) bool {
+	if h.registration.Mode() == services.RegistrationInvite {
+		utils.Error(c, 10038, "仅限受邀注册")
+		return false
+	}
+	if !h.registration.DomainAllowed(email) {
+		utils.Error(c, 10037, "该邮箱域名不允许注册")
+		return false
+	}
+	return true
+}
+
+// checkPasswordPolicy 校验密码策略，不符合时返回10006及具体原因
+func (h *AuthHandler) checkPasswordPolicy(c *gin.Context, password, email string) bool {
+	violations := h.auth.CheckPolicy(password, email)
+	if len(violations) > 0 {
+		utils.ErrorWithData(c, 10006, "密码不符合要求", map[string]interface{}{
+			"reasons": violations,
+		})
+		return false
+	}
+	return true
+}
+
+// checkPasswordReuse 检查新密码是否与最近使用过的密码相同，相同时返回10006及原因
+func (h *AuthHandler) checkPasswordReuse(c *gin.Context, user *models.User, password string) bool {
+	violations, err := h.auth.CheckReuse(c.Request.Context(), user, password)
+	if err != nil {
+		authLog.ErrorContext(c.Request.Context(), "查询历史密码失败", "error", err, "user_id", user.ID)
+		utils.Error(c, 10001, "修改密码失败")
+		return false
+	}
+	if len(violations) > 0 {
+		utils.ErrorWithData(c, 10006, "密码不符合要求", map[string]interface{}{
+			"reasons": violations,
+		})
+		return false
+	}
+	return true
+}
+
+// UserHandler 个人资料接口
+type UserHandler struct {
+	users *services.UserService
+}
+
+// NewUserHandler 创建个人资料接口
+func NewUserHandler(users *services.UserService) *UserHandler {
+	return &UserHandler{users: users}
+}
+
 // Profile 获取个人信息
-func Profile(c *gin.Context) {
+func (h *UserHandler) Profile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.Error(c, 10001, "用户未认证")
 		return
 	}
 
-	db := database.GetDB()
-
-	// 获取用户信息
-	var user models.User
-	if err := db.First(&user, userID).Error; err != nil {
+	// 获取用户信息及当前借阅数量（重新评估借阅权限，确保返回最新的暂停原因）
+	user, borrowCount, err := h.users.Profile(c.Request.Context(), userID.(uint))
+	if err != nil {
 		utils.Error(c, 10001, "获取用户信息失败")
 		return
 	}
 
-	// 获取当前借阅数量
-	var borrowCount int64
-	db.Model(&models.BorrowRecord{}).Where("user_id = ? AND status = ?", userID, "borrowed").Count(&borrowCount)
-
 	utils.Success(c, map[string]interface{}{
-		"user_info": map[string]interface{}{
-			"id":            user.ID,
-			"email":         user.Email,
-			"role":          user.Role,
-			"register_time": user.RegisterTime.Format("2006-01-02 15:04:05"),
-			"status":        user.Status,
-		},
+		"user_info":            services.UserProfile(user),
 		"current_borrow_count": borrowCount,
 	})
 }
 
-// ChangePassword 修改密码
-func ChangePassword(c *gin.Context) {
-	var req ChangePasswordRequest
+// UpdateProfile 修改个人资料（姓名、联系电话）
+// 借书卡号、学号、分组和资格到期日由管理员维护
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Error(c, 10001, "参数错误")
 		return
@@ -364,45 +532,205 @@ func ChangePassword(c *gin.Context) {
 
 	userID, _ := c.Get("user_id")
 
-	// 验证密码长度
-	if !utils.ValidatePassword(req.NewPassword) {
-		utils.Error(c, 10006, "密码长度不足")
+	user, err := h.users.Get(c.Request.Context(), userID.(uint))
+	if err != nil {
+		utils.Error(c, 10001, "获取用户信息失败")
+		return
+	}
+
+	updates, ok := buildProfileUpdates(c, ProfileFields{Name: req.Name, Phone: req.Phone})
+	if !ok {
 		return
 	}
 
+	if err := h.users.Update(c.Request.Context(), user, updates); err != nil {
+		respondProfileUpdateError(c, err, "修改个人资料失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"user_info": services.UserProfile(user),
+	})
+}
+
+// ChangePassword 修改密码
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
 	// 验证密码一致性
 	if req.NewPassword != req.ConfirmNewPassword {
 		utils.Error(c, 10005, "密码不一致")
 		return
 	}
 
-	db := database.GetDB()
-
 	// 获取用户信息
-	var user models.User
-	if err := db.First(&user, userID).Error; err != nil {
+	user, err := h.auth.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
 		utils.Error(c, 10001, "获取用户信息失败")
 		return
 	}
 
 	// 验证原密码
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.OldPassword)); err != nil {
+	if !h.auth.VerifyPassword(user, req.OldPassword) {
 		utils.Error(c, 10007, "原密码错误")
 		return
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
-	if err != nil {
-		utils.Error(c, 10001, "密码加密失败")
+	// 验证密码策略
+	if !h.checkPasswordPolicy(c, req.NewPassword, user.Email) {
+		return
+	}
+
+	// 检查是否与最近使用过的密码相同
+	if !h.checkPasswordReuse(c, user, req.NewPassword) {
 		return
 	}
 
 	// 更新密码
-	if err := db.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := h.auth.SetPassword(c.Request.Context(), user, req.NewPassword); err != nil {
 		utils.Error(c, 10001, "修改密码失败")
 		return
 	}
 
 	utils.Success(c, map[string]interface{}{})
 }
+
+// ChangeEmail 修改登录邮箱
+// 需要当前密码和发送到新邮箱的验证码；修改成功后通知旧邮箱，并吊销携带旧邮箱的所有token，返回新token
+func (h *AuthHandler) ChangeEmail(c *gin.Context) {
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	// 验证邮箱格式
+	if !utils.ValidateEmail(req.NewEmail) {
+		utils.Error(c, 10002, "邮箱格式错误")
+		return
+	}
+
+	// 获取用户信息
+	user, err := h.auth.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
+		utils.Error(c, 10001, "获取用户信息失败")
+		return
+	}
+
+	if req.NewEmail == user.Email {
+		utils.Error(c, 10001, "新邮箱与当前邮箱相同")
+		return
+	}
+
+	// 修改邮箱同样受邮箱域名限制
+	if !h.registration.DomainAllowed(req.NewEmail) {
+		utils.Error(c, 10037, "该邮箱域名不允许注册")
+		return
+	}
+
+	// 验证当前密码
+	if !h.auth.VerifyPassword(user, req.Password) {
+		utils.Error(c, 10007, "密码错误")
+		return
+	}
+
+	// 检查新邮箱是否已注册
+	taken, err := h.auth.EmailTaken(c.Request.Context(), req.NewEmail)
+	if err != nil {
+		utils.Error(c, 10001, "修改邮箱失败")
+		return
+	}
+	if taken {
+		utils.Error(c, 10003, "邮箱已被注册")
+		return
+	}
+
+	// 验证新邮箱收到的验证码
+	if !h.emails.VerifyCode(c.Request.Context(), req.NewEmail, "change_email", req.Code) {
+		utils.Error(c, 10004, "验证码错误或已过期")
+		return
+	}
+
+	// 更新邮箱并吊销携带旧邮箱的token
+	oldEmail := user.Email
+	if err := h.auth.ChangeEmail(c.Request.Context(), user, req.NewEmail); err != nil {
+		if err == services.ErrEmailTaken {
+			utils.Error(c, 10003, "邮箱已被注册")
+		} else {
+			utils.Error(c, 10001, "修改邮箱失败")
+		}
+		return
+	}
+	changedAt := time.Now()
+
+	// 通知旧邮箱（失败不影响修改结果）
+	locale := req.Locale
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+	if err := h.emails.SendEmailChangedNotice(c.Request.Context(), oldEmail, req.NewEmail, locale, changedAt); err != nil {
+		authLog.ErrorContext(c.Request.Context(), "发送邮箱变更通知失败", "error", err, "user_id", user.ID)
+	}
+
+	// 签发携带新邮箱的token
+	h.respondLogin(c, user)
+}
+
+// ExportData 导出个人数据（个人信息、借阅记录、验证码记录、API密钥）
+func (h *AuthHandler) ExportData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	export, err := h.accounts.Export(c.Request.Context(), userID.(uint))
+	if err != nil {
+		authLog.ErrorContext(c.Request.Context(), "导出个人数据失败", "error", err, "user_id", userID)
+		utils.Error(c, 10001, "导出个人数据失败")
+		return
+	}
+
+	utils.Success(c, export)
+}
+
+// DeleteAccount 注销账户
+// 需要当前密码；存在未归还的图书时不能注销
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	// 获取用户信息
+	user, err := h.auth.FindByID(c.Request.Context(), userID.(uint))
+	if err != nil {
+		utils.Error(c, 10001, "获取用户信息失败")
+		return
+	}
+
+	// 验证当前密码
+	if !h.auth.VerifyPassword(user, req.Password) {
+		utils.Error(c, 10007, "密码错误")
+		return
+	}
+
+	if err := h.accounts.Delete(c.Request.Context(), user.ID); err != nil {
+		if err == services.ErrOpenLoans {
+			utils.Error(c, 10036, "存在未归还的图书，请归还后再注销账户")
+		} else {
+			authLog.ErrorContext(c.Request.Context(), "注销账户失败", "error", err, "user_id", user.ID)
+			utils.Error(c, 10001, "注销账户失败")
+		}
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{})
+}
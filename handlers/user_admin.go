@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"book-manage/logging"
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/services"
+	"book-manage/utils"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importLog 批量导入日志
+var importLog = logging.For("import")
+
+// importWriteTimeout 批量导入请求的写入超时（随机密码模式下逐行哈希，耗时可能超过服务器默认的写入超时）
+const importWriteTimeout = 2 * time.Minute
+
+// ProfileFields 可修改的资料字段（nil表示不修改，空字符串表示清空）
+type ProfileFields struct {
+	Name                *string
+	Phone               *string
+	StudentID           *string
+	CardNumber          *string
+	Group               *string
+	MembershipExpiresAt *string // 格式 2006-01-02
+}
+
+// UserListRequest 读者列表请求
+type UserListRequest struct {
+	Token      string `json:"token"` // token可选，中间件会处理
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Keyword    string `json:"keyword"`     // 可选：按邮箱、姓名或联系电话模糊搜索
+	CardNumber string `json:"card_number"` // 可选：按借书卡号精确查找
+	StudentID  string `json:"student_id"`  // 可选：按学号精确查找
+	Group      string `json:"group"`       // 可选：按分组筛选
+	Status     string `json:"status"`      // 可选：normal、disabled
+}
+
+// UpdateUserRequest 管理员修改读者资料请求（未传入的字段保持不变）
+type UpdateUserRequest struct {
+	Token               string  `json:"token"` // token可选，中间件会处理
+	UserID              uint    `json:"user_id" binding:"required"`
+	Name                *string `json:"name"`
+	Phone               *string `json:"phone"`
+	StudentID           *string `json:"student_id"`
+	CardNumber          *string `json:"card_number"`
+	Group               *string `json:"group"`
+	MembershipExpiresAt *string `json:"membership_expires_at"` // 格式 2006-01-02，空字符串表示长期有效
+}
+
+// SuspendUserRequest 管理员暂停读者借阅请求
+type SuspendUserRequest struct {
+	Token  string `json:"token"` // token可选，中间件会处理
+	UserID uint   `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+	Until  string `json:"until"` // 可选：截止日期（含当天），格式 2006-01-02，为空表示直到管理员解除
+}
+
+// UnsuspendUserRequest 管理员解除读者借阅暂停请求
+type UnsuspendUserRequest struct {
+	Token  string `json:"token"` // token可选，中间件会处理
+	UserID uint   `json:"user_id" binding:"required"`
+}
+
+// ImportUsersRequest 批量导入读者请求（multipart/form-data，CSV文件字段为 file）
+type ImportUsersRequest struct {
+	PasswordMode string `form:"password_mode"` // random（默认）或 link
+	SendWelcome  bool   `form:"send_welcome"`  // 是否发送欢迎邮件
+	DryRun       bool   `form:"dry_run"`       // 仅校验，不创建用户
+	Locale       string `form:"locale"`        // 欢迎邮件语言（zh-CN、en），默认取 Accept-Language
+}
+
+// UserAdminHandler 读者管理接口
+type UserAdminHandler struct {
+	users      *services.UserService
+	suspension *services.SuspensionService
+	imports    *services.ImportService
+}
+
+// NewUserAdminHandler 创建读者管理接口
+func NewUserAdminHandler(users *services.UserService, suspension *services.SuspensionService,
+	imports *services.ImportService) *UserAdminHandler {
+	return &UserAdminHandler{users: users, suspension: suspension, imports: imports}
+}
+
+// UserList 管理员查看读者列表
+func (h *UserAdminHandler) UserList(c *gin.Context) {
+	var req UserListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	// 设置默认值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 10
+	}
+
+	users, total, err := h.users.Search(c.Request.Context(), repository.UserFilter{
+		Keyword:    strings.TrimSpace(req.Keyword),
+		CardNumber: strings.TrimSpace(req.CardNumber),
+		StudentID:  strings.TrimSpace(req.StudentID),
+		Group:      req.Group,
+		Status:     req.Status,
+		Page:       req.Page,
+		Limit:      req.Limit,
+	})
+	if err != nil {
+		utils.Error(c, 10001, "查询读者失败")
+		return
+	}
+
+	list := make([]map[string]interface{}, 0, len(users))
+	for i := range users {
+		list = append(list, services.UserProfile(&users[i]))
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"list":  list,
+		"total": total,
+		"page":  req.Page,
+		"limit": req.Limit,
+	})
+}
+
+// UpdateUser 管理员修改读者资料
+func (h *UserAdminHandler) UpdateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	user, ok := h.findManagedUser(c, req.UserID)
+	if !ok {
+		return
+	}
+
+	updates, ok := buildProfileUpdates(c, ProfileFields{
+		Name:                req.Name,
+		Phone:               req.Phone,
+		StudentID:           req.StudentID,
+		CardNumber:          req.CardNumber,
+		Group:               req.Group,
+		MembershipExpiresAt: req.MembershipExpiresAt,
+	})
+	if !ok {
+		return
+	}
+
+	// 续期资格后可能解除借阅暂停
+	if err := h.users.Update(c.Request.Context(), user, updates); err != nil {
+		respondProfileUpdateError(c, err, "修改读者资料失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"user_info": services.UserProfile(user),
+	})
+}
+
+// SuspendUser 管理员手动暂停读者借阅
+func (h *UserAdminHandler) SuspendUser(c *gin.Context) {
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > 255 {
+		utils.Error(c, 10001, "暂停原因不能为空且不能超过255个字符")
+		return
+	}
+	until, err := services.ParseMembershipExpiry(strings.TrimSpace(req.Until))
+	if err != nil {
+		utils.Error(c, 10001, "截止日期格式错误，应为 YYYY-MM-DD")
+		return
+	}
+
+	user, ok := h.findManagedUser(c, req.UserID)
+	if !ok {
+		return
+	}
+	if user.Status == models.UserStatusDisabled {
+		utils.Error(c, 10001, "账户已被禁用")
+		return
+	}
+
+	if err := h.suspension.Suspend(c.Request.Context(), user, reason, until); err != nil {
+		utils.Error(c, 10001, "暂停借阅失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"user_info": services.UserProfile(user),
+	})
+}
+
+// UnsuspendUser 管理员解除读者借阅暂停
+// 解除后按规则重新评估，仍触发自动暂停规则（如资格未续期）时会再次暂停
+func (h *UserAdminHandler) UnsuspendUser(c *gin.Context) {
+	var req UnsuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	user, ok := h.findManagedUser(c, req.UserID)
+	if !ok {
+		return
+	}
+
+	if err := h.suspension.Lift(c.Request.Context(), user); err != nil {
+		utils.Error(c, 10001, "解除暂停失败")
+		return
+	}
+
+	utils.Success(c, map[string]interface{}{
+		"user_info": services.UserProfile(user),
+	})
+}
+
+// ImportUsers 从CSV批量导入读者
+func (h *UserAdminHandler) ImportUsers(c *gin.Context) {
+	var req ImportUsersRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.Error(c, 10001, "参数错误")
+		return
+	}
+
+	if req.PasswordMode == "" {
+		req.PasswordMode = services.ImportPasswordRandom
+	}
+	if req.PasswordMode != services.ImportPasswordRandom && req.PasswordMode != services.ImportPasswordLink {
+		utils.Error(c, 10001, "password_mode参数错误")
+		return
+	}
+	if req.PasswordMode == services.ImportPasswordLink {
+		// 未设置密码的用户只能通过欢迎邮件中的链接设置密码
+		if !req.SendWelcome {
+			utils.Error(c, 10001, "password_mode为link时必须发送欢迎邮件")
+			return
+		}
+		if !h.imports.LinkModeAvailable() {
+			utils.Error(c, 10001, "未配置设置密码页面地址（registration.set_password_url）")
+			return
+		}
+	}
+	if req.Locale == "" {
+		req.Locale = c.GetHeader("Accept-Language")
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.Error(c, 10001, "请选择CSV文件")
+		return
+	}
+	if file.Size > 2*1024*1024 {
+		utils.Error(c, 10001, "CSV文件不能超过 2MB")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		utils.Error(c, 10001, "无法读取CSV文件")
+		return
+	}
+	defer src.Close()
+
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(importWriteTimeout)); err != nil {
+		importLog.DebugContext(c.Request.Context(), "无法延长写入超时", "error", err)
+	}
+
+	result, err := h.imports.Import(c.Request.Context(), src, services.ImportOptions{
+		PasswordMode: req.PasswordMode,
+		SendWelcome:  req.SendWelcome,
+		DryRun:       req.DryRun,
+		Locale:       req.Locale,
+	})
+	if err != nil {
+		utils.Error(c, 10001, err.Error())
+		return
+	}
+
+	importLog.InfoContext(c.Request.Context(), "导入完成", "total", result.Total, "created", result.Created,
+		"skipped", result.Skipped, "failed", result.Failed, "dry_run", result.DryRun)
+	utils.Success(c, result)
+}
+
+// findManagedUser 查找未注销的读者，不存在时已写入错误响应
+func (h *UserAdminHandler) findManagedUser(c *gin.Context, userID uint) (*models.User, bool) {
+	user, err := h.users.GetManaged(c.Request.Context(), userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			utils.Error(c, 10001, "用户不存在")
+		} else {
+			utils.Error(c, 10001, "查询用户失败")
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// respondProfileUpdateError 写入修改资料失败的错误响应（学号、借书卡号冲突返回对应错误码）
+func respondProfileUpdateError(c *gin.Context, err error, message string) {
+	switch err {
+	case services.ErrStudentIDTaken:
+		utils.Error(c, 10042, "学号已被使用")
+	case services.ErrCardNumberTaken:
+		utils.Error(c, 10041, "借书卡号已被使用")
+	default:
+		utils.Error(c, 10001, message)
+	}
+}
+
+// buildProfileUpdates 校验资料字段格式并生成更新内容，校验失败时已写入错误响应
+// 学号、借书卡号的唯一性由 UserService.Update 检查，见 respondProfileUpdateError
+func buildProfileUpdates(c *gin.Context, fields ProfileFields) (map[string]interface{}, bool) {
+	updates := make(map[string]interface{})
+
+	if fields.Name != nil {
+		name := strings.TrimSpace(*fields.Name)
+		if utf8.RuneCountInString(name) > 100 {
+			utils.Error(c, 10001, "姓名不能超过100个字符")
+			return nil, false
+		}
+		updates["name"] = name
+	}
+
+	if fields.Phone != nil {
+		phone := strings.TrimSpace(*fields.Phone)
+		if phone != "" && !utils.ValidatePhone(phone) {
+			utils.Error(c, 10001, "联系电话格式错误")
+			return nil, false
+		}
+		updates["phone"] = phone
+	}
+
+	if fields.StudentID != nil {
+		studentID := strings.TrimSpace(*fields.StudentID)
+		if studentID == "" {
+			updates["student_id"] = nil
+		} else {
+			if utf8.RuneCountInString(studentID) > 50 {
+				utils.Error(c, 10001, "学号不能超过50个字符")
+				return nil, false
+			}
+			updates["student_id"] = studentID
+		}
+	}
+
+	if fields.CardNumber != nil {
+		cardNumber := strings.TrimSpace(*fields.CardNumber)
+		if cardNumber == "" {
+			updates["card_number"] = nil
+		} else {
+			if !utils.ValidateCardNumber(cardNumber) {
+				utils.Error(c, 10001, "借书卡号格式错误")
+				return nil, false
+			}
+			updates["card_number"] = cardNumber
+		}
+	}
+
+	if fields.Group != nil {
+		group := strings.TrimSpace(*fields.Group)
+		if utf8.RuneCountInString(group) > 100 {
+			utils.Error(c, 10001, "分组不能超过100个字符")
+			return nil, false
+		}
+		updates["user_group"] = group
+	}
+
+	if fields.MembershipExpiresAt != nil {
+		expiresAt, err := services.ParseMembershipExpiry(strings.TrimSpace(*fields.MembershipExpiresAt))
+		if err != nil {
+			utils.Error(c, 10001, "资格到期日格式错误，应为 YYYY-MM-DD")
+			return nil, false
+		}
+		updates["membership_expires_at"] = expiresAt
+	}
+
+	return updates, true
+}
@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// gormSlowThreshold 慢查询阈值
+const gormSlowThreshold = 200 * time.Millisecond
+
+// GormLogger GORM日志适配器，输出到 gorm 组件
+// 执行失败的SQL记录为 error（不包括记录不存在），慢查询记录为 warn，其余SQL仅在 debug 级别记录
+type GormLogger struct {
+	log *slog.Logger
+}
+
+// NewGormLogger 创建GORM日志适配器，级别由日志配置中的 gorm 组件决定
+func NewGormLogger() *GormLogger {
+	return &GormLogger{log: For("gorm")}
+}
+
+// LogMode 级别由日志配置决定，忽略GORM设置的级别
+func (l *GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.log.InfoContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.log.WarnContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.log.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+}
+
+// Trace 记录SQL执行结果
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.log.ErrorContext(ctx, "SQL执行失败", "error", err, "sql", sql, "rows", rows, "elapsed_ms", elapsed.Milliseconds())
+	case elapsed > gormSlowThreshold:
+		sql, rows := fc()
+		l.log.WarnContext(ctx, "慢查询", "sql", sql, "rows", rows, "elapsed_ms", elapsed.Milliseconds())
+	case l.log.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		l.log.DebugContext(ctx, "SQL", "sql", sql, "rows", rows, "elapsed_ms", elapsed.Milliseconds())
+	}
+}
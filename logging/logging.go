@@ -0,0 +1,151 @@
+// Package logging 基于 log/slog 的结构化日志
+//
+// 各模块通过 For 获取带组件名的日志记录器，组件级别可单独配置（如 gorm、outbox）。
+// 日志自动附带请求ID和链路追踪ID（需使用 *Context 方法并传入请求的 context），
+// 密码、token、验证码等敏感字段在输出前脱敏。
+package logging
+
+import (
+	"book-manage/config"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// state 当前日志配置，Init 之前使用默认配置（JSON 输出到标准输出，级别 info）
+type state struct {
+	base   slog.Handler
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+var (
+	mu      sync.RWMutex
+	current = state{
+		base:  newHandler(os.Stdout, "json"),
+		level: slog.LevelInfo,
+	}
+)
+
+// Init 按配置初始化日志，并将 slog 默认日志记录器设置为 app 组件
+func Init(cfg *config.LogConfig) error {
+	return initWithWriter(os.Stdout, cfg)
+}
+
+// initWithWriter 按配置初始化日志并输出到 w
+func initWithWriter(w io.Writer, cfg *config.LogConfig) error {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]slog.Level, len(cfg.Levels))
+	for component, value := range cfg.Levels {
+		l, err := ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", component, err)
+		}
+		levels[strings.ToLower(component)] = l
+	}
+
+	format := strings.ToLower(cfg.Format)
+	switch format {
+	case "", "json", "text":
+	default:
+		return fmt.Errorf("unsupported log format: %s", cfg.Format)
+	}
+
+	mu.Lock()
+	current = state{base: newHandler(w, format), level: level, levels: levels}
+	mu.Unlock()
+
+	slog.SetDefault(For("app"))
+	return nil
+}
+
+// ParseLevel 解析日志级别：debug、info、warn、error，为空时为 info
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unsupported log level: %s", value)
+	}
+}
+
+// For 获取组件日志记录器，可在 Init 之前调用（级别和输出格式在记录时读取当前配置）
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: strings.ToLower(component)})
+}
+
+// newHandler 创建输出处理器，级别由 componentHandler 控制，此处不再过滤
+func newHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redact}
+	if format == "text" {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// resolve 当前输出处理器和组件的日志级别（组件未单独配置时使用默认级别）
+func resolve(component string) (slog.Handler, slog.Level) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if level, ok := current.levels[component]; ok {
+		return current.base, level
+	}
+	return current.base, current.level
+}
+
+// componentHandler 为日志附加组件名、请求ID和链路追踪ID，并按组件级别过滤
+type componentHandler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler // WithAttrs/WithGroup 调用，记录时按顺序应用
+}
+
+func (h *componentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	_, min := resolve(h.component)
+	return level >= min
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	base, _ := resolve(h.component)
+
+	attrs := []slog.Attr{slog.String("component", h.component)}
+	if id := RequestID(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	handler := base.WithAttrs(attrs)
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *componentHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &componentHandler{component: h.component, ops: append(ops, op)}
+}
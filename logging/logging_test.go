@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"book-manage/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureLogs 按配置初始化日志并返回输出缓冲区，测试结束后恢复默认配置
+func captureLogs(t *testing.T, cfg config.LogConfig) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := initWithWriter(&buf, &cfg); err != nil {
+		t.Fatalf("init logging: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = initWithWriter(os.Stdout, &config.LogConfig{})
+	})
+	return &buf
+}
+
+// entries 解析JSON日志，每行一条
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestComponentAndRequestID(t *testing.T) {
+	buf := captureLogs(t, config.LogConfig{Level: "info"})
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	For("borrow").InfoContext(ctx, "借书失败", "book_id", 1)
+
+	logs := entries(t, buf)
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log line, got %d", len(logs))
+	}
+	if logs[0]["component"] != "borrow" || logs[0]["request_id"] != "req-123" || logs[0]["book_id"] != float64(1) {
+		t.Fatalf("unexpected log entry: %v", logs[0])
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := captureLogs(t, config.LogConfig{Level: "warn", Levels: map[string]string{"outbox": "debug", "GORM": "error"}})
+
+	For("outbox").Debug("outbox debug")
+	For("gorm").Warn("gorm warn")
+	For("email").Info("email info")
+	For("email").Warn("email warn")
+
+	var messages []string
+	for _, entry := range entries(t, buf) {
+		messages = append(messages, entry["msg"].(string))
+	}
+	if got := strings.Join(messages, ","); got != "outbox debug,email warn" {
+		t.Fatalf("unexpected messages: %s", got)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	buf := captureLogs(t, config.LogConfig{})
+
+	logger := For("auth").With("access_token", "abc")
+	logger.Info("敏感字段",
+		"password", "secret-password",
+		"Authorization", "Bearer xyz",
+		"api_key", "re_123",
+		"code", "123456",
+		"verify_code", "654321",
+		"status_code", 200,
+		"email", "user@example.com",
+	)
+
+	output := buf.String()
+	for _, secret := range []string{"abc", "secret-password", "Bearer xyz", "re_123", "123456", "654321"} {
+		if strings.Contains(output, secret) {
+			t.Fatalf("log output contains %q: %s", secret, output)
+		}
+	}
+	entry := entries(t, buf)[0]
+	if entry["status_code"] != float64(200) || entry["email"] != "user@example.com" {
+		t.Fatalf("non-sensitive fields should be kept: %v", entry)
+	}
+}
+
+func TestInitRejectsInvalidConfig(t *testing.T) {
+	for _, cfg := range []config.LogConfig{
+		{Level: "verbose"},
+		{Format: "xml"},
+		{Levels: map[string]string{"gorm": "loud"}},
+	} {
+		if err := initWithWriter(&bytes.Buffer{}, &cfg); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+}
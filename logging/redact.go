@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// redacted 脱敏后的字段值
+const redacted = "[REDACTED]"
+
+// sensitiveKeys 字段名包含以下内容时脱敏（不区分大小写）
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "cookie"}
+
+// redact 脱敏敏感字段：密码、token、密钥，以及字符串类型的验证码（code、*_code）
+// 整数类型的 code 为业务码，不脱敏
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	if (key == "code" || strings.HasSuffix(key, "_code")) && a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
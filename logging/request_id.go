@@ -0,0 +1,20 @@
+package logging
+
+import "context"
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID写入 context，使用该 context 记录的日志自动附带请求ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 获取 context 中的请求ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"book-manage/app"
 	"book-manage/config"
 	"book-manage/database"
-	"book-manage/handlers"
-	"book-manage/middleware"
-	"book-manage/services"
-	"book-manage/utils"
-	"fmt"
-	"log"
+	"book-manage/logging"
+	"book-manage/tracing"
+	"context"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,100 +21,45 @@ func main() {
 	// 加载配置
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		slog.Error("Failed to load config", "error", err)
+		os.Exit(1)
 	}
 
-	// 设置JWT密钥
-	utils.SetJWTSecret(cfg.JWT.Secret)
-
-	// 初始化数据库
-	if err := database.InitDB(cfg); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-
-	// 初始化管理员服务
-	services.InitAdminService(cfg)
-
-	// 初始化邮件服务
-	services.InitEmailService(&cfg.Email)
-
-	// 初始化R2服务
-	if err := services.InitR2Service(&cfg.CloudflareR2); err != nil {
-		log.Printf("Warning: Failed to initialize R2 service: %v (图片上传功能将不可用)", err)
-	}
-
-	// 初始化中间件（传入配置）
-	middleware.InitMiddleware(cfg)
-
-	// 创建Gin路由
-	r := gin.Default()
-
-	// 添加CORS中间件
-	r.Use(middleware.CORSMiddleware())
-
-	// 用户管理模块（无需登录）
-	userGroup := r.Group("/api/user")
-	{
-		userGroup.POST("/register", handlers.Register)
-		userGroup.POST("/login", handlers.Login)
-		userGroup.POST("/sendEmailCode", handlers.SendEmailCode)
-		userGroup.POST("/forgetPassword", handlers.ForgetPassword)
+	// 初始化日志（级别、格式和各组件级别见 config.LogConfig）
+	if err := logging.Init(&cfg.Log); err != nil {
+		slog.Error("Failed to initialize logging", "error", err)
+		os.Exit(1)
 	}
-
-	// 用户管理模块（需要登录）
-	userAuthGroup := r.Group("/api/user")
-	userAuthGroup.Use(middleware.AuthMiddleware())
-	{
-		userAuthGroup.POST("/profile", handlers.Profile)
-		userAuthGroup.POST("/changePassword", handlers.ChangePassword)
-		userAuthGroup.POST("/borrowRecords", handlers.BorrowRecords)
+	// 未通过 GIN_MODE 指定时使用 release 模式，避免 Gin 输出调试日志
+	if os.Getenv("GIN_MODE") == "" {
+		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// 图书管理模块（需要登录）
-	bookGroup := r.Group("/api/book")
-	bookGroup.Use(middleware.AuthMiddleware())
-	{
-		bookGroup.POST("/detail", handlers.BookDetail)
-		bookGroup.POST("/search", handlers.BookSearch)
+	// 数据库迁移子命令：book-manage migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			slog.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// 图书管理模块（需要管理员权限）
-	bookAdminGroup := r.Group("/api/book")
-	bookAdminGroup.Use(middleware.AuthMiddleware())
-	bookAdminGroup.Use(middleware.AdminMiddleware())
-	{
-		bookAdminGroup.POST("/add", handlers.AddBook)
-		bookAdminGroup.POST("/edit", handlers.EditBook)
-		bookAdminGroup.POST("/delete", handlers.DeleteBook)
-		bookAdminGroup.POST("/uploadCover", handlers.UploadCover)
-		bookAdminGroup.POST("/deleteCover", handlers.DeleteCover)
-	}
-
-	// 借阅管理模块（需要登录）
-	borrowGroup := r.Group("/api/borrow")
-	borrowGroup.Use(middleware.AuthMiddleware())
-	{
-		borrowGroup.POST("/borrow", handlers.Borrow)
-		borrowGroup.POST("/return", handlers.Return)
-		borrowGroup.POST("/records", handlers.BorrowRecords)
+	// 初始化链路追踪（未启用时为空操作）
+	shutdownTracing, err := tracing.Init(context.Background(), &cfg.Tracing)
+	if err != nil {
+		slog.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
 	}
 
-	// 借阅管理模块（需要管理员权限）
-	borrowAdminGroup := r.Group("/api/borrow")
-	borrowAdminGroup.Use(middleware.AuthMiddleware())
-	borrowAdminGroup.Use(middleware.AdminMiddleware())
-	{
-		borrowAdminGroup.POST("/allRecords", handlers.AllRecords)
+	// 初始化服务并创建路由
+	application, err := app.New(cfg, app.Options{})
+	if err != nil {
+		slog.Error("Failed to initialize application", "error", err)
+		os.Exit(1)
 	}
 
-	// 管理员模块（需要管理员权限）
-	adminGroup := r.Group("/api/admin")
-	adminGroup.Use(middleware.AuthMiddleware())
-	adminGroup.Use(middleware.AdminMiddleware())
-	{
-		adminGroup.POST("/emailCodeList", handlers.EmailCodeList)
-		adminGroup.POST("/emailCodeStats", handlers.EmailCodeStats)
-	}
+	// 启动后台任务（HTTP服务停止后再停止）
+	application.Workers.Start(context.Background())
 
 	// 启动服务器
 	port := cfg.Server.Port
@@ -120,20 +67,62 @@ func main() {
 		port = "8080"
 	}
 
-	fmt.Printf("Server is running on port %s\n", port)
-	fmt.Printf("API文档请参考 API.md\n")
-	fmt.Printf("数据库连接: %s@%s:%s/%s\n", cfg.Database.User, cfg.Database.Host, cfg.Database.Port, cfg.Database.Database)
+	dsn := "sqlite://" + cfg.Database.Path
+	if cfg.Database.Driver != "sqlite" {
+		dsn = cfg.Database.User + "@" + cfg.Database.Host + ":" + cfg.Database.Port + "/" + cfg.Database.Database
+	}
+	slog.Info("Server is running", "port", port, "database", dsn)
 
 	// 创建HTTP服务器并配置超时时间（与前端一致：10秒）
 	srv := &http.Server{
 		Addr:         ":" + port,
-		Handler:      r,
+		Handler:      application.Router,
 		ReadTimeout:  10 * time.Second,  // 读取超时：10秒
-		WriteTimeout: 10 * time.Second, // 写入超时：10秒
+		WriteTimeout: 10 * time.Second,  // 写入超时：10秒
 		IdleTimeout:  120 * time.Second, // 空闲连接超时：2分钟
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Failed to start server: %v", err)
+	// 收到 SIGINT/SIGTERM 时开始优雅退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Failed to start server", "error", err)
+			exitCode = 1
+		}
+	case <-ctx.Done():
+		slog.Info("收到退出信号，正在关闭服务", "timeout_seconds", cfg.Server.ShutdownTimeoutSeconds)
+	}
+	// 恢复默认信号处理，再次收到信号时立即退出
+	stop()
+
+	// 先停止接收新请求并等待进行中的请求完成，再停止后台任务，最后关闭数据库连接池
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("HTTP server shutdown", "error", err)
+	}
+	if err := application.Workers.Stop(shutdownCtx); err != nil {
+		slog.Warn("Failed to stop background workers", "error", err)
+	}
+	if err := database.Close(); err != nil {
+		slog.Warn("Failed to close database", "error", err)
+	}
+	// 最后导出尚未发送的span
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
 }
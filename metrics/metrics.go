@@ -0,0 +1,143 @@
+// Package metrics Prometheus 指标（HTTP请求、数据库连接池和业务事件），通过 /metrics 接口暴露
+package metrics
+
+import (
+	"book-manage/utils"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 指标名称前缀
+const namespace = "book_manage"
+
+// 邮件发送结果
+const (
+	EmailSent   = "sent"   // 发送成功
+	EmailRetry  = "retry"  // 发送失败，稍后重试
+	EmailFailed = "failed" // 达到最大重试次数，不再发送
+)
+
+// Registry 应用指标注册表（包含Go运行时和进程指标）
+var Registry = prometheus.NewRegistry()
+
+var (
+	// httpRequestDuration HTTP请求耗时，按路由、HTTP状态码和业务码区分
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, HTTP status and business code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status", "code"})
+
+	// Borrows 借书成功次数
+	Borrows = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "borrows_total",
+		Help:      "Number of books borrowed.",
+	})
+
+	// Returns 还书成功次数
+	Returns = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "returns_total",
+		Help:      "Number of books returned.",
+	})
+
+	// Emails 邮件发送结果，按邮件类型和结果（sent、retry、failed）区分
+	Emails = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "emails_total",
+		Help:      "Email delivery attempts by category and result (sent, retry, failed).",
+	}, []string{"category", "result"})
+
+	// R2UploadBytes 上传到R2的图片字节数
+	R2UploadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "r2_upload_bytes_total",
+		Help:      "Bytes of images uploaded to R2.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		Borrows,
+		Returns,
+		Emails,
+		R2UploadBytes,
+	)
+}
+
+// RegisterDBStats 注册数据库连接池指标（sql.DB.Stats），重复注册时忽略
+func RegisterDBStats(db *sql.DB, name string) error {
+	return register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterOverdueLoans 注册逾期未还借阅数量指标，每次采集时调用 count 查询（查询失败时为 -1）
+func RegisterOverdueLoans(count func() (int64, error)) error {
+	return register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "overdue_loans",
+		Help:      "Number of borrowed books past their due date.",
+	}, func() float64 {
+		n, err := count()
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	}))
+}
+
+// register 注册采集器，已注册时忽略（测试中可能多次创建应用）
+func register(c prometheus.Collector) error {
+	if err := Registry.Register(c); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Middleware 记录每个请求的耗时
+// 路由使用Gin的路由模板（如 /api/book/detail），未匹配的路由记为 unmatched，避免指标基数过大
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		code := ""
+		if v, ok := c.Get(utils.ResponseCodeKey); ok {
+			code = strconv.Itoa(v.(int))
+		}
+		httpRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), code).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler /metrics 接口，token 不为空时需携带 Authorization: Bearer <token>
+func Handler(token string) gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"book-manage/config"
 	"book-manage/services"
 	"book-manage/utils"
 	"bytes"
@@ -13,16 +12,30 @@ import (
 )
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
-var cfg *config.Config
 
-// InitMiddleware 初始化中间件（传入配置）
-func InitMiddleware(config *config.Config) {
-	cfg = config
+// Auth 认证和权限校验中间件
+type Auth struct {
+	tokens  *services.TokenService
+	apiKeys *services.APIKeyService
+	admin   *services.AdminService
+	rbac    *services.RBACService
+}
+
+// NewAuth 创建认证和权限校验中间件
+func NewAuth(tokens *services.TokenService, apiKeys *services.APIKeyService,
+	admin *services.AdminService, rbac *services.RBACService) *Auth {
+	return &Auth{tokens: tokens, apiKeys: apiKeys, admin: admin, rbac: rbac}
 }
 
 // AuthMiddleware 认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+func (a *Auth) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 优先使用API密钥认证（供脚本和第三方集成使用）
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			a.authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		// 从Header、Query参数或请求体中获取token
 		var token string
 
@@ -47,7 +60,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			if err == nil {
 				// 恢复请求体
 				c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-				
+
 				// 解析JSON获取token
 				var jsonData map[string]interface{}
 				if json.Unmarshal(bodyBytes, &jsonData) == nil {
@@ -71,6 +84,16 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查token是否已被吊销（如修改邮箱后，携带旧邮箱的token失效）
+		if claims.IssuedAt != nil {
+			revoked, err := a.tokens.IsRevoked(c.Request.Context(), claims.Email, claims.IssuedAt.Time)
+			if err != nil || revoked {
+				utils.Error(c, 10001, "token无效或已过期")
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息存储到上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
@@ -80,45 +103,91 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AdminMiddleware 管理员权限中间件
-// 判断逻辑：1. 优先检查邮箱白名单 2. 检查JWT token中的role字段 3. 检查数据库role字段
-func AdminMiddleware() gin.HandlerFunc {
+// authenticateAPIKey 使用API密钥认证，并检查密钥是否具备当前路由所需的权限范围
+func (a *Auth) authenticateAPIKey(c *gin.Context, rawKey string) {
+	key, user, err := a.apiKeys.Authenticate(c.Request.Context(), rawKey)
+	if err != nil {
+		utils.Error(c, 10001, "API密钥无效或已过期")
+		c.Abort()
+		return
+	}
+
+	// 未配置权限范围的路由（如修改密码、管理API密钥）不允许通过API密钥访问
+	scope, ok := services.RouteScope(c.FullPath())
+	if !ok || !services.HasScope(key, scope) {
+		utils.Error(c, 10009, "权限不足")
+		c.Abort()
+		return
+	}
+
+	// 确定用户角色（与登录时的逻辑一致）
+	role := user.Role
+	if r, err := a.admin.GetUserRole(c.Request.Context(), user.Email); err == nil {
+		role = r
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("user_email", user.Email)
+	c.Set("user_role", role)
+	c.Set("api_key_id", key.ID)
+
+	c.Next()
+}
+
+// RequirePermission 权限校验中间件，需在AuthMiddleware之后使用
+// 权限按用户在数据库中的当前角色解析，角色变更后下一次请求即生效
+func (a *Auth) RequirePermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userEmail, exists := c.Get("user_email")
-		if !exists {
+		permissions, ok := a.resolvePermissions(c)
+		if !ok || !services.HasPermission(permissions, perm) {
 			utils.Error(c, 10009, "权限不足")
 			c.Abort()
 			return
 		}
 
-		email := userEmail.(string)
+		c.Next()
+	}
+}
 
-		// 优先检查邮箱白名单
-		if cfg != nil && cfg.IsAdminEmail(email) {
-			c.Next()
-			return
-		}
+// resolvePermissions 解析当前请求用户的权限列表（同一请求内只解析一次）
+func (a *Auth) resolvePermissions(c *gin.Context) ([]string, bool) {
+	if permissions, ok := CurrentPermissions(c); ok {
+		return permissions, true
+	}
 
-		// 检查JWT token中的role字段
-		role, exists := c.Get("user_role")
-		if exists && role == "admin" {
-			c.Next()
-			return
-		}
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		return nil, false
+	}
+	email := userEmail.(string)
+	ctx := c.Request.Context()
 
-		// 最后检查数据库role字段
-		adminService := services.GetAdminService()
-		if adminService != nil {
-			isAdmin, err := adminService.IsAdmin(email)
-			if err == nil && isAdmin {
-				// 更新上下文中的角色
-				c.Set("user_role", "admin")
-				c.Next()
-				return
-			}
+	// 以数据库中的当前角色为准（token中的角色可能已过时）
+	role := ""
+	if r, err := a.admin.GetUserRole(ctx, email); err == nil {
+		role = r
+	}
+	if role == "" {
+		if r, exists := c.Get("user_role"); exists {
+			role, _ = r.(string)
 		}
+	}
 
-		utils.Error(c, 10009, "权限不足")
-		c.Abort()
+	permissions, err := a.rbac.ResolvePermissions(ctx, email, role)
+	if err != nil {
+		return nil, false
+	}
+	c.Set("user_permissions", permissions)
+	return permissions, true
+}
+
+// CurrentPermissions 获取RequirePermission已解析的当前用户权限列表
+// 仅在RequirePermission之后的处理函数中可用
+func CurrentPermissions(c *gin.Context) ([]string, bool) {
+	if value, exists := c.Get("user_permissions"); exists {
+		if permissions, ok := value.([]string); ok {
+			return permissions, true
+		}
 	}
+	return nil, false
 }
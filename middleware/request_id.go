@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"book-manage/logging"
+	"book-manage/utils"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern 允许沿用的客户端请求ID格式，不符合时重新生成
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// httpLog 访问日志
+var httpLog = logging.For("http")
+
+// RequestIDMiddleware 请求ID中间件
+// 沿用客户端或网关传入的 X-Request-ID（格式不合法时重新生成），写入响应头和请求 context，日志自动附带
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLogMiddleware 访问日志中间件，记录请求方法、路由、状态码、业务码和耗时
+// 只记录路径，不记录查询参数（可能包含token）
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if code, ok := c.Get(utils.ResponseCodeKey); ok {
+			attrs = append(attrs, slog.Any("code", code))
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		httpLog.LogAttrs(c.Request.Context(), level, "请求完成", attrs...)
+	}
+}
+
+// RecoveryMiddleware 捕获处理请求时的panic，记录错误和调用栈后返回500
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err interface{}) {
+		httpLog.ErrorContext(c.Request.Context(), "处理请求时发生panic",
+			"error", err, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"book-manage/config"
+	"book-manage/database"
+	"fmt"
+	"strconv"
+)
+
+// migrateUsage migrate 子命令用法
+const migrateUsage = `用法：
+  book-manage migrate up          执行全部未执行的迁移
+  book-manage migrate down [N]    回滚最近执行的 N 个迁移（默认 1）
+  book-manage migrate status      查看迁移执行状态`
+
+// runMigrate 执行 migrate 子命令
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("缺少迁移命令\n%s", migrateUsage)
+	}
+
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(database.GetDB())
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("没有需要执行的迁移")
+		}
+		for _, m := range applied {
+			fmt.Printf("已执行 %04d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("回滚步数必须为正整数: %s", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := database.MigrateDown(database.GetDB(), steps)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("没有可回滚的迁移")
+		}
+		for _, m := range rolledBack {
+			fmt.Printf("已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus(database.GetDB())
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "未执行"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-45s %s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("未知的迁移命令: %s\n%s", args[0], migrateUsage)
+	}
+	return nil
+}
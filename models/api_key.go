@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey 个人API密钥模型
+// 密钥格式为 bk_{prefix}_{secret}，数据库仅保存前缀和密钥的SHA-256哈希
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	Name       string     `gorm:"not null;size:50" json:"name"`
+	Prefix     string     `gorm:"uniqueIndex;not null;size:16" json:"prefix"`
+	SecretHash string     `gorm:"column:secret_hash;not null;size:64" json:"-"`
+	Scopes     string     `gorm:"not null;size:200" json:"scopes"` // 逗号分隔，如 catalog:read,circulation
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at;index" json:"revoked_at"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_key"
+}
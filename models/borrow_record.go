@@ -4,6 +4,12 @@ import (
 	"time"
 )
 
+// 借阅状态
+const (
+	BorrowStatusBorrowed = "borrowed" // 借阅中
+	BorrowStatusReturned = "returned" // 已归还
+)
+
 // BorrowRecord 借阅记录模型
 type BorrowRecord struct {
 	ID         uint       `gorm:"primaryKey" json:"id"`
@@ -24,13 +30,15 @@ func (BorrowRecord) TableName() string {
 
 // BorrowRecordWithDetails 包含详细信息的借阅记录
 type BorrowRecordWithDetails struct {
-	ID         uint      `json:"id"`
-	UserID     uint      `json:"user_id,omitempty"`
-	UserEmail  string    `json:"user_email,omitempty"`
-	BookID     uint      `json:"book_id"`
-	BookTitle  string    `json:"book_title"`
-	BorrowDate time.Time `json:"borrow_date"`
-	DueDate    time.Time `json:"due_date"`
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id,omitempty"`
+	UserEmail  string     `json:"user_email,omitempty"`
+	UserName   string     `json:"user_name,omitempty"`
+	CardNumber string     `json:"card_number,omitempty"`
+	BookID     uint       `json:"book_id"`
+	BookTitle  string     `json:"book_title"`
+	BorrowDate time.Time  `json:"borrow_date"`
+	DueDate    time.Time  `json:"due_date"`
 	ReturnDate *time.Time `json:"return_date"`
-	Status     string    `json:"status"`
+	Status     string     `json:"status"`
 }
@@ -6,18 +6,19 @@ import (
 
 // EmailCodeRecord 邮箱验证码记录模型
 type EmailCodeRecord struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	Email      string    `gorm:"not null;size:100;index" json:"email"`
-	Code       string    `gorm:"not null;size:10" json:"code"`
-	Action     string    `gorm:"not null;size:20;index" json:"action"` // register, forget
-	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"created_at"`
-	ExpiresAt  time.Time `gorm:"column:expires_at;not null;index" json:"expires_at"`
-	IsUsed     bool      `gorm:"column:is_used;default:false;index" json:"is_used"`
-	UsedAt     *time.Time `gorm:"column:used_at" json:"used_at"`
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Email     string     `gorm:"not null;size:100;index" json:"email"`
+	Code      string     `gorm:"not null;size:10" json:"code"`         // 遮盖后的验证码，如 1****6，不保存明文
+	Action    string     `gorm:"not null;size:20;index" json:"action"` // register, forget, login, change_email, set_password
+	CreatedAt time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null;index" json:"expires_at"`
+	IsUsed    bool       `gorm:"column:is_used;default:false;index" json:"is_used"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	// InvalidatedAt 作废时间（重新发送或错误次数过多时作废）
+	InvalidatedAt *time.Time `gorm:"column:invalidated_at" json:"invalidated_at"`
 }
 
 // TableName 指定表名
 func (EmailCodeRecord) TableName() string {
 	return "email_code_record"
 }
-
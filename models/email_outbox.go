@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// 邮件发件箱状态
+const (
+	OutboxStatusPending = "pending" // 等待发送（含等待重试）
+	OutboxStatusSending = "sending" // 发送中（已被某个实例领取）
+	OutboxStatusSent    = "sent"    // 发送成功
+	OutboxStatusFailed  = "failed"  // 重试次数耗尽，发送失败
+)
+
+// EmailOutbox 邮件发件箱
+// 邮件先写入发件箱再由后台worker异步发送，失败后按指数退避重试
+type EmailOutbox struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Category          string     `gorm:"not null;size:50;index" json:"category"` // 邮件类型，如 code_register
+	FromEmail         string     `gorm:"column:from_email;not null;size:200" json:"from_email"`
+	ToEmail           string     `gorm:"column:to_email;not null;size:500;index" json:"to_email"` // 多个收件人以逗号分隔
+	Subject           string     `gorm:"not null;size:200" json:"subject"`
+	HTMLBody          string     `gorm:"column:html_body;type:text" json:"-"`
+	TextBody          string     `gorm:"column:text_body;type:text" json:"-"`
+	Status            string     `gorm:"type:varchar(10);not null;default:'pending';index:idx_email_outbox_status_next" json:"status"`
+	Attempts          int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts       int        `gorm:"column:max_attempts;not null" json:"max_attempts"`
+	NextAttemptAt     time.Time  `gorm:"column:next_attempt_at;not null;index:idx_email_outbox_status_next" json:"next_attempt_at"`
+	LockedAt          *time.Time `gorm:"column:locked_at" json:"locked_at"`
+	LastError         string     `gorm:"column:last_error;type:text" json:"last_error"`
+	Provider          string     `gorm:"size:20" json:"provider"`
+	ProviderMessageID string     `gorm:"column:provider_message_id;size:200" json:"provider_message_id"`
+	CreatedAt         time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	SentAt            *time.Time `gorm:"column:sent_at" json:"sent_at"`
+}
+
+// TableName 指定表名
+func (EmailOutbox) TableName() string {
+	return "email_outbox"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// EmailTemplate 邮件模板（管理员自定义的模板，覆盖系统内置模板）
+type EmailTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Key       string    `gorm:"column:template_key;not null;size:50;uniqueIndex:idx_email_template_key_locale" json:"key"` // 模板标识，如 code_register
+	Locale    string    `gorm:"not null;size:10;uniqueIndex:idx_email_template_key_locale" json:"locale"`                  // 语言，如 zh-CN、en
+	Subject   string    `gorm:"not null;size:200" json:"subject"`
+	HTMLBody  string    `gorm:"column:html_body;type:text;not null" json:"html_body"`
+	TextBody  string    `gorm:"column:text_body;type:text;not null" json:"text_body"`
+	UpdatedAt time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EmailTemplate) TableName() string {
+	return "email_template"
+}
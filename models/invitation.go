@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+// Invitation 注册邀请
+// 邀请码仅保存SHA-256哈希，明文只在创建时返回并通过邮件发送给受邀人
+type Invitation struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Email     string     `gorm:"not null;size:100;index" json:"email"` // 受邀邮箱，注册时必须一致
+	TokenHash string     `gorm:"column:token_hash;uniqueIndex;not null;size:64" json:"-"`
+	Role      string     `gorm:"type:varchar(50);not null;default:'user'" json:"role"` // 注册后分配的角色
+	Group     string     `gorm:"column:user_group;size:100" json:"group"`              // 注册后分配的分组
+	CreatedBy uint       `gorm:"not null" json:"created_by"`
+	CreatedAt time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	UsedBy    *uint      `gorm:"column:used_by" json:"used_by"`
+	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+// TableName 指定表名
+func (Invitation) TableName() string {
+	return "invitation"
+}
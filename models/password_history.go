@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory 历史密码记录（用于禁止重复使用最近的密码）
+type PasswordHistory struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	PasswordHash string    `gorm:"column:password_hash;not null;size:255" json:"-"`
+	CreatedAt    time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Role 角色模型
+// 角色名称对应 User.Role 字段，内置角色 admin（拥有全部权限）和 user（普通读者）
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"uniqueIndex;not null;size:50" json:"name"`
+	Description string       `gorm:"size:200" json:"description"`
+	BuiltIn     bool         `gorm:"column:built_in;default:false" json:"built_in"`
+	Permissions []Permission `gorm:"many2many:role_permission" json:"permissions"`
+	CreatedAt   time.Time    `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 指定表名
+func (Role) TableName() string {
+	return "role"
+}
+
+// Permission 权限模型
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Code        string `gorm:"uniqueIndex;not null;size:50" json:"code"` // 如 book:edit
+	Description string `gorm:"size:200" json:"description"`
+}
+
+// TableName 指定表名
+func (Permission) TableName() string {
+	return "permission"
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenRevocation 登录token吊销记录
+// 签发时间不晚于 RevokedAt 且携带该邮箱的token均视为已吊销（如修改邮箱后旧邮箱签发的token）
+type TokenRevocation struct {
+	Email     string    `gorm:"primaryKey;size:100" json:"email"`
+	RevokedAt time.Time `gorm:"not null" json:"revoked_at"`
+}
+
+// TableName 指定表名
+func (TokenRevocation) TableName() string {
+	return "token_revocation"
+}
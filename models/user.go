@@ -4,14 +4,34 @@ import (
 	"time"
 )
 
+// 用户状态
+const (
+	UserStatusNormal    = "normal"    // 正常
+	UserStatusSuspended = "suspended" // 借阅权限已暂停（可登录，不能借书）
+	UserStatusDisabled  = "disabled"  // 已禁用（不能登录）
+)
+
 // User 用户模型
 type User struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	Email        string    `gorm:"uniqueIndex;not null;size:100" json:"email"`
-	Password     string    `gorm:"not null;size:100" json:"-"`
-	Role         string    `gorm:"type:varchar(10);default:'user';check:role IN ('admin','user')" json:"role"`
+	Password     string    `gorm:"not null;size:255" json:"-"`                        // 密码哈希（bcrypt或argon2id，自带算法和参数）
+	Role         string    `gorm:"type:varchar(50);default:'user';index" json:"role"` // 对应 Role.Name
 	RegisterTime time.Time `gorm:"column:register_time;default:CURRENT_TIMESTAMP" json:"register_time"`
-	Status       string    `gorm:"type:varchar(10);default:'normal';check:status IN ('normal','disabled')" json:"status"`
+	Status       string    `gorm:"type:varchar(10);default:'normal';check:status IN ('normal','suspended','disabled')" json:"status"`
+	Name         string    `gorm:"size:100" json:"name"`                                      // 姓名
+	StudentID    *string   `gorm:"column:student_id;size:50;uniqueIndex" json:"student_id"`   // 学号（可为空，非空时唯一）
+	Group        string    `gorm:"column:user_group;size:100;index" json:"group"`             // 分组（如院系、班级），可由邀请预先分配
+	Phone        string    `gorm:"size:30" json:"phone"`                                      // 联系电话
+	CardNumber   *string   `gorm:"column:card_number;size:50;uniqueIndex" json:"card_number"` // 借书卡号（可为空，非空时唯一）
+	// MembershipExpiresAt 读者资格到期日（为空表示长期有效）
+	MembershipExpiresAt *time.Time `gorm:"column:membership_expires_at" json:"membership_expires_at"`
+	// 借阅权限暂停信息（status为suspended时有效）
+	SuspendedReason string     `gorm:"column:suspended_reason;size:255" json:"suspended_reason"`
+	SuspendedUntil  *time.Time `gorm:"column:suspended_until" json:"suspended_until"`         // 手动暂停的截止日期（含当天），为空表示直到解除
+	SuspensionRule  string     `gorm:"column:suspension_rule;size:50" json:"suspension_rule"` // 触发暂停的规则，manual 表示管理员手动暂停
+	// AnonymizedAt 注销时间，注销后邮箱、密码等个人信息被清除，仅保留用户行用于借阅统计
+	AnonymizedAt *time.Time `gorm:"column:anonymized_at" json:"anonymized_at,omitempty"`
 }
 
 // TableName 指定表名
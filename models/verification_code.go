@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// VerificationCode 待校验的验证码（PostgreSQL验证码存储使用）
+// 每个邮箱+用途仅保留一条记录，重新发送时覆盖；email_code_record 仅用于管理员审计
+type VerificationCode struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"not null;size:100;uniqueIndex:idx_verification_code_email_action" json:"email"`
+	Action    string    `gorm:"not null;size:20;uniqueIndex:idx_verification_code_email_action" json:"action"`
+	CodeHash  string    `gorm:"column:code_hash;not null;default:'';size:100" json:"-"` // 加盐哈希，格式：{salt}${sha256}
+	Attempts  int       `gorm:"not null;default:0" json:"attempts"`                     // 错误校验次数
+	SentAt    time.Time `gorm:"column:sent_at;not null" json:"sent_at"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index" json:"expires_at"`
+}
+
+// TableName 指定表名
+func (VerificationCode) TableName() string {
+	return "verification_code"
+}
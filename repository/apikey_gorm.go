@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormAPIKeyRepo 基于数据库表 api_key 的API密钥仓储
+type GormAPIKeyRepo struct {
+	db *gorm.DB
+}
+
+// NewGormAPIKeyRepo 创建数据库API密钥仓储
+func NewGormAPIKeyRepo(db *gorm.DB) *GormAPIKeyRepo {
+	return &GormAPIKeyRepo{db: db}
+}
+
+// Create 创建API密钥
+func (r *GormAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+// FindByPrefix 按前缀查找API密钥及其所属用户
+func (r *GormAPIKeyRepo) FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Preload("User").Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &key, nil
+}
+
+// TouchLastUsed 记录最近使用时间
+func (r *GormAPIKeyRepo) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+// ListByUser 获取用户的全部API密钥
+func (r *GormAPIKeyRepo) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke 吊销用户未吊销的API密钥
+func (r *GormAPIKeyRepo) Revoke(ctx context.Context, userID, keyID uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// GormBookRepo 基于数据库表 book 的图书仓储
+type GormBookRepo struct {
+	db *gorm.DB
+}
+
+// NewGormBookRepo 创建数据库图书仓储
+func NewGormBookRepo(db *gorm.DB) *GormBookRepo {
+	return &GormBookRepo{db: db}
+}
+
+// FindByID 按ID查找图书
+func (r *GormBookRepo) FindByID(ctx context.Context, id uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.WithContext(ctx).First(&book, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &book, nil
+}
+
+// FindByISBN 按ISBN查找图书
+func (r *GormBookRepo) FindByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.WithContext(ctx).Where("isbn = ?", isbn).First(&book).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &book, nil
+}
+
+// Search 分页查询图书
+func (r *GormBookRepo) Search(ctx context.Context, filter BookFilter) ([]models.Book, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Book{})
+
+	// 关键词搜索（书名或作者）
+	if filter.Keyword != "" {
+		keyword := "%" + filter.Keyword + "%"
+		query = query.Where("title LIKE ? OR author LIKE ?", keyword, keyword)
+	}
+
+	// 分类筛选
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var books []models.Book
+	if err := query.Offset(offset(filter.Page, filter.Limit)).Limit(filter.Limit).Order("create_time DESC").Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	return books, total, nil
+}
+
+// Create 创建图书
+func (r *GormBookRepo) Create(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Create(book).Error
+}
+
+// Save 保存图书
+func (r *GormBookRepo) Save(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Save(book).Error
+}
+
+// Delete 删除图书
+func (r *GormBookRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Book{}, id).Error
+}
+
+// translateError 将GORM的记录不存在错误转换为ErrNotFound
+func translateError(err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// isDuplicateKey 判断是否为违反唯一约束的错误（按数据库方言转换驱动错误）
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// paginate 按页码和每页数量分页，limit 不大于0时不分页
+func paginate(query *gorm.DB, page, limit int) *gorm.DB {
+	if limit <= 0 {
+		return query
+	}
+	return query.Offset(offset(page, limit)).Limit(limit)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"book-manage/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCodeThrottled 同一邮箱+用途在限流时间内重复请求验证码
+var ErrCodeThrottled = errors.New("请求过于频繁，请稍后再试")
+
+// VerifyResult 验证码校验结果
+type VerifyResult int
+
+const (
+	VerifyOK       VerifyResult = iota // 校验成功，验证码已消费
+	VerifyMismatch                     // 验证码错误，已累计一次失败
+	VerifyNotFound                     // 验证码不存在或已过期
+	VerifyLocked                       // 错误次数达到上限，验证码已作废
+)
+
+// CodeRepo 验证码存储
+// 频率限制、过期、错误次数和校验都由存储实现，多实例部署时需使用共享存储（PostgreSQL或Redis）
+// 存储中只保存加盐哈希，不保存明文验证码
+type CodeRepo interface {
+	// Save 保存验证码，覆盖（作废）同一邮箱+用途的旧验证码
+	// 距上次发送不足throttle时返回ErrCodeThrottled
+	Save(ctx context.Context, email, action, code string, ttl, throttle time.Duration) error
+	// Verify 校验验证码（跨实例原子操作）
+	// 成功后验证码立即失效；失败累计maxAttempts次后验证码作废，maxAttempts不大于0时不限制错误次数
+	Verify(ctx context.Context, email, action, code string, maxAttempts int) (VerifyResult, error)
+	// Delete 删除同一邮箱+用途的验证码（如已通过其他方式完成校验）
+	Delete(ctx context.Context, email, action string) error
+	// Cleanup 清理过期验证码（Redis等自带过期机制的实现可为空操作）
+	Cleanup(ctx context.Context) error
+}
+
+// NewCodeRepo 根据配置创建验证码存储，driver 为 postgres 时使用 db 所连接的数据库
+func NewCodeRepo(cfg *config.CodeStoreConfig, db *gorm.DB) (CodeRepo, error) {
+	driver := "postgres"
+	if cfg != nil && cfg.Driver != "" {
+		driver = strings.ToLower(cfg.Driver)
+	}
+
+	switch driver {
+	case "postgres":
+		return NewGormCodeRepo(db), nil
+	case "redis":
+		if cfg.RedisAddr == "" {
+			return nil, fmt.Errorf("redis_addr is required for redis code store")
+		}
+		return NewRedisCodeRepoFromConfig(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported code store driver: %s", cfg.Driver)
+	}
+}
+
+// hashCode 计算验证码的加盐哈希，格式：{salt}${sha256(salt+code)}
+func hashCode(code string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	saltHex := hex.EncodeToString(salt)
+	sum := sha256.Sum256([]byte(saltHex + code))
+	return saltHex + "$" + hex.EncodeToString(sum[:]), nil
+}
+
+// matchCode 校验验证码与加盐哈希是否匹配
+func matchCode(hash, code string) bool {
+	parts := strings.SplitN(hash, "$", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(parts[0] + code))
+	return subtle.ConstantTimeCompare([]byte(parts[1]), []byte(hex.EncodeToString(sum[:]))) == 1
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormCodeRepo 基于数据库表 verification_code 的验证码存储
+// 使用 SQLite 数据库时同样适用
+type GormCodeRepo struct {
+	db *gorm.DB
+}
+
+// NewGormCodeRepo 创建数据库验证码存储
+func NewGormCodeRepo(db *gorm.DB) *GormCodeRepo {
+	return &GormCodeRepo{db: db}
+}
+
+// Save 保存验证码
+// 使用 INSERT ... ON CONFLICT DO UPDATE WHERE 在一条语句内完成限流判断和覆盖，
+// 多个实例并发请求时只有一个能写入成功
+func (s *GormCodeRepo) Save(ctx context.Context, email, action, code string, ttl, throttle time.Duration) error {
+	codeHash, err := hashCode(code)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record := models.VerificationCode{
+		Email:     email,
+		Action:    action,
+		CodeHash:  codeHash,
+		Attempts:  0,
+		SentAt:    now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}, {Name: "action"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "attempts", "sent_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lt{Column: clause.Column{Table: models.VerificationCode{}.TableName(), Name: "sent_at"}, Value: now.Add(-throttle)},
+		}},
+	}).Create(&record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCodeThrottled
+	}
+	return nil
+}
+
+// Verify 校验验证码
+// 在事务中对记录加行锁（SELECT ... FOR UPDATE），保证并发校验时错误次数和消费状态一致
+// SQLite 不支持行锁，由单连接保证事务串行执行
+func (s *GormCodeRepo) Verify(ctx context.Context, email, action, code string, maxAttempts int) (VerifyResult, error) {
+	result := VerifyNotFound
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var record models.VerificationCode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("email = ? AND action = ?", email, action).
+			First(&record).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if time.Now().After(record.ExpiresAt) {
+			return tx.Delete(&record).Error
+		}
+
+		if matchCode(record.CodeHash, code) {
+			result = VerifyOK
+			return tx.Delete(&record).Error
+		}
+
+		if maxAttempts <= 0 {
+			result = VerifyMismatch
+			return nil
+		}
+		if record.Attempts+1 >= maxAttempts {
+			result = VerifyLocked
+			return tx.Delete(&record).Error
+		}
+
+		result = VerifyMismatch
+		return tx.Model(&record).UpdateColumn("attempts", gorm.Expr("attempts + ?", 1)).Error
+	})
+	if err != nil {
+		return VerifyNotFound, err
+	}
+	return result, nil
+}
+
+// Delete 删除验证码
+func (s *GormCodeRepo) Delete(ctx context.Context, email, action string) error {
+	return s.db.WithContext(ctx).
+		Where("email = ? AND action = ?", email, action).
+		Delete(&models.VerificationCode{}).Error
+}
+
+// Cleanup 清理过期验证码
+func (s *GormCodeRepo) Cleanup(ctx context.Context) error {
+	return s.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&models.VerificationCode{}).Error
+}
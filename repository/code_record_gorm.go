@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormCodeRecordRepo 基于数据库表 email_code_record 的验证码审计记录仓储
+type GormCodeRecordRepo struct {
+	db *gorm.DB
+}
+
+// NewGormCodeRecordRepo 创建数据库验证码审计记录仓储
+func NewGormCodeRecordRepo(db *gorm.DB) *GormCodeRecordRepo {
+	return &GormCodeRecordRepo{db: db}
+}
+
+// active 同一邮箱+用途未使用且未作废的记录
+func (r *GormCodeRecordRepo) active(db *gorm.DB, email, action string) *gorm.DB {
+	return db.Model(&models.EmailCodeRecord{}).
+		Where("email = ? AND action = ? AND is_used = ? AND invalidated_at IS NULL", email, action, false)
+}
+
+// Create 创建记录，并作废同一邮箱+用途未使用的旧记录
+func (r *GormCodeRecordRepo) Create(ctx context.Context, record *models.EmailCodeRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.active(tx, record.Email, record.Action).Update("invalidated_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	})
+}
+
+// MarkUsed 将未使用的记录标记为已使用
+func (r *GormCodeRecordRepo) MarkUsed(ctx context.Context, email, action string, at time.Time) error {
+	return r.active(r.db.WithContext(ctx), email, action).Updates(map[string]interface{}{
+		"is_used": true,
+		"used_at": at,
+	}).Error
+}
+
+// Invalidate 作废未使用的记录
+func (r *GormCodeRecordRepo) Invalidate(ctx context.Context, email, action string, at time.Time) error {
+	return r.active(r.db.WithContext(ctx), email, action).Update("invalidated_at", at).Error
+}
+
+// List 分页查询记录
+func (r *GormCodeRecordRepo) List(ctx context.Context, filter CodeRecordFilter) ([]models.EmailCodeRecord, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.EmailCodeRecord{})
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.IsUsed != nil {
+		query = query.Where("is_used = ?", *filter.IsUsed)
+	}
+	if filter.Keyword != "" {
+		query = query.Where("email LIKE ?", "%"+filter.Keyword+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	records := []models.EmailCodeRecord{}
+	err := paginate(query.Order("created_at DESC"), filter.Page, filter.Limit).Find(&records).Error
+	return records, total, err
+}
+
+// Stats 统计记录总数、使用情况、过期数量及各用途数量
+func (r *GormCodeRecordRepo) Stats(ctx context.Context, now time.Time) (*CodeRecordStats, error) {
+	db := r.db.WithContext(ctx)
+	stats := &CodeRecordStats{ByAction: make(map[string]int64)}
+
+	counts := []struct {
+		target *int64
+		query  string
+		args   []interface{}
+	}{
+		{&stats.Total, "1 = 1", nil},
+		{&stats.Used, "is_used = ?", []interface{}{true}},
+		{&stats.Unused, "is_used = ?", []interface{}{false}},
+		{&stats.Expired, "is_used = ? AND expires_at < ?", []interface{}{false, now}},
+	}
+	for _, c := range counts {
+		if err := db.Model(&models.EmailCodeRecord{}).Where(c.query, c.args...).Count(c.target).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var actions []struct {
+		Action string
+		Count  int64
+	}
+	if err := db.Model(&models.EmailCodeRecord{}).Select("action, count(*) as count").Group("action").Scan(&actions).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range actions {
+		stats.ByAction[a.Action] = a.Count
+	}
+	return stats, nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"book-manage/config"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisVerifyRetries 乐观锁冲突时的重试次数
+const redisVerifyRetries = 3
+
+// RedisCodeRepo 基于Redis的验证码存储
+type RedisCodeRepo struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCodeRepo 使用已有的Redis客户端创建验证码存储（测试中可传入miniredis客户端）
+func NewRedisCodeRepo(client redis.UniversalClient) *RedisCodeRepo {
+	return &RedisCodeRepo{client: client}
+}
+
+// NewRedisCodeRepoFromConfig 根据配置连接Redis并创建验证码存储
+func NewRedisCodeRepoFromConfig(cfg *config.CodeStoreConfig) (*RedisCodeRepo, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect redis: %w", err)
+	}
+
+	return NewRedisCodeRepo(client), nil
+}
+
+// Save 保存验证码
+// 使用 WATCH 限流标记 + MULTI 在同一事务中写入限流标记和验证码（hash、attempts），覆盖旧验证码；
+// 限流期内的重复请求返回ErrCodeThrottled，并发请求中只有一个能写入成功
+func (s *RedisCodeRepo) Save(ctx context.Context, email, action, code string, ttl, throttle time.Duration) error {
+	codeHash, err := hashCode(code)
+	if err != nil {
+		return err
+	}
+
+	throttleKey := redisThrottleKey(email, action)
+	key := redisCodeKey(email, action)
+	err = s.client.Watch(ctx, func(tx *redis.Tx) error {
+		n, err := tx.Exists(ctx, throttleKey).Result()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCodeThrottled
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, throttleKey, 1, throttle)
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, "hash", codeHash, "attempts", 0)
+			pipe.Expire(ctx, key, ttl)
+			return nil
+		})
+		return err
+	}, throttleKey)
+
+	// 限流标记在事务执行前被其他请求写入
+	if err == redis.TxFailedErr {
+		return ErrCodeThrottled
+	}
+	return err
+}
+
+// Verify 校验验证码
+// 使用 WATCH + MULTI 乐观锁，保证多实例并发校验时错误次数和消费状态一致
+func (s *RedisCodeRepo) Verify(ctx context.Context, email, action, code string, maxAttempts int) (VerifyResult, error) {
+	key := redisCodeKey(email, action)
+
+	for i := 0; i < redisVerifyRetries; i++ {
+		result := VerifyNotFound
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			values, err := tx.HGetAll(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+			if len(values) == 0 {
+				return nil
+			}
+
+			attempts, _ := strconv.Atoi(values["attempts"])
+			switch {
+			case matchCode(values["hash"], code):
+				result = VerifyOK
+			case maxAttempts <= 0:
+				result = VerifyMismatch
+				return nil
+			case attempts+1 >= maxAttempts:
+				result = VerifyLocked
+			default:
+				result = VerifyMismatch
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				if result == VerifyMismatch {
+					pipe.HIncrBy(ctx, key, "attempts", 1)
+				} else {
+					pipe.Del(ctx, key)
+				}
+				return nil
+			})
+			return err
+		}, key)
+
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return VerifyNotFound, err
+		}
+		return result, nil
+	}
+
+	return VerifyNotFound, fmt.Errorf("verify code conflict, please retry")
+}
+
+// Delete 删除验证码（保留限流标记）
+func (s *RedisCodeRepo) Delete(ctx context.Context, email, action string) error {
+	return s.client.Del(ctx, redisCodeKey(email, action)).Err()
+}
+
+// Cleanup Redis通过键过期自动清理，无需处理
+func (s *RedisCodeRepo) Cleanup(ctx context.Context) error {
+	return nil
+}
+
+// redisCodeKey 验证码键
+func redisCodeKey(email, action string) string {
+	return fmt.Sprintf("email_code:%s:%s", action, email)
+}
+
+// redisThrottleKey 限流标记键
+func redisThrottleKey(email, action string) string {
+	return fmt.Sprintf("email_code_throttle:%s:%s", action, email)
+}
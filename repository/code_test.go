@@ -0,0 +1,188 @@
+package repository_test
+
+import (
+	"book-manage/database"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testTTL      = 10 * time.Minute
+	testThrottle = time.Minute
+)
+
+// codeStore 被测验证码存储，elapse 模拟经过一段时间（限流和验证码均按该时长推进）
+type codeStore struct {
+	repo   repository.CodeRepo
+	elapse func(d time.Duration)
+}
+
+// newGormStore 使用迁移后的内存 SQLite 数据库创建验证码存储
+func newGormStore(t *testing.T) codeStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	return codeStore{
+		repo: repository.NewGormCodeRepo(db),
+		elapse: func(d time.Duration) {
+			// 将发送时间和过期时间提前，等同于时间向后推进
+			var records []models.VerificationCode
+			if err := db.Find(&records).Error; err != nil {
+				t.Fatalf("elapse: %v", err)
+			}
+			for _, r := range records {
+				err := db.Model(&r).UpdateColumns(map[string]interface{}{
+					"sent_at":    r.SentAt.Add(-d),
+					"expires_at": r.ExpiresAt.Add(-d),
+				}).Error
+				if err != nil {
+					t.Fatalf("elapse: %v", err)
+				}
+			}
+		},
+	}
+}
+
+// newRedisStore 使用 miniredis 创建验证码存储
+func newRedisStore(t *testing.T) codeStore {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return codeStore{
+		repo:   repository.NewRedisCodeRepo(client),
+		elapse: mr.FastForward,
+	}
+}
+
+// forEachStore 对数据库和Redis两种存储分别运行测试
+func forEachStore(t *testing.T, fn func(t *testing.T, s codeStore)) {
+	t.Run("gorm", func(t *testing.T) { fn(t, newGormStore(t)) })
+	t.Run("redis", func(t *testing.T) { fn(t, newRedisStore(t)) })
+}
+
+func TestCodeSaveThrottle(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s codeStore) {
+		ctx := context.Background()
+		if err := s.repo.Save(ctx, "a@example.com", "login", "111111", testTTL, testThrottle); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		if err := s.repo.Save(ctx, "a@example.com", "login", "222222", testTTL, testThrottle); !errors.Is(err, repository.ErrCodeThrottled) {
+			t.Fatalf("second save within throttle = %v, want ErrCodeThrottled", err)
+		}
+		// 限流按邮箱+用途区分
+		if err := s.repo.Save(ctx, "a@example.com", "register", "333333", testTTL, testThrottle); err != nil {
+			t.Fatalf("save other action: %v", err)
+		}
+
+		// 限流期过后可重新发送，新验证码覆盖旧验证码
+		s.elapse(testThrottle + time.Second)
+		if err := s.repo.Save(ctx, "a@example.com", "login", "444444", testTTL, testThrottle); err != nil {
+			t.Fatalf("save after throttle: %v", err)
+		}
+		if got, _ := s.repo.Verify(ctx, "a@example.com", "login", "111111", 5); got != repository.VerifyMismatch {
+			t.Fatalf("old code result = %v, want mismatch", got)
+		}
+		if got, _ := s.repo.Verify(ctx, "a@example.com", "login", "444444", 5); got != repository.VerifyOK {
+			t.Fatalf("new code result = %v, want ok", got)
+		}
+	})
+}
+
+func TestCodeVerify(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s codeStore) {
+		ctx := context.Background()
+		if got, err := s.repo.Verify(ctx, "b@example.com", "login", "123456", 5); err != nil || got != repository.VerifyNotFound {
+			t.Fatalf("verify without code = %v, %v", got, err)
+		}
+
+		if err := s.repo.Save(ctx, "b@example.com", "login", "123456", testTTL, testThrottle); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		if got, _ := s.repo.Verify(ctx, "b@example.com", "register", "123456", 5); got != repository.VerifyNotFound {
+			t.Fatalf("verify other action = %v, want not found", got)
+		}
+		if got, _ := s.repo.Verify(ctx, "b@example.com", "login", "000000", 5); got != repository.VerifyMismatch {
+			t.Fatalf("wrong code = %v, want mismatch", got)
+		}
+		if got, _ := s.repo.Verify(ctx, "b@example.com", "login", "123456", 5); got != repository.VerifyOK {
+			t.Fatalf("correct code = %v, want ok", got)
+		}
+		// 验证码只能使用一次
+		if got, _ := s.repo.Verify(ctx, "b@example.com", "login", "123456", 5); got != repository.VerifyNotFound {
+			t.Fatalf("reused code = %v, want not found", got)
+		}
+	})
+}
+
+func TestCodeExpires(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s codeStore) {
+		ctx := context.Background()
+		if err := s.repo.Save(ctx, "c@example.com", "login", "123456", testTTL, testThrottle); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		s.elapse(testTTL + time.Second)
+		if got, _ := s.repo.Verify(ctx, "c@example.com", "login", "123456", 5); got != repository.VerifyNotFound {
+			t.Fatalf("expired code = %v, want not found", got)
+		}
+	})
+}
+
+func TestCodeAttemptLockout(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s codeStore) {
+		ctx := context.Background()
+		if err := s.repo.Save(ctx, "d@example.com", "login", "123456", testTTL, testThrottle); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+
+		want := []repository.VerifyResult{repository.VerifyMismatch, repository.VerifyMismatch, repository.VerifyLocked}
+		for i, w := range want {
+			if got, err := s.repo.Verify(ctx, "d@example.com", "login", "000000", 3); err != nil || got != w {
+				t.Fatalf("attempt %d = %v, %v, want %v", i+1, got, err, w)
+			}
+		}
+		// 锁定后验证码作废，正确的验证码也无法通过
+		if got, _ := s.repo.Verify(ctx, "d@example.com", "login", "123456", 3); got != repository.VerifyNotFound {
+			t.Fatalf("verify after lockout = %v, want not found", got)
+		}
+	})
+}
+
+func TestCodeUnlimitedAttempts(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s codeStore) {
+		ctx := context.Background()
+		if err := s.repo.Save(ctx, "e@example.com", "set_password", "123456", testTTL, testThrottle); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+
+		// maxAttempts 不大于0时错误次数不受限制，验证码不会作废
+		for i := 0; i < 10; i++ {
+			if got, err := s.repo.Verify(ctx, "e@example.com", "set_password", "000000", 0); err != nil || got != repository.VerifyMismatch {
+				t.Fatalf("attempt %d = %v, %v, want mismatch", i+1, got, err)
+			}
+		}
+		if got, _ := s.repo.Verify(ctx, "e@example.com", "set_password", "123456", 0); got != repository.VerifyOK {
+			t.Fatalf("correct code after mismatches = %v, want ok", got)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormInvitationRepo 基于数据库表 invitation 的注册邀请仓储
+type GormInvitationRepo struct {
+	db *gorm.DB
+}
+
+// NewGormInvitationRepo 创建数据库注册邀请仓储
+func NewGormInvitationRepo(db *gorm.DB) *GormInvitationRepo {
+	return &GormInvitationRepo{db: db}
+}
+
+// Create 创建邀请
+func (r *GormInvitationRepo) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+// FindByTokenHash 按邀请码哈希查找邀请
+func (r *GormInvitationRepo) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &invitation, nil
+}
+
+// List 分页查询邀请，过期状态以 now 为准
+func (r *GormInvitationRepo) List(ctx context.Context, filter InvitationFilter, now time.Time) ([]models.Invitation, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Invitation{})
+
+	switch filter.Status {
+	case "pending":
+		query = query.Where("used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case "used":
+		query = query.Where("used_at IS NOT NULL")
+	case "revoked":
+		query = query.Where("revoked_at IS NOT NULL")
+	case "expired":
+		query = query.Where("used_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+	if filter.Email != "" {
+		query = query.Where("email LIKE ?", "%"+filter.Email+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	invitations := []models.Invitation{}
+	err := paginate(query.Order("created_at DESC"), filter.Page, filter.Limit).Find(&invitations).Error
+	return invitations, total, err
+}
+
+// Revoke 吊销未使用的邀请
+func (r *GormInvitationRepo) Revoke(ctx context.Context, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
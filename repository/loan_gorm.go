@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormLoanRepo 基于数据库表 borrow_record 的借阅记录仓储
+type GormLoanRepo struct {
+	db *gorm.DB
+}
+
+// NewGormLoanRepo 创建数据库借阅记录仓储
+func NewGormLoanRepo(db *gorm.DB) *GormLoanRepo {
+	return &GormLoanRepo{db: db}
+}
+
+// FindOpen 查找用户对某本图书未归还的借阅记录
+func (r *GormLoanRepo) FindOpen(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error) {
+	var record models.BorrowRecord
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND book_id = ? AND status = ?", userID, bookID, models.BorrowStatusBorrowed).
+		First(&record).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+// CountOpenByUser 统计用户未归还的借阅数量
+func (r *GormLoanRepo) CountOpenByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("user_id = ? AND status = ?", userID, models.BorrowStatusBorrowed).
+		Count(&count).Error
+	return count, err
+}
+
+// CountOpenByBook 统计图书未归还的借阅数量
+func (r *GormLoanRepo) CountOpenByBook(ctx context.Context, bookID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("book_id = ? AND status = ?", bookID, models.BorrowStatusBorrowed).
+		Count(&count).Error
+	return count, err
+}
+
+// CountOverdue 统计到期日早于 now 且未归还的借阅数量
+func (r *GormLoanRepo) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BorrowRecord{}).
+		Where("status = ? AND due_date < ?", models.BorrowStatusBorrowed, now).
+		Count(&count).Error
+	return count, err
+}
+
+// Create 创建借阅记录并扣减图书可借数量
+// 扣减时带上 available_quantity > 0 条件，并发借出最后一本时只有一个请求成功
+func (r *GormLoanRepo) Create(ctx context.Context, record *models.BorrowRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Book{}).
+			Where("id = ? AND available_quantity > 0", record.BookID).
+			Update("available_quantity", gorm.Expr("available_quantity - ?", 1))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOutOfStock
+		}
+		return tx.Create(record).Error
+	})
+}
+
+// MarkReturned 将借阅记录标记为已归还并增加图书可借数量
+func (r *GormLoanRepo) MarkReturned(ctx context.Context, record *models.BorrowRecord, returnDate time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.BorrowRecord{}).
+			Where("id = ? AND status = ?", record.ID, models.BorrowStatusBorrowed).
+			Updates(map[string]interface{}{
+				"status":      models.BorrowStatusReturned,
+				"return_date": returnDate,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&models.Book{}).
+			Where("id = ?", record.BookID).
+			Update("available_quantity", gorm.Expr("available_quantity + ?", 1)).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	record.Status = models.BorrowStatusReturned
+	record.ReturnDate = &returnDate
+	return nil
+}
+
+// ListByUser 分页查询用户的借阅记录
+func (r *GormLoanRepo) ListByUser(ctx context.Context, userID uint, filter LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	query := r.db.WithContext(ctx).Table("borrow_record").
+		Select("borrow_record.id, borrow_record.book_id, book.title as book_title, borrow_record.borrow_date, borrow_record.due_date, borrow_record.return_date, borrow_record.status").
+		Joins("LEFT JOIN book ON borrow_record.book_id = book.id").
+		Where("borrow_record.user_id = ?", userID)
+
+	// 状态筛选
+	if filter.Status != "" {
+		query = query.Where("borrow_record.status = ?", filter.Status)
+	}
+
+	return r.page(query, filter)
+}
+
+// List 分页查询全部借阅记录
+func (r *GormLoanRepo) List(ctx context.Context, filter LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	// 注意：user是保留字，表名由GORM按数据库方言加引号，查询中使用别名u
+	query := r.db.WithContext(ctx).Table("borrow_record").
+		Select("borrow_record.id, borrow_record.user_id, u.email as user_email, u.name as user_name, COALESCE(u.card_number, '') as card_number, borrow_record.book_id, book.title as book_title, borrow_record.borrow_date, borrow_record.due_date, borrow_record.return_date, borrow_record.status").
+		Joins("LEFT JOIN ? u ON borrow_record.user_id = u.id", clause.Table{Name: models.User{}.TableName()}).
+		Joins("LEFT JOIN book ON borrow_record.book_id = book.id")
+
+	// 用户邮箱筛选
+	if filter.UserEmail != "" {
+		query = query.Where("u.email LIKE ?", "%"+filter.UserEmail+"%")
+	}
+
+	// 借书卡号筛选
+	if filter.CardNumber != "" {
+		query = query.Where("u.card_number = ?", filter.CardNumber)
+	}
+
+	// 图书名称筛选
+	if filter.BookTitle != "" {
+		query = query.Where("book.title LIKE ?", "%"+filter.BookTitle+"%")
+	}
+
+	// 状态筛选
+	if filter.Status != "" {
+		query = query.Where("borrow_record.status = ?", filter.Status)
+	}
+
+	return r.page(query, filter)
+}
+
+// page 统计总数并查询当前页，按借阅时间倒序
+func (r *GormLoanRepo) page(query *gorm.DB, filter LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []models.BorrowRecordWithDetails
+	if err := paginate(query, filter.Page, filter.Limit).Order("borrow_record.borrow_date DESC").Scan(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+// OldestOverdue 查找用户到期日早于 before 的未归还借阅中到期最早的一条
+func (r *GormLoanRepo) OldestOverdue(ctx context.Context, userID uint, before time.Time) (*models.BorrowRecordWithDetails, error) {
+	var record models.BorrowRecordWithDetails
+	result := r.db.WithContext(ctx).Table("borrow_record").
+		Select("borrow_record.id, borrow_record.book_id, book.title as book_title, borrow_record.borrow_date, borrow_record.due_date, borrow_record.status").
+		Joins("LEFT JOIN book ON borrow_record.book_id = book.id").
+		Where("borrow_record.user_id = ? AND borrow_record.status = ? AND borrow_record.due_date < ?", userID, models.BorrowStatusBorrowed, before).
+		Order("borrow_record.due_date").
+		Limit(1).
+		Scan(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
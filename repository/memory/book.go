@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BookRepo 图书仓储的内存实现
+type BookRepo struct {
+	store *Store
+}
+
+// FindByID 按ID查找图书
+func (r *BookRepo) FindByID(ctx context.Context, id uint) (*models.Book, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	book, ok := r.store.books[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &book, nil
+}
+
+// FindByISBN 按ISBN查找图书
+func (r *BookRepo) FindByISBN(ctx context.Context, isbn string) (*models.Book, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, book := range r.store.books {
+		if book.ISBN == isbn {
+			return &book, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Search 分页查询图书
+func (r *BookRepo) Search(ctx context.Context, filter repository.BookFilter) ([]models.Book, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var books []models.Book
+	for _, book := range r.store.books {
+		if filter.Keyword != "" && !strings.Contains(book.Title, filter.Keyword) && !strings.Contains(book.Author, filter.Keyword) {
+			continue
+		}
+		if filter.Category != "" && book.Category != filter.Category {
+			continue
+		}
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].CreateTime.Equal(books[j].CreateTime) {
+			return books[i].ID > books[j].ID
+		}
+		return books[i].CreateTime.After(books[j].CreateTime)
+	})
+
+	start, end := page(len(books), filter.Page, filter.Limit)
+	return books[start:end], int64(len(books)), nil
+}
+
+// Create 创建图书，ISBN重复时返回错误
+func (r *BookRepo) Create(ctx context.Context, book *models.Book) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.books {
+		if existing.ISBN == book.ISBN {
+			return fmt.Errorf("duplicate isbn: %s", book.ISBN)
+		}
+	}
+	book.ID = r.store.newID()
+	r.store.books[book.ID] = *book
+	return nil
+}
+
+// Save 保存图书
+func (r *BookRepo) Save(ctx context.Context, book *models.Book) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if book.ID == 0 {
+		book.ID = r.store.newID()
+	}
+	r.store.books[book.ID] = *book
+	return nil
+}
+
+// Delete 删除图书
+func (r *BookRepo) Delete(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.books, id)
+	return nil
+}
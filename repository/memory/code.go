@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"book-manage/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// CodeRepo 验证码存储的内存实现
+// 仅用于测试：验证码以明文保存，行为（限流、过期、错误次数）与数据库和Redis实现一致
+type CodeRepo struct {
+	mu    sync.Mutex
+	codes map[string]*codeEntry
+}
+
+type codeEntry struct {
+	code      string
+	attempts  int
+	sentAt    time.Time
+	expiresAt time.Time
+}
+
+// NewCodeRepo 创建内存验证码存储
+func NewCodeRepo() *CodeRepo {
+	return &CodeRepo{codes: make(map[string]*codeEntry)}
+}
+
+// Save 保存验证码
+func (r *CodeRepo) Save(ctx context.Context, email, action, code string, ttl, throttle time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	key := codeKey(email, action)
+	if entry, ok := r.codes[key]; ok && now.Sub(entry.sentAt) < throttle {
+		return repository.ErrCodeThrottled
+	}
+	r.codes[key] = &codeEntry{code: code, sentAt: now, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+// Verify 校验验证码
+func (r *CodeRepo) Verify(ctx context.Context, email, action, code string, maxAttempts int) (repository.VerifyResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := codeKey(email, action)
+	entry, ok := r.codes[key]
+	if !ok {
+		return repository.VerifyNotFound, nil
+	}
+
+	switch {
+	case time.Now().After(entry.expiresAt):
+		delete(r.codes, key)
+		return repository.VerifyNotFound, nil
+	case entry.code == code:
+		delete(r.codes, key)
+		return repository.VerifyOK, nil
+	case maxAttempts <= 0:
+		return repository.VerifyMismatch, nil
+	case entry.attempts+1 >= maxAttempts:
+		delete(r.codes, key)
+		return repository.VerifyLocked, nil
+	default:
+		entry.attempts++
+		return repository.VerifyMismatch, nil
+	}
+}
+
+// Delete 删除验证码
+func (r *CodeRepo) Delete(ctx context.Context, email, action string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.codes, codeKey(email, action))
+	return nil
+}
+
+// Cleanup 清理过期验证码
+func (r *CodeRepo) Cleanup(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range r.codes {
+		if now.After(entry.expiresAt) {
+			delete(r.codes, key)
+		}
+	}
+	return nil
+}
+
+// codeKey 验证码键
+func codeKey(email, action string) string {
+	return action + ":" + email
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CodeRecordRepo 验证码审计记录的内存实现
+type CodeRecordRepo struct {
+	mu      sync.Mutex
+	records []models.EmailCodeRecord // 按创建顺序
+}
+
+// NewCodeRecordRepo 创建内存验证码审计记录仓储
+func NewCodeRecordRepo() *CodeRecordRepo {
+	return &CodeRecordRepo{}
+}
+
+// Create 创建记录，并作废同一邮箱+用途未使用的旧记录
+func (r *CodeRecordRepo) Create(ctx context.Context, record *models.EmailCodeRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.update(record.Email, record.Action, func(old *models.EmailCodeRecord) { old.InvalidatedAt = &now })
+	record.ID = uint(len(r.records) + 1)
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	r.records = append(r.records, *record)
+	return nil
+}
+
+// MarkUsed 将未使用的记录标记为已使用
+func (r *CodeRecordRepo) MarkUsed(ctx context.Context, email, action string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.update(email, action, func(record *models.EmailCodeRecord) {
+		record.IsUsed = true
+		record.UsedAt = &at
+	})
+	return nil
+}
+
+// Invalidate 作废未使用的记录
+func (r *CodeRecordRepo) Invalidate(ctx context.Context, email, action string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.update(email, action, func(record *models.EmailCodeRecord) { record.InvalidatedAt = &at })
+	return nil
+}
+
+// update 修改同一邮箱+用途未使用且未作废的记录（调用方需持有锁）
+func (r *CodeRecordRepo) update(email, action string, fn func(record *models.EmailCodeRecord)) {
+	for i := range r.records {
+		record := &r.records[i]
+		if record.Email == email && record.Action == action && !record.IsUsed && record.InvalidatedAt == nil {
+			fn(record)
+		}
+	}
+}
+
+// List 分页查询记录
+func (r *CodeRecordRepo) List(ctx context.Context, filter repository.CodeRecordFilter) ([]models.EmailCodeRecord, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records := []models.EmailCodeRecord{}
+	for _, record := range r.records {
+		if filter.Email != "" && record.Email != filter.Email {
+			continue
+		}
+		if filter.Action != "" && record.Action != filter.Action {
+			continue
+		}
+		if filter.IsUsed != nil && record.IsUsed != *filter.IsUsed {
+			continue
+		}
+		if filter.Keyword != "" && !strings.Contains(record.Email, filter.Keyword) {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID > records[j].ID })
+
+	start, end := page(len(records), filter.Page, filter.Limit)
+	return records[start:end], int64(len(records)), nil
+}
+
+// Stats 统计记录总数、使用情况、过期数量及各用途数量
+func (r *CodeRecordRepo) Stats(ctx context.Context, now time.Time) (*repository.CodeRecordStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := &repository.CodeRecordStats{ByAction: make(map[string]int64)}
+	for _, record := range r.records {
+		stats.Total++
+		if record.IsUsed {
+			stats.Used++
+		} else {
+			stats.Unused++
+			if record.ExpiresAt.Before(now) {
+				stats.Expired++
+			}
+		}
+		stats.ByAction[record.Action]++
+	}
+	return stats, nil
+}
@@ -0,0 +1,220 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoanRepo 借阅记录仓储的内存实现
+type LoanRepo struct {
+	store *Store
+}
+
+// FindOpen 查找用户对某本图书未归还的借阅记录
+func (r *LoanRepo) FindOpen(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, record := range r.store.loans {
+		if record.UserID == userID && record.BookID == bookID && record.Status == models.BorrowStatusBorrowed {
+			return &record, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// CountOpenByUser 统计用户未归还的借阅数量
+func (r *LoanRepo) CountOpenByUser(ctx context.Context, userID uint) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, record := range r.store.loans {
+		if record.UserID == userID && record.Status == models.BorrowStatusBorrowed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountOpenByBook 统计图书未归还的借阅数量
+func (r *LoanRepo) CountOpenByBook(ctx context.Context, bookID uint) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, record := range r.store.loans {
+		if record.BookID == bookID && record.Status == models.BorrowStatusBorrowed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountOverdue 统计到期日早于 now 且未归还的借阅数量
+func (r *LoanRepo) CountOverdue(ctx context.Context, now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, record := range r.store.loans {
+		if record.Status == models.BorrowStatusBorrowed && record.DueDate.Before(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Create 创建借阅记录并扣减图书可借数量
+func (r *LoanRepo) Create(ctx context.Context, record *models.BorrowRecord) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	book, ok := r.store.books[record.BookID]
+	if !ok || book.AvailableQuantity <= 0 {
+		return repository.ErrOutOfStock
+	}
+	book.AvailableQuantity--
+	r.store.books[book.ID] = book
+
+	if record.Status == "" {
+		record.Status = models.BorrowStatusBorrowed
+	}
+	record.ID = r.store.newID()
+	r.store.loans[record.ID] = *record
+	return nil
+}
+
+// MarkReturned 将借阅记录标记为已归还并增加图书可借数量
+func (r *LoanRepo) MarkReturned(ctx context.Context, record *models.BorrowRecord, returnDate time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.loans[record.ID]
+	if !ok || stored.Status != models.BorrowStatusBorrowed {
+		return repository.ErrNotFound
+	}
+	stored.Status = models.BorrowStatusReturned
+	stored.ReturnDate = &returnDate
+	r.store.loans[record.ID] = stored
+
+	if book, ok := r.store.books[stored.BookID]; ok {
+		book.AvailableQuantity++
+		r.store.books[book.ID] = book
+	}
+
+	*record = stored
+	return nil
+}
+
+// ListByUser 分页查询用户的借阅记录
+func (r *LoanRepo) ListByUser(ctx context.Context, userID uint, filter repository.LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var records []models.BorrowRecordWithDetails
+	for _, record := range r.store.loans {
+		if record.UserID != userID || (filter.Status != "" && record.Status != filter.Status) {
+			continue
+		}
+		records = append(records, models.BorrowRecordWithDetails{
+			ID:         record.ID,
+			BookID:     record.BookID,
+			BookTitle:  r.store.books[record.BookID].Title,
+			BorrowDate: record.BorrowDate,
+			DueDate:    record.DueDate,
+			ReturnDate: record.ReturnDate,
+			Status:     record.Status,
+		})
+	}
+	return pageLoans(records, filter)
+}
+
+// OldestOverdue 查找用户到期日早于 before 的未归还借阅中到期最早的一条
+func (r *LoanRepo) OldestOverdue(ctx context.Context, userID uint, before time.Time) (*models.BorrowRecordWithDetails, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var oldest *models.BorrowRecord
+	for _, record := range r.store.loans {
+		if record.UserID != userID || record.Status != models.BorrowStatusBorrowed || !record.DueDate.Before(before) {
+			continue
+		}
+		if oldest == nil || record.DueDate.Before(oldest.DueDate) {
+			found := record
+			oldest = &found
+		}
+	}
+	if oldest == nil {
+		return nil, repository.ErrNotFound
+	}
+	return &models.BorrowRecordWithDetails{
+		ID:         oldest.ID,
+		BookID:     oldest.BookID,
+		BookTitle:  r.store.books[oldest.BookID].Title,
+		BorrowDate: oldest.BorrowDate,
+		DueDate:    oldest.DueDate,
+		Status:     oldest.Status,
+	}, nil
+}
+
+// List 分页查询全部借阅记录
+func (r *LoanRepo) List(ctx context.Context, filter repository.LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var records []models.BorrowRecordWithDetails
+	for _, record := range r.store.loans {
+		user := r.store.users[record.UserID]
+		book := r.store.books[record.BookID]
+
+		cardNumber := ""
+		if user.CardNumber != nil {
+			cardNumber = *user.CardNumber
+		}
+		if filter.UserEmail != "" && !strings.Contains(user.Email, filter.UserEmail) {
+			continue
+		}
+		if filter.CardNumber != "" && cardNumber != filter.CardNumber {
+			continue
+		}
+		if filter.BookTitle != "" && !strings.Contains(book.Title, filter.BookTitle) {
+			continue
+		}
+		if filter.Status != "" && record.Status != filter.Status {
+			continue
+		}
+
+		records = append(records, models.BorrowRecordWithDetails{
+			ID:         record.ID,
+			UserID:     record.UserID,
+			UserEmail:  user.Email,
+			UserName:   user.Name,
+			CardNumber: cardNumber,
+			BookID:     record.BookID,
+			BookTitle:  book.Title,
+			BorrowDate: record.BorrowDate,
+			DueDate:    record.DueDate,
+			ReturnDate: record.ReturnDate,
+			Status:     record.Status,
+		})
+	}
+	return pageLoans(records, filter)
+}
+
+// pageLoans 按借阅时间倒序排序并分页
+func pageLoans(records []models.BorrowRecordWithDetails, filter repository.LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].BorrowDate.Equal(records[j].BorrowDate) {
+			return records[i].ID > records[j].ID
+		}
+		return records[i].BorrowDate.After(records[j].BorrowDate)
+	})
+
+	start, end := page(len(records), filter.Page, filter.Limit)
+	return records[start:end], int64(len(records)), nil
+}
@@ -0,0 +1,73 @@
+// Package memory 仓储接口的内存实现，用于单元测试（不依赖数据库）
+// 同一个 Store 中的图书、用户和借阅记录共享数据，借书、还书时同步更新图书库存
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"sync"
+)
+
+// Store 内存数据存储
+type Store struct {
+	Books *BookRepo
+	Users *UserRepo
+	Loans *LoanRepo
+
+	mu              sync.Mutex
+	books           map[uint]models.Book
+	users           map[uint]models.User
+	loans           map[uint]models.BorrowRecord
+	passwords       map[uint][]string // 用户ID -> 历史密码哈希（按时间倒序）
+	usedInvitations map[uint]bool     // 已使用的邀请ID
+	nextID          uint
+}
+
+// NewStore 创建空的内存数据存储
+func NewStore() *Store {
+	s := &Store{
+		books: make(map[uint]models.Book),
+		users: make(map[uint]models.User),
+		loans: make(map[uint]models.BorrowRecord),
+
+		passwords:       make(map[uint][]string),
+		usedInvitations: make(map[uint]bool),
+	}
+	s.Books = &BookRepo{store: s}
+	s.Users = &UserRepo{store: s}
+	s.Loans = &LoanRepo{store: s}
+	return s
+}
+
+// newID 生成自增ID（调用方需持有锁）
+func (s *Store) newID() uint {
+	s.nextID++
+	return s.nextID
+}
+
+// page 计算分页区间
+func page(total, pageNum, limit int) (int, int) {
+	start := 0
+	if pageNum > 0 {
+		start = (pageNum - 1) * limit
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if limit > 0 && start+limit < total {
+		end = start + limit
+	}
+	return start, end
+}
+
+var (
+	_ repository.BookRepo = (*BookRepo)(nil)
+	_ repository.UserRepo = (*UserRepo)(nil)
+	_ repository.LoanRepo = (*LoanRepo)(nil)
+	_ repository.CodeRepo = (*CodeRepo)(nil)
+
+	_ repository.OutboxRepo     = (*OutboxRepo)(nil)
+	_ repository.TemplateRepo   = (*TemplateRepo)(nil)
+	_ repository.CodeRecordRepo = (*CodeRecordRepo)(nil)
+)
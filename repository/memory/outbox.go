@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutboxRepo 邮件发件箱的内存实现
+type OutboxRepo struct {
+	mu     sync.Mutex
+	items  map[uint]models.EmailOutbox
+	nextID uint
+}
+
+// NewOutboxRepo 创建内存邮件发件箱
+func NewOutboxRepo() *OutboxRepo {
+	return &OutboxRepo{items: make(map[uint]models.EmailOutbox)}
+}
+
+// Create 写入待发送邮件
+func (r *OutboxRepo) Create(ctx context.Context, item *models.EmailOutbox) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	item.ID = r.nextID
+	if item.Status == "" {
+		item.Status = models.OutboxStatusPending
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+	r.items[item.ID] = *item
+	return nil
+}
+
+// List 分页查询发件箱
+func (r *OutboxRepo) List(ctx context.Context, filter repository.OutboxFilter) ([]models.EmailOutbox, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := []models.EmailOutbox{}
+	for _, item := range r.items {
+		if filter.Status != "" && item.Status != filter.Status {
+			continue
+		}
+		if filter.Email != "" && !strings.Contains(item.ToEmail, filter.Email) {
+			continue
+		}
+		if filter.Category != "" && item.Category != filter.Category {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+
+	start, end := page(len(items), filter.Page, filter.Limit)
+	return items[start:end], int64(len(items)), nil
+}
+
+// StatusCounts 统计各状态的邮件数量
+func (r *OutboxRepo) StatusCounts(ctx context.Context) ([]repository.OutboxStatusCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, item := range r.items {
+		counts[item.Status]++
+	}
+	result := []repository.OutboxStatusCount{}
+	for status, count := range counts {
+		result = append(result, repository.OutboxStatusCount{Status: status, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Status < result[j].Status })
+	return result, nil
+}
+
+// Retry 将正文未被清除的失败邮件重新置为待发送
+func (r *OutboxRepo) Retry(ctx context.Context, id uint, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[id]
+	if !ok || item.Status != models.OutboxStatusFailed || (item.HTMLBody == "" && item.TextBody == "") {
+		return repository.ErrNotFound
+	}
+	item.Status = models.OutboxStatusPending
+	item.Attempts = 0
+	item.NextAttemptAt = now
+	item.LockedAt = nil
+	r.items[id] = item
+	return nil
+}
+
+// Claim 领取到期的邮件并标记为发送中
+func (r *OutboxRepo) Claim(ctx context.Context, now, staleBefore time.Time, limit int) ([]models.EmailOutbox, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := []models.EmailOutbox{}
+	for _, item := range r.items {
+		due := item.Status == models.OutboxStatusPending && !item.NextAttemptAt.After(now)
+		stale := item.Status == models.OutboxStatusSending && item.LockedAt != nil && item.LockedAt.Before(staleBefore)
+		if due || stale {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	for i := range items {
+		lockedAt := now
+		stored := r.items[items[i].ID]
+		stored.Status = models.OutboxStatusSending
+		stored.LockedAt = &lockedAt
+		r.items[stored.ID] = stored
+	}
+	return items, nil
+}
+
+// Update 按列名更新邮件
+func (r *OutboxRepo) Update(ctx context.Context, item *models.EmailOutbox, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.items[item.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for column, value := range updates {
+		if err := setOutboxColumn(&stored, column, value); err != nil {
+			return err
+		}
+	}
+	r.items[item.ID] = stored
+	*item = stored
+	return nil
+}
+
+// PurgeBodies 清除已结束邮件的正文
+func (r *OutboxRepo) PurgeBodies(ctx context.Context, failedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, item := range r.items {
+		if item.HTMLBody == "" && item.TextBody == "" {
+			continue
+		}
+		if item.Status == models.OutboxStatusSent ||
+			(item.Status == models.OutboxStatusFailed && item.CreatedAt.Before(failedBefore)) {
+			item.HTMLBody, item.TextBody = "", ""
+			r.items[id] = item
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// setOutboxColumn 按列名设置邮件字段
+func setOutboxColumn(item *models.EmailOutbox, column string, value interface{}) error {
+	switch column {
+	case "status":
+		item.Status = value.(string)
+	case "attempts":
+		item.Attempts = value.(int)
+	case "next_attempt_at":
+		item.NextAttemptAt = value.(time.Time)
+	case "locked_at":
+		item.LockedAt = timePtr(value)
+	case "last_error":
+		item.LastError = value.(string)
+	case "provider":
+		item.Provider = value.(string)
+	case "provider_message_id":
+		item.ProviderMessageID = value.(string)
+	case "sent_at":
+		item.SentAt = timePtr(value)
+	case "html_body":
+		item.HTMLBody = value.(string)
+	case "text_body":
+		item.TextBody = value.(string)
+	default:
+		return fmt.Errorf("unsupported outbox column: %s", column)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TemplateRepo 自定义邮件模板的内存实现
+type TemplateRepo struct {
+	mu        sync.Mutex
+	templates map[string]models.EmailTemplate // 模板标识+语言 -> 模板
+	nextID    uint
+}
+
+// NewTemplateRepo 创建内存自定义邮件模板仓储
+func NewTemplateRepo() *TemplateRepo {
+	return &TemplateRepo{templates: make(map[string]models.EmailTemplate)}
+}
+
+// List 获取全部自定义模板
+func (r *TemplateRepo) List(ctx context.Context) ([]models.EmailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	templates := []models.EmailTemplate{}
+	for _, tpl := range r.templates {
+		templates = append(templates, tpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates, nil
+}
+
+// Find 按模板标识和语言查找自定义模板
+func (r *TemplateRepo) Find(ctx context.Context, key, locale string) (*models.EmailTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tpl, ok := r.templates[templateKey(key, locale)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &tpl, nil
+}
+
+// Save 保存自定义模板，同一模板标识和语言已存在时覆盖
+func (r *TemplateRepo) Save(ctx context.Context, tpl *models.EmailTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := templateKey(tpl.Key, tpl.Locale)
+	if existing, ok := r.templates[k]; ok {
+		tpl.ID = existing.ID
+	} else {
+		r.nextID++
+		tpl.ID = r.nextID
+	}
+	tpl.UpdatedAt = time.Now()
+	r.templates[k] = *tpl
+	return nil
+}
+
+// Delete 删除自定义模板
+func (r *TemplateRepo) Delete(ctx context.Context, key, locale string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.templates, templateKey(key, locale))
+	return nil
+}
+
+// templateKey 模板键
+func templateKey(key, locale string) string {
+	return key + ":" + locale
+}
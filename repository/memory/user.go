@@ -0,0 +1,377 @@
+package memory
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UserRepo 用户仓储的内存实现
+type UserRepo struct {
+	store *Store
+}
+
+// FindByID 按ID查找用户
+func (r *UserRepo) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindByEmail 按邮箱查找用户
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Create 创建用户，邮箱重复时返回错误；未设置的角色和状态使用与数据库相同的默认值
+func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.create(user)
+}
+
+// CreateWithInvitation 创建用户并记录邀请已使用（内存实现不校验邀请是否存在或已吊销）
+func (r *UserRepo) CreateWithInvitation(ctx context.Context, user *models.User, invitationID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.usedInvitations[invitationID] {
+		return repository.ErrInvitationUsed
+	}
+	if err := r.create(user); err != nil {
+		return err
+	}
+	r.store.usedInvitations[invitationID] = true
+	return nil
+}
+
+// create 创建用户（调用方需持有锁）
+func (r *UserRepo) create(user *models.User) error {
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return fmt.Errorf("duplicate email: %s", user.Email)
+		}
+	}
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.Status == "" {
+		user.Status = models.UserStatusNormal
+	}
+	if user.RegisterTime.IsZero() {
+		user.RegisterTime = time.Now()
+	}
+	user.ID = r.store.newID()
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+// Update 按列名更新用户字段并重新读取
+// 只支持服务层用到的列，其他列返回错误
+func (r *UserRepo) Update(ctx context.Context, user *models.User, updates map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for column, value := range updates {
+		if err := setUserColumn(&stored, column, value); err != nil {
+			return err
+		}
+	}
+	r.store.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// ChangeEmail 修改用户邮箱（内存实现不记录token吊销）
+func (r *UserRepo) ChangeEmail(ctx context.Context, user *models.User, newEmail string, revokedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for id, other := range r.store.users {
+		if id != user.ID && other.Email == newEmail {
+			return repository.ErrDuplicateKey
+		}
+	}
+	stored.Email = newEmail
+	r.store.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// UpdatePassword 更新密码哈希，并将旧哈希写入历史记录
+func (r *UserRepo) UpdatePassword(ctx context.Context, user *models.User, hash string, historySize int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if historySize > 0 && stored.Password != "" {
+		history := append([]string{stored.Password}, r.store.passwords[user.ID]...)
+		if len(history) > historySize {
+			history = history[:historySize]
+		}
+		r.store.passwords[user.ID] = history
+	}
+	stored.Password = hash
+	r.store.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// PasswordHistory 获取用户最近使用过的密码哈希
+func (r *UserRepo) PasswordHistory(ctx context.Context, userID uint, limit int) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	history := r.store.passwords[userID]
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return append([]string{}, history...), nil
+}
+
+// Search 分页查询未注销的用户，按ID倒序
+func (r *UserRepo) Search(ctx context.Context, filter repository.UserFilter) ([]models.User, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	keyword := strings.TrimSpace(filter.Keyword)
+	users := []models.User{}
+	for _, user := range r.store.users {
+		if user.AnonymizedAt != nil {
+			continue
+		}
+		if keyword != "" && !strings.Contains(user.Email, keyword) && !strings.Contains(user.Name, keyword) && !strings.Contains(user.Phone, keyword) {
+			continue
+		}
+		if filter.CardNumber != "" && (user.CardNumber == nil || *user.CardNumber != filter.CardNumber) {
+			continue
+		}
+		if filter.StudentID != "" && (user.StudentID == nil || *user.StudentID != filter.StudentID) {
+			continue
+		}
+		if filter.Group != "" && user.Group != filter.Group {
+			continue
+		}
+		if filter.Status != "" && user.Status != filter.Status {
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID > users[j].ID })
+
+	start, end := page(len(users), filter.Page, filter.Limit)
+	return users[start:end], int64(len(users)), nil
+}
+
+// CountBy 统计指定列等于 value 的用户数量
+func (r *UserRepo) CountBy(ctx context.Context, column, value string, excludeID uint) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var count int64
+	for _, user := range r.store.users {
+		if excludeID != 0 && user.ID == excludeID {
+			continue
+		}
+		current, ok, err := userColumn(&user, column)
+		if err != nil {
+			return 0, err
+		}
+		if ok && current == value {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ExistingValues 返回 values 中已被用户使用的值
+func (r *UserRepo) ExistingValues(ctx context.Context, column string, values []string) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	wanted := make(map[string]bool, len(values))
+	for _, value := range values {
+		wanted[value] = true
+	}
+	existing := []string{}
+	for _, user := range r.store.users {
+		current, ok, err := userColumn(&user, column)
+		if err != nil {
+			return nil, err
+		}
+		if ok && wanted[current] {
+			existing = append(existing, current)
+			delete(wanted, current)
+		}
+	}
+	return existing, nil
+}
+
+// userColumn 按列名读取用户字段，字段为空（NULL）时 ok 为 false
+func userColumn(user *models.User, column string) (value string, ok bool, err error) {
+	switch column {
+	case "email":
+		return user.Email, true, nil
+	case "role":
+		return user.Role, true, nil
+	case "student_id":
+		if user.StudentID == nil {
+			return "", false, nil
+		}
+		return *user.StudentID, true, nil
+	case "card_number":
+		if user.CardNumber == nil {
+			return "", false, nil
+		}
+		return *user.CardNumber, true, nil
+	default:
+		return "", false, fmt.Errorf("unsupported user column: %s", column)
+	}
+}
+
+// ListSuspensionCandidates 按ID顺序获取可能需要暂停或解除暂停的用户
+func (r *UserRepo) ListSuspensionCandidates(ctx context.Context, afterID uint, now time.Time, overdueBefore *time.Time, limit int) ([]models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	overdue := make(map[uint]bool)
+	if overdueBefore != nil {
+		for _, record := range r.store.loans {
+			if record.Status == models.BorrowStatusBorrowed && record.DueDate.Before(*overdueBefore) {
+				overdue[record.UserID] = true
+			}
+		}
+	}
+
+	users := []models.User{}
+	for _, user := range r.store.users {
+		if user.ID <= afterID || user.AnonymizedAt != nil || user.Status == models.UserStatusDisabled {
+			continue
+		}
+		expired := user.MembershipExpiresAt != nil && user.MembershipExpiresAt.Before(now)
+		if user.Status == models.UserStatusSuspended || expired || overdue[user.ID] {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Anonymize 注销用户：匿名化用户行并清除历史密码（内存实现不保存API密钥、验证码记录和发件箱）
+func (r *UserRepo) Anonymize(ctx context.Context, user *models.User, updates map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	for _, record := range r.store.loans {
+		if record.UserID == user.ID && record.Status == models.BorrowStatusBorrowed {
+			return repository.ErrOpenLoans
+		}
+	}
+	for column, value := range updates {
+		if err := setUserColumn(&stored, column, value); err != nil {
+			return err
+		}
+	}
+	delete(r.store.passwords, user.ID)
+	r.store.users[user.ID] = stored
+	*user = stored
+	return nil
+}
+
+// setUserColumn 按列名设置用户字段
+func setUserColumn(user *models.User, column string, value interface{}) error {
+	switch column {
+	case "email":
+		user.Email = value.(string)
+	case "password":
+		user.Password = value.(string)
+	case "role":
+		user.Role = value.(string)
+	case "status":
+		user.Status = value.(string)
+	case "name":
+		user.Name = value.(string)
+	case "phone":
+		user.Phone = value.(string)
+	case "user_group":
+		user.Group = value.(string)
+	case "student_id":
+		user.StudentID = stringPtr(value)
+	case "card_number":
+		user.CardNumber = stringPtr(value)
+	case "membership_expires_at":
+		user.MembershipExpiresAt = timePtr(value)
+	case "suspended_reason":
+		user.SuspendedReason = value.(string)
+	case "suspended_until":
+		user.SuspendedUntil = timePtr(value)
+	case "suspension_rule":
+		user.SuspensionRule = value.(string)
+	case "anonymized_at":
+		user.AnonymizedAt = timePtr(value)
+	default:
+		return fmt.Errorf("unsupported user column: %s", column)
+	}
+	return nil
+}
+
+// stringPtr 将 nil、string 或 *string 转换为 *string
+func stringPtr(value interface{}) *string {
+	switch v := value.(type) {
+	case string:
+		return &v
+	case *string:
+		return v
+	default:
+		return nil
+	}
+}
+
+// timePtr 将 nil、time.Time 或 *time.Time 转换为 *time.Time
+func timePtr(value interface{}) *time.Time {
+	switch v := value.(type) {
+	case time.Time:
+		return &v
+	case *time.Time:
+		return v
+	default:
+		return nil
+	}
+}
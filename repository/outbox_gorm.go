@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormOutboxRepo 基于数据库表 email_outbox 的邮件发件箱仓储
+type GormOutboxRepo struct {
+	db *gorm.DB
+}
+
+// NewGormOutboxRepo 创建数据库邮件发件箱仓储
+func NewGormOutboxRepo(db *gorm.DB) *GormOutboxRepo {
+	return &GormOutboxRepo{db: db}
+}
+
+// Create 写入待发送邮件
+func (r *GormOutboxRepo) Create(ctx context.Context, item *models.EmailOutbox) error {
+	return r.db.WithContext(ctx).Create(item).Error
+}
+
+// List 分页查询发件箱
+func (r *GormOutboxRepo) List(ctx context.Context, filter OutboxFilter) ([]models.EmailOutbox, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.EmailOutbox{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Email != "" {
+		query = query.Where("to_email LIKE ?", "%"+filter.Email+"%")
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	records := []models.EmailOutbox{}
+	err := paginate(query.Order("created_at DESC"), filter.Page, filter.Limit).Find(&records).Error
+	return records, total, err
+}
+
+// StatusCounts 统计各状态的邮件数量
+func (r *GormOutboxRepo) StatusCounts(ctx context.Context) ([]OutboxStatusCount, error) {
+	counts := []OutboxStatusCount{}
+	err := r.db.WithContext(ctx).Model(&models.EmailOutbox{}).
+		Select("status, count(*) as count").Group("status").Scan(&counts).Error
+	return counts, err
+}
+
+// Retry 将正文未被清除的失败邮件重新置为待发送
+func (r *GormOutboxRepo) Retry(ctx context.Context, id uint, now time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.EmailOutbox{}).
+		Where("id = ? AND status = ? AND (html_body <> '' OR text_body <> '')", id, models.OutboxStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"locked_at":       nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Claim 领取到期的邮件并标记为发送中
+// 使用 SELECT ... FOR UPDATE SKIP LOCKED，多个实例同时运行时不会重复发送
+// SQLite 不支持该语法（GORM 会忽略），仅用于单实例的本地开发和测试
+func (r *GormOutboxRepo) Claim(ctx context.Context, now, staleBefore time.Time, limit int) ([]models.EmailOutbox, error) {
+	var items []models.EmailOutbox
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+				models.OutboxStatusPending, now,
+				models.OutboxStatusSending, staleBefore).
+			Order("id").
+			Limit(limit).
+			Find(&items).Error
+		if err != nil || len(items) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(items))
+		for i := range items {
+			ids[i] = items[i].ID
+		}
+		return tx.Model(&models.EmailOutbox{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":    models.OutboxStatusSending,
+			"locked_at": now,
+		}).Error
+	})
+	return items, err
+}
+
+// Update 按列名更新邮件
+func (r *GormOutboxRepo) Update(ctx context.Context, item *models.EmailOutbox, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(item).Updates(updates).Error
+}
+
+// PurgeBodies 清除已结束邮件的正文
+func (r *GormOutboxRepo) PurgeBodies(ctx context.Context, failedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.EmailOutbox{}).
+		Where("(html_body <> '' OR text_body <> '') AND (status = ? OR (status = ? AND created_at < ?))",
+			models.OutboxStatusSent, models.OutboxStatusFailed, failedBefore).
+		Updates(map[string]interface{}{"html_body": "", "text_body": ""})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,283 @@
+// Package repository 数据访问层
+// 定义图书、用户、借阅记录、验证码、角色权限、API密钥、邀请、token吊销、发件箱和邮件模板的仓储接口
+// 及其数据库实现，服务层只依赖接口，单元测试中可使用 repository/memory 中的内存实现替代数据库
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvitationUsed 邀请已被使用或已吊销
+var ErrInvitationUsed = errors.New("invitation already used or revoked")
+
+// ErrOutOfStock 图书没有可借库存
+var ErrOutOfStock = errors.New("book out of stock")
+
+// ErrOpenLoans 用户仍有未归还的借阅
+var ErrOpenLoans = errors.New("user has open loans")
+
+// ErrDuplicateKey 违反唯一约束
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// BookFilter 图书查询条件
+type BookFilter struct {
+	Keyword  string // 书名或作者模糊匹配
+	Category string // 分类精确匹配
+	Page     int
+	Limit    int
+}
+
+// UserFilter 读者查询条件（空值表示不筛选）
+type UserFilter struct {
+	Keyword    string // 邮箱、姓名或联系电话模糊匹配
+	CardNumber string // 借书卡号精确匹配
+	StudentID  string // 学号精确匹配
+	Group      string // 分组精确匹配
+	Status     string // normal、suspended、disabled
+	Page       int
+	Limit      int
+}
+
+// LoanFilter 借阅记录查询条件（空值表示不筛选）
+type LoanFilter struct {
+	UserEmail  string // 用户邮箱模糊匹配
+	CardNumber string // 借书卡号精确匹配
+	BookTitle  string // 书名模糊匹配
+	Status     string // borrowed、returned
+	Page       int
+	Limit      int // 不大于0时不分页，返回全部记录
+}
+
+// InvitationFilter 邀请查询条件（空值表示不筛选）
+type InvitationFilter struct {
+	Status string // pending（未使用）、used（已使用）、revoked（已吊销）、expired（已过期）
+	Email  string // 邀请邮箱模糊匹配
+	Page   int
+	Limit  int
+}
+
+// OutboxFilter 发件箱查询条件（空值表示不筛选）
+type OutboxFilter struct {
+	Status   string // pending、sending、sent、failed
+	Email    string // 收件人模糊匹配
+	Category string // 邮件类型
+	Page     int
+	Limit    int
+}
+
+// OutboxStatusCount 各状态的邮件数量
+type OutboxStatusCount struct {
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+// CodeRecordFilter 验证码记录查询条件（空值表示不筛选）
+type CodeRecordFilter struct {
+	Email   string // 邮箱精确匹配
+	Action  string // 用途
+	IsUsed  *bool  // 是否已使用
+	Keyword string // 邮箱模糊匹配（验证码仅以遮盖形式保存，不支持按验证码搜索）
+	Page    int
+	Limit   int // 不大于0时不分页，返回全部记录
+}
+
+// CodeRecordStats 验证码记录统计
+type CodeRecordStats struct {
+	Total    int64
+	Used     int64
+	Unused   int64
+	Expired  int64            // 未使用且已过期
+	ByAction map[string]int64 // 用途 -> 数量
+}
+
+// BookRepo 图书仓储
+type BookRepo interface {
+	// FindByID 按ID查找图书，不存在时返回ErrNotFound
+	FindByID(ctx context.Context, id uint) (*models.Book, error)
+	// FindByISBN 按ISBN查找图书，不存在时返回ErrNotFound
+	FindByISBN(ctx context.Context, isbn string) (*models.Book, error)
+	// Search 分页查询图书，按创建时间倒序，返回当前页及总数
+	Search(ctx context.Context, filter BookFilter) ([]models.Book, int64, error)
+	// Create 创建图书
+	Create(ctx context.Context, book *models.Book) error
+	// Save 保存图书的全部字段
+	Save(ctx context.Context, book *models.Book) error
+	// Delete 删除图书
+	Delete(ctx context.Context, id uint) error
+}
+
+// UserRepo 用户仓储
+type UserRepo interface {
+	// FindByID 按ID查找用户，不存在时返回ErrNotFound
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	// FindByEmail 按邮箱查找用户，不存在时返回ErrNotFound
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// Create 创建用户
+	Create(ctx context.Context, user *models.User) error
+	// CreateWithInvitation 创建用户并将邀请标记为已使用（同一事务）
+	// 邀请已被使用或已吊销时返回ErrInvitationUsed，用户不会被创建
+	CreateWithInvitation(ctx context.Context, user *models.User, invitationID uint) error
+	// Update 按列名更新用户字段，更新后重新读取到 user 中
+	Update(ctx context.Context, user *models.User, updates map[string]interface{}) error
+	// ChangeEmail 修改用户邮箱并吊销携带旧邮箱签发的token（同一事务），修改后重新读取到 user 中
+	// 新邮箱已被其他用户使用时返回ErrDuplicateKey
+	ChangeEmail(ctx context.Context, user *models.User, newEmail string, revokedAt time.Time) error
+	// UpdatePassword 更新密码哈希，并将旧哈希写入历史记录（同一事务）
+	// 历史记录只保留最近 historySize 条，historySize 不大于0时不记录
+	UpdatePassword(ctx context.Context, user *models.User, hash string, historySize int) error
+	// PasswordHistory 获取用户最近使用过的密码哈希，按时间倒序，最多 limit 条
+	PasswordHistory(ctx context.Context, userID uint, limit int) ([]string, error)
+	// Search 分页查询未注销的用户，按ID倒序，返回当前页及总数
+	Search(ctx context.Context, filter UserFilter) ([]models.User, int64, error)
+	// CountBy 统计指定列（email、student_id、card_number、role）等于 value 的用户数量
+	// excludeID 不为0时排除该用户（用于修改资料时的唯一性检查）
+	CountBy(ctx context.Context, column, value string, excludeID uint) (int64, error)
+	// ExistingValues 返回 values 中已被用户使用的值（列同CountBy，用于批量导入时的唯一性检查）
+	ExistingValues(ctx context.Context, column string, values []string) ([]string, error)
+	// ListSuspensionCandidates 按ID顺序获取ID大于 afterID、未注销且未禁用的用户中，
+	// 已暂停、读者资格在 now 之前到期，或有到期日早于 overdueBefore 的未归还借阅的用户（overdueBefore 为空时不按逾期筛选）
+	ListSuspensionCandidates(ctx context.Context, afterID uint, now time.Time, overdueBefore *time.Time, limit int) ([]models.User, error)
+	// Anonymize 注销用户：按 updates 匿名化用户行并重新读取到 user 中，同时吊销其API密钥，删除其历史密码、
+	// 发往原邮箱的验证码记录和发件箱邮件（包括尚未发送的，同一事务）；存在未归还的借阅时返回ErrOpenLoans
+	Anonymize(ctx context.Context, user *models.User, updates map[string]interface{}) error
+}
+
+// LoanRepo 借阅记录仓储
+type LoanRepo interface {
+	// FindOpen 查找用户对某本图书未归还的借阅记录，不存在时返回ErrNotFound
+	FindOpen(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error)
+	// CountOpenByUser 统计用户未归还的借阅数量
+	CountOpenByUser(ctx context.Context, userID uint) (int64, error)
+	// CountOpenByBook 统计图书未归还的借阅数量
+	CountOpenByBook(ctx context.Context, bookID uint) (int64, error)
+	// CountOverdue 统计到期日早于 now 且未归还的借阅数量
+	CountOverdue(ctx context.Context, now time.Time) (int64, error)
+	// Create 创建借阅记录并扣减图书可借数量（同一事务），没有可借库存时返回ErrOutOfStock
+	Create(ctx context.Context, record *models.BorrowRecord) error
+	// MarkReturned 将借阅记录标记为已归还并增加图书可借数量（同一事务）
+	// 记录已被归还时返回ErrNotFound
+	MarkReturned(ctx context.Context, record *models.BorrowRecord, returnDate time.Time) error
+	// ListByUser 分页查询用户的借阅记录（含书名），按借阅时间倒序，只使用 filter 中的状态和分页条件
+	ListByUser(ctx context.Context, userID uint, filter LoanFilter) ([]models.BorrowRecordWithDetails, int64, error)
+	// List 分页查询全部借阅记录（含用户和书名），按借阅时间倒序
+	List(ctx context.Context, filter LoanFilter) ([]models.BorrowRecordWithDetails, int64, error)
+	// OldestOverdue 查找用户到期日早于 before 的未归还借阅中到期最早的一条（含书名），不存在时返回ErrNotFound
+	OldestOverdue(ctx context.Context, userID uint, before time.Time) (*models.BorrowRecordWithDetails, error)
+}
+
+// RoleRepo 角色与权限仓储
+type RoleRepo interface {
+	// Seed 写入权限和角色（按权限码、角色名判断，已存在则跳过）
+	Seed(ctx context.Context, permissions []models.Permission, roles []models.Role) error
+	// PermissionCodes 获取全部权限码，按权限码排序
+	PermissionCodes(ctx context.Context) ([]string, error)
+	// ListPermissions 获取全部权限，按权限码排序
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+	// FindPermissions 按权限码查找权限，未定义的权限码被忽略
+	FindPermissions(ctx context.Context, codes []string) ([]models.Permission, error)
+	// ListRoles 获取全部角色及其权限，按ID排序
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	// FindRoleByID 按ID查找角色（不含权限），不存在时返回ErrNotFound
+	FindRoleByID(ctx context.Context, id uint) (*models.Role, error)
+	// FindRoleByName 按名称查找角色及其权限，不存在时返回ErrNotFound
+	FindRoleByName(ctx context.Context, name string) (*models.Role, error)
+	// CreateRole 创建角色及其权限关联
+	CreateRole(ctx context.Context, role *models.Role) error
+	// UpdateRole 更新角色描述和权限（同一事务），为空的参数不更新；更新后重新读取到 role 中（含权限）
+	UpdateRole(ctx context.Context, role *models.Role, description *string, permissions *[]models.Permission) error
+	// DeleteRole 删除角色及其权限关联（同一事务）
+	DeleteRole(ctx context.Context, role *models.Role) error
+}
+
+// APIKeyRepo API密钥仓储
+type APIKeyRepo interface {
+	// Create 创建API密钥
+	Create(ctx context.Context, key *models.APIKey) error
+	// FindByPrefix 按前缀查找API密钥（含所属用户），不存在时返回ErrNotFound
+	FindByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// TouchLastUsed 记录最近使用时间
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+	// ListByUser 获取用户的全部API密钥，按创建时间倒序
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	// Revoke 吊销用户未吊销的API密钥，密钥不存在、不属于该用户或已吊销时返回ErrNotFound
+	Revoke(ctx context.Context, userID, keyID uint, at time.Time) error
+}
+
+// InvitationRepo 注册邀请仓储
+type InvitationRepo interface {
+	// Create 创建邀请
+	Create(ctx context.Context, invitation *models.Invitation) error
+	// FindByTokenHash 按邀请码哈希查找邀请，不存在时返回ErrNotFound
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	// List 分页查询邀请，按创建时间倒序，返回当前页及总数
+	List(ctx context.Context, filter InvitationFilter, now time.Time) ([]models.Invitation, int64, error)
+	// Revoke 吊销未使用的邀请，邀请不存在或已使用/已吊销时返回ErrNotFound
+	Revoke(ctx context.Context, id uint, at time.Time) error
+}
+
+// TokenRepo 登录token吊销记录仓储（每个邮箱只保留最近一次吊销时间）
+type TokenRepo interface {
+	// RevokeEmail 记录邮箱的吊销时间（覆盖旧记录）
+	RevokeEmail(ctx context.Context, email string, at time.Time) error
+	// RevokedSince 检查邮箱的吊销时间是否不早于 issuedAt
+	RevokedSince(ctx context.Context, email string, issuedAt time.Time) (bool, error)
+}
+
+// OutboxRepo 邮件发件箱仓储
+type OutboxRepo interface {
+	// Create 写入待发送邮件
+	Create(ctx context.Context, item *models.EmailOutbox) error
+	// List 分页查询发件箱，按创建时间倒序，返回当前页及总数
+	List(ctx context.Context, filter OutboxFilter) ([]models.EmailOutbox, int64, error)
+	// StatusCounts 统计各状态的邮件数量
+	StatusCounts(ctx context.Context) ([]OutboxStatusCount, error)
+	// Retry 将正文未被清除的失败邮件重新置为待发送，邮件不存在、未失败或正文已清除时返回ErrNotFound
+	Retry(ctx context.Context, id uint, now time.Time) error
+	// Claim 领取最多 limit 封到期的待发送邮件，以及领取时间早于 staleBefore 的发送中邮件，并标记为发送中
+	// 多个实例同时领取时不会领取到同一封邮件
+	Claim(ctx context.Context, now, staleBefore time.Time, limit int) ([]models.EmailOutbox, error)
+	// Update 按列名更新邮件
+	Update(ctx context.Context, item *models.EmailOutbox, updates map[string]interface{}) error
+	// PurgeBodies 清除已发送邮件，以及创建时间早于 failedBefore 的失败邮件的正文，返回清除的数量
+	PurgeBodies(ctx context.Context, failedBefore time.Time) (int64, error)
+}
+
+// TemplateRepo 自定义邮件模板仓储
+type TemplateRepo interface {
+	// List 获取全部自定义模板
+	List(ctx context.Context) ([]models.EmailTemplate, error)
+	// Find 按模板标识和语言查找自定义模板，不存在时返回ErrNotFound
+	Find(ctx context.Context, key, locale string) (*models.EmailTemplate, error)
+	// Save 保存自定义模板（同一模板标识和语言已存在时覆盖）
+	Save(ctx context.Context, tpl *models.EmailTemplate) error
+	// Delete 删除自定义模板
+	Delete(ctx context.Context, key, locale string) error
+}
+
+// CodeRecordRepo 验证码审计记录仓储（仅保存遮盖后的验证码，供管理员查看）
+type CodeRecordRepo interface {
+	// Create 创建记录，并作废同一邮箱+用途未使用的旧记录
+	Create(ctx context.Context, record *models.EmailCodeRecord) error
+	// MarkUsed 将同一邮箱+用途未使用且未作废的记录标记为已使用
+	MarkUsed(ctx context.Context, email, action string, at time.Time) error
+	// Invalidate 作废同一邮箱+用途未使用的记录
+	Invalidate(ctx context.Context, email, action string, at time.Time) error
+	// List 分页查询记录，按创建时间倒序，返回当前页及总数
+	List(ctx context.Context, filter CodeRecordFilter) ([]models.EmailCodeRecord, int64, error)
+	// Stats 统计记录总数、使用情况、过期数量（以 now 为准）及各用途数量
+	Stats(ctx context.Context, now time.Time) (*CodeRecordStats, error)
+}
+
+// offset 分页偏移量
+func offset(page, limit int) int {
+	if page <= 0 {
+		return 0
+	}
+	return (page - 1) * limit
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GormRoleRepo 基于数据库表 role、permission 的角色与权限仓储
+type GormRoleRepo struct {
+	db *gorm.DB
+}
+
+// NewGormRoleRepo 创建数据库角色与权限仓储
+func NewGormRoleRepo(db *gorm.DB) *GormRoleRepo {
+	return &GormRoleRepo{db: db}
+}
+
+// Seed 写入权限和角色，已存在则跳过
+func (r *GormRoleRepo) Seed(ctx context.Context, permissions []models.Permission, roles []models.Role) error {
+	db := r.db.WithContext(ctx)
+
+	for _, p := range permissions {
+		perm := p
+		if err := db.Where("code = ?", perm.Code).FirstOrCreate(&perm).Error; err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", perm.Code, err)
+		}
+	}
+	for _, ro := range roles {
+		role := ro
+		if err := db.Where("name = ?", role.Name).FirstOrCreate(&role).Error; err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", role.Name, err)
+		}
+	}
+	return nil
+}
+
+// PermissionCodes 获取全部权限码
+func (r *GormRoleRepo) PermissionCodes(ctx context.Context) ([]string, error) {
+	codes := []string{}
+	err := r.db.WithContext(ctx).Model(&models.Permission{}).Order("code").Pluck("code", &codes).Error
+	return codes, err
+}
+
+// ListPermissions 获取全部权限
+func (r *GormRoleRepo) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	err := r.db.WithContext(ctx).Order("code").Find(&permissions).Error
+	return permissions, err
+}
+
+// FindPermissions 按权限码查找权限
+func (r *GormRoleRepo) FindPermissions(ctx context.Context, codes []string) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if len(codes) == 0 {
+		return permissions, nil
+	}
+	err := r.db.WithContext(ctx).Where("code IN ?", codes).Find(&permissions).Error
+	return permissions, err
+}
+
+// ListRoles 获取全部角色及其权限
+func (r *GormRoleRepo) ListRoles(ctx context.Context) ([]models.Role, error) {
+	roles := []models.Role{}
+	err := r.db.WithContext(ctx).Preload("Permissions").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+// FindRoleByID 按ID查找角色
+func (r *GormRoleRepo) FindRoleByID(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.WithContext(ctx).First(&role, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &role, nil
+}
+
+// FindRoleByName 按名称查找角色及其权限
+func (r *GormRoleRepo) FindRoleByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &role, nil
+}
+
+// CreateRole 创建角色及其权限关联
+func (r *GormRoleRepo) CreateRole(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+// UpdateRole 更新角色描述和权限并重新读取
+func (r *GormRoleRepo) UpdateRole(ctx context.Context, role *models.Role, description *string, permissions *[]models.Permission) error {
+	db := r.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if description != nil {
+			if err := tx.Model(role).Update("description", *description).Error; err != nil {
+				return err
+			}
+		}
+		if permissions != nil {
+			if err := tx.Model(role).Association("Permissions").Replace(*permissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return translateError(db.Preload("Permissions").First(role, role.ID).Error)
+}
+
+// DeleteRole 删除角色及其权限关联
+func (r *GormRoleRepo) DeleteRole(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
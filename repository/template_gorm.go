@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// GormTemplateRepo 基于数据库表 email_template 的自定义邮件模板仓储
+type GormTemplateRepo struct {
+	db *gorm.DB
+}
+
+// NewGormTemplateRepo 创建数据库自定义邮件模板仓储
+func NewGormTemplateRepo(db *gorm.DB) *GormTemplateRepo {
+	return &GormTemplateRepo{db: db}
+}
+
+// List 获取全部自定义模板
+func (r *GormTemplateRepo) List(ctx context.Context) ([]models.EmailTemplate, error) {
+	templates := []models.EmailTemplate{}
+	err := r.db.WithContext(ctx).Find(&templates).Error
+	return templates, err
+}
+
+// Find 按模板标识和语言查找自定义模板
+func (r *GormTemplateRepo) Find(ctx context.Context, key, locale string) (*models.EmailTemplate, error) {
+	var tpl models.EmailTemplate
+	if err := r.db.WithContext(ctx).Where("template_key = ? AND locale = ?", key, locale).First(&tpl).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &tpl, nil
+}
+
+// Save 保存自定义模板，同一模板标识和语言已存在时覆盖
+func (r *GormTemplateRepo) Save(ctx context.Context, tpl *models.EmailTemplate) error {
+	db := r.db.WithContext(ctx)
+
+	var existing models.EmailTemplate
+	err := db.Where("template_key = ? AND locale = ?", tpl.Key, tpl.Locale).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	tpl.ID = existing.ID
+	return db.Save(tpl).Error
+}
+
+// Delete 删除自定义模板
+func (r *GormTemplateRepo) Delete(ctx context.Context, key, locale string) error {
+	return r.db.WithContext(ctx).Where("template_key = ? AND locale = ?", key, locale).Delete(&models.EmailTemplate{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormTokenRepo 基于数据库表 token_revocation 的token吊销记录仓储
+type GormTokenRepo struct {
+	db *gorm.DB
+}
+
+// NewGormTokenRepo 创建数据库token吊销记录仓储
+func NewGormTokenRepo(db *gorm.DB) *GormTokenRepo {
+	return &GormTokenRepo{db: db}
+}
+
+// RevokeEmail 记录邮箱的吊销时间
+func (r *GormTokenRepo) RevokeEmail(ctx context.Context, email string, at time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at"}),
+	}).Create(&models.TokenRevocation{Email: email, RevokedAt: at}).Error
+}
+
+// RevokedSince 检查邮箱的吊销时间是否不早于 issuedAt
+func (r *GormTokenRepo) RevokedSince(ctx context.Context, email string, issuedAt time.Time) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.TokenRevocation{}).
+		Where("email = ? AND revoked_at >= ?", email, issuedAt).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"book-manage/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userCountColumns CountBy 支持的列
+var userCountColumns = map[string]bool{
+	"email":       true,
+	"student_id":  true,
+	"card_number": true,
+	"role":        true,
+}
+
+// GormUserRepo 基于数据库表 user 的用户仓储
+type GormUserRepo struct {
+	db *gorm.DB
+}
+
+// NewGormUserRepo 创建数据库用户仓储
+func NewGormUserRepo(db *gorm.DB) *GormUserRepo {
+	return &GormUserRepo{db: db}
+}
+
+// FindByID 按ID查找用户
+func (r *GormUserRepo) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// FindByEmail 按邮箱查找用户
+func (r *GormUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// Create 创建用户
+func (r *GormUserRepo) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// CreateWithInvitation 创建用户并将邀请标记为已使用，并发使用同一邀请时只有一次成功
+func (r *GormUserRepo) CreateWithInvitation(ctx context.Context, user *models.User, invitationID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", invitationID).
+			Updates(map[string]interface{}{
+				"used_at": time.Now(),
+				"used_by": user.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationUsed
+		}
+		return nil
+	})
+}
+
+// Update 按列名更新用户字段并重新读取
+func (r *GormUserRepo) Update(ctx context.Context, user *models.User, updates map[string]interface{}) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return translateError(db.First(user, user.ID).Error)
+}
+
+// ChangeEmail 修改用户邮箱并记录旧邮箱的token吊销时间
+func (r *GormUserRepo) ChangeEmail(ctx context.Context, user *models.User, newEmail string, revokedAt time.Time) error {
+	db := r.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("email", newEmail).Error; err != nil {
+			if isDuplicateKey(tx, err) {
+				return ErrDuplicateKey
+			}
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_at"}),
+		}).Create(&models.TokenRevocation{Email: user.Email, RevokedAt: revokedAt}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return translateError(db.First(user, user.ID).Error)
+}
+
+// UpdatePassword 更新密码哈希，并将旧哈希写入历史记录、清理超出保留数量的记录
+func (r *GormUserRepo) UpdatePassword(ctx context.Context, user *models.User, hash string, historySize int) error {
+	oldHash := user.Password
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("password", hash).Error; err != nil {
+			return err
+		}
+		if historySize <= 0 || oldHash == "" {
+			return nil
+		}
+
+		if err := tx.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: oldHash}).Error; err != nil {
+			return err
+		}
+		var keepIDs []uint
+		if err := tx.Model(&models.PasswordHistory{}).
+			Where("user_id = ?", user.ID).
+			Order("created_at DESC, id DESC").
+			Limit(historySize).
+			Pluck("id", &keepIDs).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id NOT IN ?", user.ID, keepIDs).Delete(&models.PasswordHistory{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	return nil
+}
+
+// PasswordHistory 获取用户最近使用过的密码哈希
+func (r *GormUserRepo) PasswordHistory(ctx context.Context, userID uint, limit int) ([]string, error) {
+	hashes := []string{}
+	err := r.db.WithContext(ctx).Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	return hashes, err
+}
+
+// Search 分页查询未注销的用户
+func (r *GormUserRepo) Search(ctx context.Context, filter UserFilter) ([]models.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.User{}).Where("anonymized_at IS NULL")
+
+	if keyword := strings.TrimSpace(filter.Keyword); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("email LIKE ? OR name LIKE ? OR phone LIKE ?", like, like, like)
+	}
+	if filter.CardNumber != "" {
+		query = query.Where("card_number = ?", filter.CardNumber)
+	}
+	if filter.StudentID != "" {
+		query = query.Where("student_id = ?", filter.StudentID)
+	}
+	if filter.Group != "" {
+		query = query.Where("user_group = ?", filter.Group)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	users := []models.User{}
+	err := query.Order("id DESC").Offset(offset(filter.Page, filter.Limit)).Limit(filter.Limit).Find(&users).Error
+	return users, total, err
+}
+
+// CountBy 统计指定列等于 value 的用户数量
+func (r *GormUserRepo) CountBy(ctx context.Context, column, value string, excludeID uint) (int64, error) {
+	if !userCountColumns[column] {
+		return 0, fmt.Errorf("unsupported user column: %s", column)
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.User{}).Where(column+" = ?", value)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// ExistingValues 返回 values 中已被用户使用的值
+func (r *GormUserRepo) ExistingValues(ctx context.Context, column string, values []string) ([]string, error) {
+	if !userCountColumns[column] {
+		return nil, fmt.Errorf("unsupported user column: %s", column)
+	}
+
+	existing := []string{}
+	if len(values) == 0 {
+		return existing, nil
+	}
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where(column+" IN ?", values).Distinct().Pluck(column, &existing).Error
+	return existing, err
+}
+
+// ListSuspensionCandidates 按ID顺序获取可能需要暂停或解除暂停的用户
+func (r *GormUserRepo) ListSuspensionCandidates(ctx context.Context, afterID uint, now time.Time, overdueBefore *time.Time, limit int) ([]models.User, error) {
+	db := r.db.WithContext(ctx)
+
+	candidates := db.Where("status = ?", models.UserStatusSuspended).
+		Or("membership_expires_at < ?", now)
+	if overdueBefore != nil {
+		candidates = candidates.Or("id IN (?)", db.Model(&models.BorrowRecord{}).
+			Select("user_id").
+			Where("status = ? AND due_date < ?", models.BorrowStatusBorrowed, *overdueBefore))
+	}
+
+	var users []models.User
+	err := db.Where("id > ? AND anonymized_at IS NULL AND status <> ?", afterID, models.UserStatusDisabled).
+		Where(candidates).
+		Order("id").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Anonymize 注销用户并清除关联的个人数据
+// 未归还借阅的检查与匿名化在同一事务中，避免检查后又借出图书
+func (r *GormUserRepo) Anonymize(ctx context.Context, user *models.User, updates map[string]interface{}) error {
+	oldEmail := user.Email
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var openLoans int64
+		if err := tx.Model(&models.BorrowRecord{}).
+			Where("user_id = ? AND status = ?", user.ID, models.BorrowStatusBorrowed).
+			Count(&openLoans).Error; err != nil {
+			return err
+		}
+		if openLoans > 0 {
+			return ErrOpenLoans
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("email = ?", oldEmail).Delete(&models.EmailCodeRecord{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("to_email = ?", oldEmail).Delete(&models.EmailOutbox{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return translateError(r.db.WithContext(ctx).First(user, user.ID).Error)
+}
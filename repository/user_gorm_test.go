@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"book-manage/database"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开迁移后的内存 SQLite 数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestAnonymizeDeletesOutboxForOldEmail(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewGormUserRepo(db)
+
+	user := &models.User{Email: "reader@example.com", Password: "hash", Status: models.UserStatusNormal}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	for _, status := range []string{models.OutboxStatusPending, models.OutboxStatusSending,
+		models.OutboxStatusSent, models.OutboxStatusFailed} {
+		item := &models.EmailOutbox{Category: "code_login", FromEmail: "noreply@example.com", ToEmail: user.Email,
+			Subject: "验证码", TextBody: "123456", Status: status, MaxAttempts: 5, NextAttemptAt: time.Now()}
+		if err := db.Create(item).Error; err != nil {
+			t.Fatalf("create outbox item: %v", err)
+		}
+	}
+	other := &models.EmailOutbox{Category: "code_login", FromEmail: "noreply@example.com", ToEmail: "other@example.com",
+		Subject: "验证码", TextBody: "654321", Status: models.OutboxStatusPending, MaxAttempts: 5, NextAttemptAt: time.Now()}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("create outbox item: %v", err)
+	}
+
+	err := repo.Anonymize(ctx, user, map[string]interface{}{
+		"email":  "deleted-1@deleted.invalid",
+		"status": models.UserStatusDisabled,
+	})
+	if err != nil {
+		t.Fatalf("anonymize: %v", err)
+	}
+	if user.Email != "deleted-1@deleted.invalid" || user.Status != models.UserStatusDisabled {
+		t.Fatalf("user not reloaded: email=%q status=%q", user.Email, user.Status)
+	}
+
+	var remaining []models.EmailOutbox
+	if err := db.Find(&remaining).Error; err != nil {
+		t.Fatalf("list outbox: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != other.ID {
+		t.Fatalf("remaining outbox = %+v, want only the other recipient's mail", remaining)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewGormUserRepo(db)
+	tokens := repository.NewGormTokenRepo(db)
+
+	user := &models.User{Email: "old@example.com", Password: "hash", Status: models.UserStatusNormal}
+	other := &models.User{Email: "taken@example.com", Password: "hash", Status: models.UserStatusNormal}
+	for _, u := range []*models.User{user, other} {
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	issuedAt := time.Now().Add(-time.Minute)
+
+	// 新邮箱已被使用：邮箱不变，也不吊销token
+	if err := repo.ChangeEmail(ctx, user, other.Email, time.Now()); err != repository.ErrDuplicateKey {
+		t.Fatalf("change to taken email: err = %v, want ErrDuplicateKey", err)
+	}
+	if revoked, err := tokens.RevokedSince(ctx, "old@example.com", issuedAt); err != nil || revoked {
+		t.Fatalf("revoked after failed change = %v, %v; want false", revoked, err)
+	}
+
+	if err := repo.ChangeEmail(ctx, user, "new@example.com", time.Now()); err != nil {
+		t.Fatalf("change email: %v", err)
+	}
+	if user.Email != "new@example.com" {
+		t.Fatalf("email = %q, want new@example.com", user.Email)
+	}
+	if revoked, err := tokens.RevokedSince(ctx, "old@example.com", issuedAt); err != nil || !revoked {
+		t.Fatalf("revoked after change = %v, %v; want true", revoked, err)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"book-manage/logging"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOpenLoans 用户仍有未归还的图书，不能注销账户
+var ErrOpenLoans = errors.New("存在未归还的图书")
+
+var accountLog = logging.For("account")
+
+// finesUnavailableReason 导出内容中罚款记录为空的原因
+const finesUnavailableReason = "系统暂未记录罚款，罚款记录为空"
+
+// AccountExport 个人数据导出内容
+type AccountExport struct {
+	ExportedAt       time.Time                        `json:"exported_at"`
+	Profile          map[string]interface{}           `json:"profile"`
+	BorrowRecords    []models.BorrowRecordWithDetails `json:"borrow_records"`
+	EmailCodeRecords []models.EmailCodeRecord         `json:"email_code_records"`
+	APIKeys          []models.APIKey                  `json:"api_keys"`
+	Fines            AccountFines                     `json:"fines"`
+}
+
+// AccountFines 导出内容中的罚款信息
+type AccountFines struct {
+	Records []map[string]interface{} `json:"records"` // 罚款记录
+	Reason  string                   `json:"reason"`  // 罚款记录为空的原因
+}
+
+// AccountService 账户自助服务（个人数据导出、注销账户）
+type AccountService struct {
+	users  repository.UserRepo
+	loans  repository.LoanRepo
+	codes  repository.CodeRecordRepo
+	keys   repository.APIKeyRepo
+	tokens *TokenService
+}
+
+// NewAccountService 创建账户自助服务
+func NewAccountService(users repository.UserRepo, loans repository.LoanRepo, codes repository.CodeRecordRepo,
+	keys repository.APIKeyRepo, tokens *TokenService) *AccountService {
+	return &AccountService{users: users, loans: loans, codes: codes, keys: keys, tokens: tokens}
+}
+
+// Export 导出用户的全部个人数据
+// 系统未记录罚款，罚款部分固定为空列表并注明原因
+func (s *AccountService) Export(ctx context.Context, userID uint) (*AccountExport, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    UserProfile(user),
+		Fines: AccountFines{
+			Records: []map[string]interface{}{},
+			Reason:  finesUnavailableReason,
+		},
+	}
+
+	if export.BorrowRecords, _, err = s.loans.ListByUser(ctx, userID, repository.LoanFilter{}); err != nil {
+		return nil, fmt.Errorf("failed to export borrow records: %w", err)
+	}
+	if export.BorrowRecords == nil {
+		export.BorrowRecords = []models.BorrowRecordWithDetails{}
+	}
+	if export.EmailCodeRecords, _, err = s.codes.List(ctx, repository.CodeRecordFilter{Email: user.Email}); err != nil {
+		return nil, fmt.Errorf("failed to export email code records: %w", err)
+	}
+	if export.APIKeys, err = s.keys.ListByUser(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to export api keys: %w", err)
+	}
+
+	return export, nil
+}
+
+// Delete 注销账户
+// 用户行匿名化保留（借阅记录仍关联该用户，借阅统计不受影响），邮箱、密码等个人信息被清除；
+// 同时吊销API密钥和已签发的token，删除验证码记录和发件箱中发往该邮箱的邮件（包括尚未发送的邮件）。
+// 存在未归还的图书时返回ErrOpenLoans
+func (s *AccountService) Delete(ctx context.Context, userID uint) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	oldEmail := user.Email
+	now := time.Now()
+	err = s.users.Anonymize(ctx, user, map[string]interface{}{
+		"email":            fmt.Sprintf("deleted-%d@deleted.invalid", user.ID),
+		"password":         "",
+		"role":             RoleUser,
+		"status":           models.UserStatusDisabled,
+		"name":             "",
+		"phone":            "",
+		"student_id":       nil,
+		"card_number":      nil,
+		"suspended_reason": "",
+		"suspended_until":  nil,
+		"suspension_rule":  "",
+		"anonymized_at":    &now,
+	})
+	if err == repository.ErrOpenLoans {
+		return ErrOpenLoans
+	}
+	if err != nil {
+		return err
+	}
+
+	// 吊销携带原邮箱的token
+	if err := s.tokens.RevokeEmail(ctx, oldEmail); err != nil {
+		accountLog.ErrorContext(ctx, "吊销token失败", "error", err, "user_id", userID)
+	}
+
+	return nil
+}
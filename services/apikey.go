@@ -0,0 +1,181 @@
+package services
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API密钥权限范围
+const (
+	ScopeCatalogRead  = "catalog:read"  // 图书检索与详情
+	ScopeCatalogWrite = "catalog:write" // 图书添加/编辑/删除（仍需 book:edit 权限）
+	ScopeCirculation  = "circulation"   // 借书、还书、借阅记录
+	ScopeProfileRead  = "profile:read"  // 个人信息
+)
+
+// ErrAPIKeyNotFound API密钥不存在或已吊销
+var ErrAPIKeyNotFound = errors.New("API密钥不存在或已吊销")
+
+// apiKeyPrefix API密钥的固定前缀，便于在日志或代码仓库中识别泄露的密钥
+const apiKeyPrefix = "bk_"
+
+// AllScopes 所有可分配的权限范围
+var AllScopes = []string{ScopeCatalogRead, ScopeCatalogWrite, ScopeCirculation, ScopeProfileRead}
+
+// apiKeyRouteScopes 路由与所需权限范围的映射
+// 未列出的路由（如修改密码、管理API密钥）不允许使用API密钥访问
+var apiKeyRouteScopes = map[string]string{
+	"/api/user/profile":       ScopeProfileRead,
+	"/api/user/borrowRecords": ScopeCirculation,
+	"/api/book/detail":        ScopeCatalogRead,
+	"/api/book/search":        ScopeCatalogRead,
+	"/api/book/add":           ScopeCatalogWrite,
+	"/api/book/edit":          ScopeCatalogWrite,
+	"/api/book/delete":        ScopeCatalogWrite,
+	"/api/book/uploadCover":   ScopeCatalogWrite,
+	"/api/book/deleteCover":   ScopeCatalogWrite,
+	"/api/borrow/borrow":      ScopeCirculation,
+	"/api/borrow/return":      ScopeCirculation,
+	"/api/borrow/records":     ScopeCirculation,
+	"/api/borrow/allRecords":  ScopeCirculation,
+}
+
+// APIKeyService API密钥服务
+type APIKeyService struct {
+	keys repository.APIKeyRepo
+}
+
+// NewAPIKeyService 创建API密钥服务
+func NewAPIKeyService(keys repository.APIKeyRepo) *APIKeyService {
+	return &APIKeyService{keys: keys}
+}
+
+// IsValidScope 检查权限范围是否合法
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteScope 获取路由所需的权限范围，未配置的路由返回false
+func RouteScope(fullPath string) (string, bool) {
+	scope, ok := apiKeyRouteScopes[fullPath]
+	return scope, ok
+}
+
+// HasScope 检查API密钥是否包含指定权限范围
+func HasScope(key *models.APIKey, scope string) bool {
+	for _, s := range strings.Split(key.Scopes, ",") {
+		if strings.TrimSpace(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Create 为用户创建API密钥，返回的明文密钥仅在创建时可见
+func (s *APIKeyService) Create(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := models.APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashSecret(secret),
+		Scopes:     strings.Join(scopes, ","),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.keys.Create(ctx, &key); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &key, fmt.Sprintf("%s%s_%s", apiKeyPrefix, prefix, secret), nil
+}
+
+// Authenticate 校验明文API密钥，返回密钥记录及其所属用户
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, nil, fmt.Errorf("invalid api key format")
+	}
+	parts := strings.SplitN(strings.TrimPrefix(rawKey, apiKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, nil, fmt.Errorf("invalid api key format")
+	}
+
+	key, err := s.keys.FindByPrefix(ctx, parts[0])
+	if err == repository.ErrNotFound {
+		return nil, nil, fmt.Errorf("api key not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.SecretHash), []byte(hashSecret(parts[1]))) != 1 {
+		return nil, nil, fmt.Errorf("api key mismatch")
+	}
+	if key.RevokedAt != nil {
+		return nil, nil, fmt.Errorf("api key revoked")
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, nil, fmt.Errorf("api key expired")
+	}
+	if key.User.Status == models.UserStatusDisabled {
+		return nil, nil, fmt.Errorf("user disabled")
+	}
+
+	// 记录最近使用时间（失败不影响本次请求）
+	s.keys.TouchLastUsed(ctx, key.ID, now)
+
+	return key, &key.User, nil
+}
+
+// List 获取用户的API密钥列表
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	return s.keys.ListByUser(ctx, userID)
+}
+
+// Revoke 吊销用户的API密钥，密钥不存在、不属于该用户或已吊销时返回ErrAPIKeyNotFound
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID uint) error {
+	err := s.keys.Revoke(ctx, userID, keyID, time.Now())
+	if err == repository.ErrNotFound {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// randomHex 生成n字节的随机数并编码为十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecret 计算密钥的SHA-256哈希
+// 密钥本身为高熵随机串，无需bcrypt等慢哈希，避免每个请求的校验开销
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"book-manage/logging"
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/utils"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEmailTaken 邮箱已被注册
+var ErrEmailTaken = errors.New("邮箱已被注册")
+
+// ErrWrongPassword 密码错误
+var ErrWrongPassword = errors.New("密码错误")
+
+// authLog 用户认证日志
+var authLog = logging.For("auth")
+
+// Session 登录成功后签发的会话
+type Session struct {
+	Role        string   // 当前角色（邮箱白名单中的用户为 admin）
+	Permissions []string // 登录时的权限，仅供客户端展示，接口鉴权时按当前角色重新解析
+	Token       string
+}
+
+// AuthService 注册、登录和密码服务
+type AuthService struct {
+	users  repository.UserRepo
+	hasher *PasswordHasher
+	policy *PasswordPolicy
+	rbac   *RBACService
+	admin  *AdminService
+}
+
+// NewAuthService 创建注册、登录和密码服务
+func NewAuthService(users repository.UserRepo, hasher *PasswordHasher, policy *PasswordPolicy,
+	rbac *RBACService, admin *AdminService) *AuthService {
+	return &AuthService{users: users, hasher: hasher, policy: policy, rbac: rbac, admin: admin}
+}
+
+// FindByEmail 按邮箱查找用户，不存在时返回ErrUserNotFound
+func (s *AuthService) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// FindByID 按ID查找用户，不存在时返回ErrUserNotFound
+func (s *AuthService) FindByID(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// EmailTaken 检查邮箱是否已被注册
+func (s *AuthService) EmailTaken(ctx context.Context, email string) (bool, error) {
+	count, err := s.users.CountBy(ctx, "email", email, 0)
+	return count > 0, err
+}
+
+// Register 创建用户（默认角色为user；使用邀请注册时使用邀请预先分配的角色和分组，并将邀请标记为已使用）
+// 邮箱已注册时返回ErrEmailTaken，邀请已被使用时返回ErrInvitationInvalid
+func (s *AuthService) Register(ctx context.Context, email, password string, invitation *models.Invitation) (*models.User, error) {
+	taken, err := s.EmailTaken(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrEmailTaken
+	}
+
+	hashStart := time.Now()
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	hashElapsed := time.Since(hashStart)
+
+	user := &models.User{
+		Email:        email,
+		Password:     hashedPassword,
+		Role:         RoleUser,
+		RegisterTime: time.Now(),
+		Status:       models.UserStatusNormal,
+	}
+
+	createStart := time.Now()
+	if invitation != nil {
+		user.Role = invitation.Role
+		user.Group = invitation.Group
+		err = s.users.CreateWithInvitation(ctx, user, invitation.ID)
+	} else {
+		err = s.users.Create(ctx, user)
+	}
+	if err == repository.ErrInvitationUsed {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	authLog.InfoContext(ctx, "用户注册成功", "user_id", user.ID,
+		"hash_ms", hashElapsed.Milliseconds(), "create_ms", time.Since(createStart).Milliseconds())
+	return user, nil
+}
+
+// Authenticate 使用邮箱和密码登录
+// 用户不存在时返回ErrUserNotFound，密码错误时返回ErrWrongPassword；
+// 哈希算法或参数已更新时，使用新配置重新哈希（失败不影响登录）
+func (s *AuthService) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if !s.VerifyPassword(user, password) {
+		return nil, ErrWrongPassword
+	}
+
+	s.rehash(ctx, user, password)
+	return user, nil
+}
+
+// VerifyPassword 校验用户的当前密码
+func (s *AuthService) VerifyPassword(user *models.User, password string) bool {
+	return s.hasher.Verify(user.Password, password)
+}
+
+// CheckPolicy 校验密码策略，返回全部不符合的原因
+func (s *AuthService) CheckPolicy(password, email string) []PasswordViolation {
+	return s.policy.Check(password, email)
+}
+
+// CheckReuse 检查新密码是否与当前密码或最近使用过的密码相同
+func (s *AuthService) CheckReuse(ctx context.Context, user *models.User, password string) ([]PasswordViolation, error) {
+	hashes := []string{user.Password}
+	if size := s.policy.HistorySize(); size > 0 {
+		history, err := s.users.PasswordHistory(ctx, user.ID, size)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, history...)
+	}
+	return s.policy.CheckReuse(s.hasher, hashes, password), nil
+}
+
+// SetPassword 设置新密码，并将旧密码写入历史记录
+func (s *AuthService) SetPassword(ctx context.Context, user *models.User, password string) error {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.users.UpdatePassword(ctx, user, hashedPassword, s.policy.HistorySize())
+}
+
+// ChangeEmail 修改登录邮箱，并在同一事务中吊销携带旧邮箱的所有token
+// 新邮箱已被注册时返回ErrEmailTaken（依赖唯一索引防止并发注册同一邮箱）
+func (s *AuthService) ChangeEmail(ctx context.Context, user *models.User, newEmail string) error {
+	err := s.users.ChangeEmail(ctx, user, newEmail, time.Now())
+	if err == repository.ErrDuplicateKey {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		authLog.ErrorContext(ctx, "修改邮箱失败", "error", err, "user_id", user.ID)
+		return err
+	}
+	return nil
+}
+
+// NewSession 确定用户角色和权限并签发登录token
+func (s *AuthService) NewSession(ctx context.Context, user *models.User) (*Session, error) {
+	// 优先检查邮箱白名单，再使用数据库role字段
+	role := user.Role
+	if s.admin != nil {
+		if r, err := s.admin.GetUserRole(ctx, user.Email); err == nil {
+			role = r
+		}
+	}
+
+	permissions := []string{}
+	if s.rbac != nil {
+		if perms, err := s.rbac.ResolvePermissions(ctx, user.Email, role); err == nil {
+			permissions = perms
+		}
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Email, role)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Role: role, Permissions: permissions, Token: token}, nil
+}
+
+// rehash 密码校验成功后，若哈希使用了过时的算法或参数则重新哈希
+func (s *AuthService) rehash(ctx context.Context, user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		authLog.ErrorContext(ctx, "重新哈希密码失败", "error", err, "user_id", user.ID)
+		return
+	}
+	if err := s.users.Update(ctx, user, map[string]interface{}{"password": hashedPassword}); err != nil {
+		authLog.ErrorContext(ctx, "更新密码哈希失败", "error", err, "user_id", user.ID)
+		return
+	}
+	authLog.InfoContext(ctx, "密码哈希已升级", "algorithm", s.hasher.Algorithm(), "user_id", user.ID)
+}
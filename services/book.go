@@ -0,0 +1,147 @@
+package services
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+// 图书管理错误
+var (
+	ErrBookNotFound          = errors.New("图书不存在")
+	ErrISBNExists            = errors.New("ISBN已存在")
+	ErrQuantityBelowBorrowed = errors.New("总数量不能小于已借出数量")
+	ErrBookOnLoan            = errors.New("图书不可删除")
+)
+
+// BookChanges 编辑图书的字段（空字符串或0表示不修改）
+type BookChanges struct {
+	Title         string
+	Author        string
+	ISBN          string
+	Category      string
+	TotalQuantity int
+	Description   string
+}
+
+// BookService 图书服务
+type BookService struct {
+	books repository.BookRepo
+	loans repository.LoanRepo
+}
+
+// NewBookService 创建图书服务
+func NewBookService(books repository.BookRepo, loans repository.LoanRepo) *BookService {
+	return &BookService{books: books, loans: loans}
+}
+
+// Get 获取图书，不存在时返回ErrBookNotFound
+func (s *BookService) Get(ctx context.Context, id uint) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, id)
+	if err == repository.ErrNotFound {
+		return nil, ErrBookNotFound
+	}
+	return book, err
+}
+
+// Search 分页搜索图书
+func (s *BookService) Search(ctx context.Context, filter repository.BookFilter) ([]models.Book, int64, error) {
+	return s.books.Search(ctx, filter)
+}
+
+// Create 添加图书，可借数量等于总数量；ISBN已存在时返回ErrISBNExists
+func (s *BookService) Create(ctx context.Context, book *models.Book) error {
+	if err := s.checkISBN(ctx, book.ISBN, 0); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	book.AvailableQuantity = book.TotalQuantity
+	book.CreateTime = now
+	book.UpdateTime = now
+	return s.books.Create(ctx, book)
+}
+
+// Update 编辑图书
+// 修改总数量时不能小于已借出数量，可借数量 = 总数量 - 已借出数量
+func (s *BookService) Update(ctx context.Context, id uint, changes BookChanges) (*models.Book, error) {
+	book, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果修改了ISBN，检查新ISBN是否已存在
+	if changes.ISBN != "" && changes.ISBN != book.ISBN {
+		if err := s.checkISBN(ctx, changes.ISBN, book.ID); err != nil {
+			return nil, err
+		}
+		book.ISBN = changes.ISBN
+	}
+
+	if changes.Title != "" {
+		book.Title = changes.Title
+	}
+	if changes.Author != "" {
+		book.Author = changes.Author
+	}
+	if changes.Category != "" {
+		book.Category = changes.Category
+	}
+	if changes.Description != "" {
+		book.Description = changes.Description
+	}
+
+	if changes.TotalQuantity > 0 {
+		borrowedCount, err := s.loans.CountOpenByBook(ctx, book.ID)
+		if err != nil {
+			return nil, err
+		}
+		if changes.TotalQuantity < int(borrowedCount) {
+			return nil, ErrQuantityBelowBorrowed
+		}
+		book.TotalQuantity = changes.TotalQuantity
+		book.AvailableQuantity = changes.TotalQuantity - int(borrowedCount)
+	}
+
+	book.UpdateTime = time.Now()
+	if err := s.books.Save(ctx, book); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// Delete 删除图书，存在未归还的借阅记录时返回ErrBookOnLoan
+func (s *BookService) Delete(ctx context.Context, id uint) error {
+	borrowedCount, err := s.loans.CountOpenByBook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if borrowedCount > 0 {
+		return ErrBookOnLoan
+	}
+	return s.books.Delete(ctx, id)
+}
+
+// SetCover 设置图书封面地址，空字符串表示删除封面
+func (s *BookService) SetCover(ctx context.Context, book *models.Book, imageURL string) error {
+	book.CoverImageURL = imageURL
+	book.UpdateTime = time.Now()
+	return s.books.Save(ctx, book)
+}
+
+// checkISBN 检查ISBN是否已被其他图书使用
+func (s *BookService) checkISBN(ctx context.Context, isbn string, excludeID uint) error {
+	existing, err := s.books.FindByISBN(ctx, isbn)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != excludeID {
+		return ErrISBNExists
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/repository/memory"
+	"book-manage/services"
+	"context"
+	"testing"
+)
+
+func TestBookServiceQuantityAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	books := services.NewBookService(store.Books, store.Loans)
+	loans := services.NewLoanService(store.Books, store.Loans, store.Users, nil)
+
+	user := &models.User{Email: "reader@example.com"}
+	store.Users.Create(ctx, user)
+	book := &models.Book{Title: "Go", Author: "A", ISBN: "9780000000001", Category: "cs", TotalQuantity: 3}
+	if err := books.Create(ctx, book); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if book.AvailableQuantity != 3 {
+		t.Errorf("available = %d, want 3", book.AvailableQuantity)
+	}
+	if err := books.Create(ctx, &models.Book{ISBN: book.ISBN}); err != services.ErrISBNExists {
+		t.Errorf("duplicate isbn err = %v, want ErrISBNExists", err)
+	}
+
+	if _, err := loans.Borrow(ctx, user.ID, book.ID); err != nil {
+		t.Fatalf("borrow: %v", err)
+	}
+
+	// 总数量不能小于已借出数量，可借数量 = 总数量 - 已借出数量
+	updated, err := books.Update(ctx, book.ID, services.BookChanges{TotalQuantity: 5, Title: "Go 2"})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.AvailableQuantity != 4 || updated.Title != "Go 2" || updated.Author != "A" {
+		t.Errorf("updated = %+v", updated)
+	}
+	if _, err := books.Update(ctx, book.ID, services.BookChanges{TotalQuantity: 0}); err != nil {
+		t.Errorf("update without quantity: %v", err)
+	}
+	if _, err := books.Update(ctx, 999, services.BookChanges{}); err != services.ErrBookNotFound {
+		t.Errorf("update missing err = %v, want ErrBookNotFound", err)
+	}
+
+	if err := books.Delete(ctx, book.ID); err != services.ErrBookOnLoan {
+		t.Errorf("delete on loan err = %v, want ErrBookOnLoan", err)
+	}
+	if err := loans.Return(ctx, user.ID, book.ID); err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if err := books.Delete(ctx, book.ID); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+
+	_, total, err := books.Search(ctx, repository.BookFilter{Keyword: "Go", Page: 1, Limit: 10})
+	if err != nil || total != 0 {
+		t.Errorf("search after delete: total = %d, err = %v", total, err)
+	}
+}
@@ -2,278 +2,420 @@ package services
 
 import (
 	"book-manage/config"
-	"book-manage/database"
+	"book-manage/logging"
 	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/utils"
+	"context"
+	"crypto/rand"
 	"fmt"
-	"math/rand"
-	"sync"
+	"math/big"
+	"net/url"
+	"strings"
 	"time"
+)
 
-	"github.com/resend/resend-go/v2"
+// 验证码有效期、同一邮箱+用途的重发间隔及默认最大错误次数
+const (
+	codeTTL                = 30 * time.Minute
+	codeThrottle           = time.Minute
+	defaultCodeMaxAttempts = 5
 )
 
-// EmailCode 邮箱验证码结构
-type EmailCode struct {
-	Code      string
-	Email     string
-	Action    string
-	ExpiresAt time.Time
-}
+// loginLinkAction 一次性登录链接在验证码存储中使用的用途标识
+const loginLinkAction = "login_link"
+
+// 设置密码链接（批量导入读者时发送）的用途标识及有效期
+const (
+	setPasswordAction = "set_password"
+	setPasswordTTL    = 7 * 24 * time.Hour
+)
 
 // EmailService 邮箱服务
 type EmailService struct {
-	codes  map[string]*EmailCode
-	mu     sync.RWMutex
-	cfg    *config.EmailConfig
-	resend *resend.Client
+	store       repository.CodeRepo
+	records     repository.CodeRecordRepo
+	templates   *TemplateService
+	maxAttempts int
+	cfg         *config.EmailConfig
+	outbox      *OutboxService
 }
 
-var emailService *EmailService
-var once sync.Once
+var emailLog = logging.For("email")
 
-// InitEmailService 初始化邮箱服务
-func InitEmailService(cfg *config.EmailConfig) {
-	once.Do(func() {
-		var client *resend.Client
-		if cfg != nil && cfg.SMTPPassword != "" {
-			// 使用 Resend API Key 初始化客户端
-			client = resend.NewClient(cfg.SMTPPassword)
-		}
-
-		emailService = &EmailService{
-			codes:  make(map[string]*EmailCode),
-			cfg:    cfg,
-			resend: client,
-		}
-		// 启动清理goroutine，定期清理过期验证码
-		go emailService.cleanupExpiredCodes()
-	})
+// NewEmailService 创建邮箱服务
+// outbox 负责异步发送邮件（失败自动重试），templates 负责渲染邮件内容
+// store 负责验证码的限流、过期和校验，多实例部署时需为共享存储；records 保存供管理员查看的验证码记录
+// maxAttempts 为单个验证码允许的错误次数，超过后验证码作废
+func NewEmailService(cfg *config.EmailConfig, outbox *OutboxService, templates *TemplateService,
+	store repository.CodeRepo, records repository.CodeRecordRepo, maxAttempts int) *EmailService {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultCodeMaxAttempts
+	}
+	return &EmailService{
+		store:       store,
+		records:     records,
+		templates:   templates,
+		maxAttempts: maxAttempts,
+		cfg:         cfg,
+		outbox:      outbox,
+	}
 }
 
-// GetEmailService 获取邮箱服务实例（单例）
-func GetEmailService() *EmailService {
-	return emailService
+// GenerateCode 生成6位数字验证码（使用crypto/rand）
+func (s *EmailService) GenerateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
-// GenerateCode 生成6位数字验证码
-func (s *EmailService) GenerateCode() string {
-	rand.Seed(time.Now().UnixNano())
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+// MaskCode 遮盖验证码，仅保留首尾各一位，如 1****6
+func MaskCode(code string) string {
+	if len(code) <= 2 {
+		return "******"
+	}
+	return code[:1] + "****" + code[len(code)-1:]
 }
 
-// SendCode 发送验证码
-func (s *EmailService) SendCode(email, action string) (string, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// 检查是否在1分钟内重复请求
-	key := fmt.Sprintf("%s:%s", email, action)
-	if existingCode, exists := s.codes[key]; exists {
-		if time.Since(existingCode.ExpiresAt.Add(-29*time.Minute)) < time.Minute {
-			return "", fmt.Errorf("请求过于频繁，请稍后再试")
-		}
+// SendCode 发送验证码，locale 为邮件语言（zh-CN、en）
+func (s *EmailService) SendCode(ctx context.Context, email, action, locale string) (string, error) {
+	code, err := s.GenerateCode()
+	if err != nil {
+		emailLog.ErrorContext(ctx, "生成验证码失败", "error", err)
+		return "", fmt.Errorf("发送验证码失败")
 	}
+	expiresAt := time.Now().Add(codeTTL)
 
-	code := s.GenerateCode()
-	expiresAt := time.Now().Add(30 * time.Minute)
-	
-	// 保存到内存缓存（用于快速验证）
-	s.codes[key] = &EmailCode{
-		Code:      code,
-		Email:     email,
-		Action:    action,
-		ExpiresAt: expiresAt,
+	// 保存到验证码存储（限流判断在存储中原子完成，旧验证码同时作废）
+	if err := s.store.Save(ctx, email, action, code, codeTTL, codeThrottle); err != nil {
+		if err == repository.ErrCodeThrottled {
+			return "", err
+		}
+		emailLog.ErrorContext(ctx, "保存验证码失败", "error", err, "action", action)
+		return "", fmt.Errorf("发送验证码失败")
 	}
 
-	// 保存到数据库（用于管理员查看）
-	db := database.GetDB()
+	// 保存到数据库（用于管理员查看，仅保存遮盖后的验证码，同时作废旧记录）
 	codeRecord := models.EmailCodeRecord{
 		Email:     email,
-		Code:      code,
+		Code:      MaskCode(code),
 		Action:    action,
 		ExpiresAt: expiresAt,
 		IsUsed:    false,
 	}
-	if err := db.Create(&codeRecord).Error; err != nil {
-		// 数据库保存失败不影响验证码生成，只记录错误
-		fmt.Printf("[Email Service] 保存验证码到数据库失败: %v\n", err)
+	if err := s.records.Create(ctx, &codeRecord); err != nil {
+		// 审计记录保存失败不影响验证码使用，只记录错误
+		emailLog.ErrorContext(ctx, "保存验证码记录失败", "error", err, "action", action)
 	}
 
-	// 立即打印验证码（用于调试）
-	fmt.Printf("[Email Service] ========== 验证码生成 ==========\n")
-	fmt.Printf("[Email Service] 邮箱: %s\n", email)
-	fmt.Printf("[Email Service] 操作: %s\n", action)
-	fmt.Printf("[Email Service] 验证码: %s\n", code)
-	fmt.Printf("[Email Service] 有效期: 30分钟\n")
-	fmt.Printf("[Email Service] ================================\n")
-
-	// 发送真实邮件
-	if s.resend != nil {
-		fmt.Printf("[Email Service] 开始使用 Resend 发送验证码到 %s (action: %s)\n", email, action)
-		err := s.sendEmailViaResend(email, action, code)
+	data := map[string]interface{}{
+		"Code":           code,
+		"ExpiresMinutes": int(codeTTL.Minutes()),
+		"Email":          email,
+	}
+	// 登录验证码邮件同时附带一次性登录链接
+	if action == "login" {
+		loginURL, err := s.createLoginLink(ctx, email)
 		if err != nil {
-			// 如果发送失败，仍然保留验证码，但记录错误
-			fmt.Printf("[Email Service] 发送邮件失败: %v，验证码: %s (用户仍可使用此验证码)\n", err, code)
+			emailLog.ErrorContext(ctx, "生成登录链接失败", "error", err)
+			return "", fmt.Errorf("发送验证码失败")
 		}
-	} else {
-		// 如果未配置邮件服务，打印到控制台（开发模式）
-		fmt.Printf("[Email Service] 邮件服务未配置，验证码打印到控制台: %s (%s): %s (有效期30分钟)\n", email, action, code)
+		data["LoginURL"] = loginURL
+	}
+
+	// 写入发件箱，由后台worker异步发送
+	if err := s.sendCodeEmail(ctx, email, action, NormalizeLocale(locale), data); err != nil {
+		emailLog.ErrorContext(ctx, "验证码邮件入队失败", "error", err, "email", email, "action", action)
+		return "", fmt.Errorf("发送验证码失败")
 	}
 
 	return code, nil
 }
 
-// sendEmailViaResend 使用 Resend 发送邮件
-func (s *EmailService) sendEmailViaResend(toEmail, action, code string) error {
-	if s.resend == nil {
-		return fmt.Errorf("Resend 客户端未初始化")
-	}
-
-	// 根据action确定邮件主题和内容
-	var subject, htmlContent string
+// codeTemplateKey 获取验证码用途对应的邮件模板
+func codeTemplateKey(action string) string {
 	switch action {
 	case "register":
-		subject = "图书管理系统 - 注册验证码"
-		htmlContent = fmt.Sprintf(`
-			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
-				<h2 style="color: #333;">欢迎注册图书管理系统</h2>
-				<p>您的注册验证码为：</p>
-				<div style="background-color: #f5f5f5; padding: 20px; text-align: center; margin: 20px 0;">
-					<h1 style="color: #007bff; font-size: 32px; margin: 0;">%s</h1>
-				</div>
-				<p>验证码有效期为 30 分钟，请勿泄露给他人。</p>
-				<p style="color: #999; font-size: 12px; margin-top: 30px;">此邮件由系统自动发送，请勿回复。</p>
-			</div>
-		`, code)
+		return TemplateCodeRegister
 	case "forget":
-		subject = "图书管理系统 - 密码重置验证码"
-		htmlContent = fmt.Sprintf(`
-			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
-				<h2 style="color: #333;">密码重置验证码</h2>
-				<p>您正在重置密码，验证码为：</p>
-				<div style="background-color: #f5f5f5; padding: 20px; text-align: center; margin: 20px 0;">
-					<h1 style="color: #007bff; font-size: 32px; margin: 0;">%s</h1>
-				</div>
-				<p>验证码有效期为 30 分钟，请勿泄露给他人。</p>
-				<p>如非本人操作，请忽略此邮件。</p>
-				<p style="color: #999; font-size: 12px; margin-top: 30px;">此邮件由系统自动发送，请勿回复。</p>
-			</div>
-		`, code)
+		return TemplateCodeForget
+	case "login":
+		return TemplateCodeLogin
 	default:
-		subject = "图书管理系统 - 验证码"
-		htmlContent = fmt.Sprintf(`
-			<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
-				<h2 style="color: #333;">验证码</h2>
-				<p>您的验证码为：</p>
-				<div style="background-color: #f5f5f5; padding: 20px; text-align: center; margin: 20px 0;">
-					<h1 style="color: #007bff; font-size: 32px; margin: 0;">%s</h1>
-				</div>
-				<p>验证码有效期为 30 分钟，请勿泄露给他人。</p>
-				<p style="color: #999; font-size: 12px; margin-top: 30px;">此邮件由系统自动发送，请勿回复。</p>
-			</div>
-		`, code)
-	}
-
-	// 使用 Resend 发送邮件
-	// 发件人邮箱使用 Resend 的域名或者配置的邮箱
-	fromEmail := "noreply@yourdomain.com"
-	if s.cfg != nil && s.cfg.SMTPUser != "" {
-		fromEmail = s.cfg.SMTPUser
-	}
-
-	params := &resend.SendEmailRequest{
-		From:    fromEmail,
-		To:      []string{toEmail},
-		Subject: subject,
-		Html:    htmlContent,
+		return TemplateCodeDefault
+	}
+}
+
+// sendCodeEmail 使用邮件模板发送验证码邮件
+func (s *EmailService) sendCodeEmail(ctx context.Context, toEmail, action, locale string, data map[string]interface{}) error {
+	templateKey := codeTemplateKey(action)
+	rendered, err := s.templates.Render(ctx, templateKey, locale, data)
+	if err != nil {
+		return fmt.Errorf("渲染邮件模板失败: %v", err)
 	}
 
-	sendStart := time.Now()
-	fmt.Printf("[Email Service] [Resend] 准备发送邮件到 %s\n", toEmail)
+	return s.Send(ctx, templateKey, &Message{
+		To:      []string{toEmail},
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
+
+// Send 将邮件写入发件箱异步发送，未指定发件人时使用配置的发件人
+// category 为邮件类型（通常为模板标识），用于管理员查看发送状态
+func (s *EmailService) Send(ctx context.Context, category string, msg *Message) error {
+	if msg.From == "" {
+		msg.From = fromAddress(s.cfg)
+	}
 
-	_, err := s.resend.Emails.Send(params)
+	item, err := s.outbox.Enqueue(ctx, category, msg)
 	if err != nil {
-		fmt.Printf("[Email Service] [Resend] 发送失败 (耗时: %v): %v\n", time.Since(sendStart), err)
-		return fmt.Errorf("发送邮件失败: %v", err)
+		return err
 	}
 
-	fmt.Printf("[Email Service] [Resend] 发送成功 (耗时: %v)\n", time.Since(sendStart))
-	fmt.Printf("[Email Service] 成功发送验证码邮件到 %s (%s)\n", toEmail, action)
+	emailLog.InfoContext(ctx, "邮件已写入发件箱", "outbox_id", item.ID, "category", category, "mailer", s.outbox.MailerName())
 	return nil
 }
 
-// VerifyCode 验证验证码
-func (s *EmailService) VerifyCode(email, action, code string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	key := fmt.Sprintf("%s:%s", email, action)
-	storedCode, exists := s.codes[key]
-	
-	// 先从内存缓存验证
-	if exists {
-		// 检查是否过期
-		if time.Now().After(storedCode.ExpiresAt) {
-			delete(s.codes, key)
-			return false
-		}
+// SendEmailChangedNotice 通知旧邮箱账户登录邮箱已变更
+func (s *EmailService) SendEmailChangedNotice(ctx context.Context, oldEmail, newEmail, locale string, changedAt time.Time) error {
+	rendered, err := s.templates.Render(ctx, TemplateEmailChanged, NormalizeLocale(locale), map[string]interface{}{
+		"OldEmail":  oldEmail,
+		"NewEmail":  newEmail,
+		"ChangedAt": changedAt.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return fmt.Errorf("渲染邮件模板失败: %v", err)
+	}
 
-		// 验证码正确后删除内存缓存
-		if storedCode.Code == code {
-			delete(s.codes, key)
-			
-			// 更新数据库记录为已使用
-			db := database.GetDB()
-			now := time.Now()
-			db.Model(&models.EmailCodeRecord{}).
-				Where("email = ? AND code = ? AND action = ? AND is_used = ?", email, code, action, false).
-				Updates(map[string]interface{}{
-					"is_used": true,
-					"used_at": &now,
-				})
-			
-			return true
-		}
+	return s.Send(ctx, TemplateEmailChanged, &Message{
+		To:      []string{oldEmail},
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
+
+// SendInvitation 发送注册邀请邮件
+func (s *EmailService) SendInvitation(ctx context.Context, invitation *models.Invitation, token, inviteURL, locale string) error {
+	rendered, err := s.templates.Render(ctx, TemplateInvitation, NormalizeLocale(locale), map[string]interface{}{
+		"Email":     invitation.Email,
+		"Token":     token,
+		"InviteURL": inviteURL,
+		"ExpiresAt": invitation.ExpiresAt.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return fmt.Errorf("渲染邮件模板失败: %v", err)
+	}
+
+	return s.Send(ctx, TemplateInvitation, &Message{
+		To:      []string{invitation.Email},
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
+
+// CreateSetPasswordToken 生成设置密码链接中的一次性token（有效期7天）
+func (s *EmailService) CreateSetPasswordToken(ctx context.Context, email string) (string, error) {
+	token, err := randomHex(24)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.Save(ctx, email, setPasswordAction, token, setPasswordTTL, codeThrottle); err != nil {
+		return "", err
+	}
+
+	// 保存审计记录（仅保存遮盖后的token）
+	codeRecord := models.EmailCodeRecord{
+		Email:     email,
+		Code:      MaskCode(token),
+		Action:    setPasswordAction,
+		ExpiresAt: time.Now().Add(setPasswordTTL),
 	}
+	if err := s.records.Create(ctx, &codeRecord); err != nil {
+		emailLog.ErrorContext(ctx, "保存设置密码链接记录失败", "error", err)
+	}
+	return token, nil
+}
 
-	// 如果内存缓存中没有，尝试从数据库验证（兼容性）
-	db := database.GetDB()
-	var codeRecord models.EmailCodeRecord
-	err := db.Where("email = ? AND code = ? AND action = ? AND is_used = ?", email, code, action, false).
-		First(&codeRecord).Error
-	
+// VerifySetPasswordToken 校验设置密码token，成功后token立即失效
+// token为48位随机十六进制串，无法穷举，不限制错误次数，避免他人用错误token使链接作废
+func (s *EmailService) VerifySetPasswordToken(ctx context.Context, email, token string) bool {
+	result, err := s.store.Verify(ctx, email, setPasswordAction, token, 0)
 	if err != nil {
+		emailLog.ErrorContext(ctx, "校验设置密码链接失败", "error", err)
 		return false
 	}
+	if result != repository.VerifyOK {
+		return false
+	}
+
+	s.records.MarkUsed(ctx, email, setPasswordAction, time.Now())
+	return true
+}
+
+// SendWelcome 发送欢迎邮件（批量导入读者时使用）
+// setPasswordURL 非空时邮件中包含设置密码链接，否则包含初始密码
+func (s *EmailService) SendWelcome(ctx context.Context, user *models.User, password, setPasswordURL, locale string) error {
+	rendered, err := s.templates.Render(ctx, TemplateWelcome, NormalizeLocale(locale), map[string]interface{}{
+		"Email":           user.Email,
+		"Name":            user.Name,
+		"Password":        password,
+		"SetPasswordURL":  setPasswordURL,
+		"LinkExpiresDays": int(setPasswordTTL.Hours() / 24),
+	})
+	if err != nil {
+		return fmt.Errorf("渲染邮件模板失败: %v", err)
+	}
+
+	return s.Send(ctx, TemplateWelcome, &Message{
+		To:      []string{user.Email},
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	})
+}
 
-	// 检查是否过期
-	if time.Now().After(codeRecord.ExpiresAt) {
+// VerifyCode 验证验证码
+// 校验成功后验证码立即失效，并将管理员审计记录标记为已使用；
+// 错误次数达到上限后验证码作废，需重新获取
+func (s *EmailService) VerifyCode(ctx context.Context, email, action, code string) bool {
+	result, err := s.store.Verify(ctx, email, action, code, s.maxAttempts)
+	if err != nil {
+		emailLog.ErrorContext(ctx, "校验验证码失败", "error", err, "action", action)
 		return false
 	}
 
-	// 标记为已使用
 	now := time.Now()
-	db.Model(&codeRecord).Updates(map[string]interface{}{
-		"is_used": true,
-		"used_at": &now,
-	})
+	switch result {
+	case repository.VerifyOK:
+		// 更新数据库记录为已使用
+		s.records.MarkUsed(ctx, email, action, now)
+		// 使用验证码登录后，同一封邮件中的登录链接随之失效
+		if action == "login" {
+			s.store.Delete(ctx, email, loginLinkAction)
+		}
+		return true
+	case repository.VerifyLocked:
+		emailLog.WarnContext(ctx, "验证码错误次数过多，已作废", "email", email, "action", action)
+		s.records.Invalidate(ctx, email, action, now)
+	}
 
-	return true
+	return false
+}
+
+// createLoginLink 生成一次性登录链接，未配置登录链接地址时返回空字符串
+// 链接中的token由服务端签名，其中的随机nonce保存在验证码存储中，使用一次后失效
+func (s *EmailService) createLoginLink(ctx context.Context, email string) (string, error) {
+	if s.cfg == nil || s.cfg.LoginLinkURL == "" {
+		return "", nil
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.Save(ctx, email, loginLinkAction, nonce, codeTTL, codeThrottle); err != nil {
+		return "", err
+	}
+	token, err := utils.GenerateLoginLinkToken(email, nonce, codeTTL)
+	if err != nil {
+		return "", err
+	}
+
+	separator := "?"
+	if strings.Contains(s.cfg.LoginLinkURL, "?") {
+		separator = "&"
+	}
+	return s.cfg.LoginLinkURL + separator + "token=" + url.QueryEscape(token), nil
+}
+
+// VerifyLoginLink 校验一次性登录链接，成功时返回对应的邮箱
+// 链接使用后立即失效，同一封邮件中的登录验证码也随之失效
+func (s *EmailService) VerifyLoginLink(ctx context.Context, token string) (string, bool) {
+	claims, err := utils.ParseLoginLinkToken(token)
+	if err != nil {
+		return "", false
+	}
+
+	result, err := s.store.Verify(ctx, claims.Email, loginLinkAction, claims.Nonce, s.maxAttempts)
+	if err != nil {
+		emailLog.ErrorContext(ctx, "校验登录链接失败", "error", err)
+		return "", false
+	}
+	if result != repository.VerifyOK {
+		return "", false
+	}
+
+	s.store.Delete(ctx, claims.Email, "login")
+	s.records.MarkUsed(ctx, claims.Email, "login", time.Now())
+	return claims.Email, true
 }
 
-// cleanupExpiredCodes 清理过期的验证码
-func (s *EmailService) cleanupExpiredCodes() {
+// CodeStats 验证码统计信息
+type CodeStats struct {
+	TotalCount       int64 `json:"total_count"`
+	UsedCount        int64 `json:"used_count"`
+	UnusedCount      int64 `json:"unused_count"`
+	ExpiredCount     int64 `json:"expired_count"`
+	RegisterCount    int64 `json:"register_count"`
+	ForgetCount      int64 `json:"forget_count"`
+	LoginCount       int64 `json:"login_count"`
+	ChangeEmailCount int64 `json:"change_email_count"`
+	SetPasswordCount int64 `json:"set_password_count"`
+}
+
+// ListCodeRecords 分页查询验证码记录（仅审计用途），按创建时间倒序，验证码只返回遮盖后的形式
+func (s *EmailService) ListCodeRecords(ctx context.Context, filter repository.CodeRecordFilter) ([]models.EmailCodeRecord, int64, error) {
+	records, total, err := s.records.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range records {
+		if !strings.Contains(records[i].Code, "*") {
+			records[i].Code = MaskCode(records[i].Code)
+		}
+	}
+	return records, total, nil
+}
+
+// CodeStats 验证码统计（总数、使用情况、过期数量及各用途数量）
+func (s *EmailService) CodeStats(ctx context.Context) (*CodeStats, error) {
+	counts, err := s.records.Stats(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &CodeStats{
+		TotalCount:       counts.Total,
+		UsedCount:        counts.Used,
+		UnusedCount:      counts.Unused,
+		ExpiredCount:     counts.Expired,
+		RegisterCount:    counts.ByAction["register"],
+		ForgetCount:      counts.ByAction["forget"],
+		LoginCount:       counts.ByAction["login"],
+		ChangeEmailCount: counts.ByAction["change_email"],
+		SetPasswordCount: counts.ByAction[setPasswordAction],
+	}, nil
+}
+
+// CleanupExpiredCodes 后台worker：定期清理过期的验证码，ctx 取消后返回
+func (s *EmailService) CleanupExpiredCodes(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-		for key, code := range s.codes {
-			if now.After(code.ExpiresAt) {
-				delete(s.codes, key)
-			}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.store.Cleanup(ctx); err != nil {
+			emailLog.Error("清理过期验证码失败", "error", err)
 		}
-		s.mu.Unlock()
 	}
 }
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// 支持的语言
+const (
+	LocaleZhCN    = "zh-CN"
+	LocaleEn      = "en"
+	DefaultLocale = LocaleZhCN
+)
+
+// 邮件模板标识
+const (
+	TemplateCodeRegister = "code_register" // 注册验证码
+	TemplateCodeForget   = "code_forget"   // 密码重置验证码
+	TemplateCodeLogin    = "code_login"    // 登录验证码及一次性登录链接
+	TemplateCodeDefault  = "code_default"  // 其他用途验证码
+	TemplateEmailChanged = "email_changed" // 邮箱变更通知（发送到旧邮箱）
+	TemplateInvitation   = "invitation"    // 注册邀请
+	TemplateWelcome      = "welcome"       // 批量导入读者的欢迎邮件
+)
+
+// SupportedLocales 支持的语言列表
+var SupportedLocales = []string{LocaleZhCN, LocaleEn}
+
+// EmailTemplateContent 邮件模板内容
+type EmailTemplateContent struct {
+	Key      string `json:"key"`
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body"`
+	BuiltIn  bool   `json:"built_in"` // 是否为系统内置模板（未被管理员覆盖）
+}
+
+// RenderedEmail 渲染后的邮件内容
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// codeEmailHTML 验证码邮件HTML布局
+const codeEmailHTML = `<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+	<h2 style="color: #333;">%s</h2>
+	<p>%s</p>
+	<div style="background-color: #f5f5f5; padding: 20px; text-align: center; margin: 20px 0;">
+		<h1 style="color: #007bff; font-size: 32px; margin: 0;">{{.Code}}</h1>
+	</div>
+	<p>%s</p>%s
+	<p style="color: #999; font-size: 12px; margin-top: 30px;">%s</p>
+</div>`
+
+// codeEmailText 验证码邮件纯文本布局
+const codeEmailText = `%s
+
+%s{{.Code}}
+
+%s%s
+%s`
+
+// noticeEmailHTML 通知邮件HTML布局
+const noticeEmailHTML = `<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+	<h2 style="color: #333;">%s</h2>
+	<p>%s</p>
+	<p>%s</p>
+	<p style="color: #999; font-size: 12px; margin-top: 30px;">%s</p>
+</div>`
+
+// noticeEmailText 通知邮件纯文本布局
+const noticeEmailText = `%s
+
+%s
+
+%s
+%s`
+
+// noticeTemplate 根据文案生成通知邮件模板
+func noticeTemplate(key, locale, subject, title, body, extra, footer string) EmailTemplateContent {
+	return EmailTemplateContent{
+		Key:      key,
+		Locale:   locale,
+		Subject:  subject,
+		HTMLBody: fmt.Sprintf(noticeEmailHTML, title, body, extra, footer),
+		TextBody: fmt.Sprintf(noticeEmailText, title, body, extra, footer),
+		BuiltIn:  true,
+	}
+}
+
+// codeTemplate 根据文案生成验证码邮件模板
+func codeTemplate(key, locale, subject, title, intro, validity, extra, footer string) EmailTemplateContent {
+	extraHTML, extraText := "", ""
+	if extra != "" {
+		extraHTML = "\n\t<p>" + extra + "</p>"
+		extraText = "\n" + extra
+	}
+	return buildCodeTemplate(key, locale, subject, title, intro, validity, extraHTML, extraText, footer)
+}
+
+// loginCodeTemplate 根据文案生成登录验证码邮件模板，配置了登录链接时附带一次性登录链接
+func loginCodeTemplate(locale, subject, title, intro, validity, linkIntro, linkLabel, extra, footer string) EmailTemplateContent {
+	extraHTML := "{{if .LoginURL}}\n\t<p>" + linkIntro + "</p>\n\t<p><a href=\"{{.LoginURL}}\" style=\"color: #007bff;\">" + linkLabel + "</a></p>{{end}}\n\t<p>" + extra + "</p>"
+	extraText := "{{if .LoginURL}}\n" + linkIntro + "\n{{.LoginURL}}\n{{end}}\n" + extra
+	return buildCodeTemplate(TemplateCodeLogin, locale, subject, title, intro, validity, extraHTML, extraText, footer)
+}
+
+// buildCodeTemplate 使用验证码邮件布局生成模板，extraHTML/extraText 为附加内容
+func buildCodeTemplate(key, locale, subject, title, intro, validity, extraHTML, extraText, footer string) EmailTemplateContent {
+	return EmailTemplateContent{
+		Key:      key,
+		Locale:   locale,
+		Subject:  subject,
+		HTMLBody: fmt.Sprintf(codeEmailHTML, title, intro, validity, extraHTML, footer),
+		TextBody: fmt.Sprintf(codeEmailText, title, intro, validity, extraText, footer),
+		BuiltIn:  true,
+	}
+}
+
+// builtinTemplates 系统内置模板
+var builtinTemplates = []EmailTemplateContent{
+	codeTemplate(TemplateCodeRegister, LocaleZhCN, "{{.AppName}} - 注册验证码", "欢迎注册{{.AppName}}", "您的注册验证码为：",
+		"验证码有效期为 {{.ExpiresMinutes}} 分钟，请勿泄露给他人。", "", "此邮件由系统自动发送，请勿回复。"),
+	codeTemplate(TemplateCodeForget, LocaleZhCN, "{{.AppName}} - 密码重置验证码", "密码重置验证码", "您正在重置密码，验证码为：",
+		"验证码有效期为 {{.ExpiresMinutes}} 分钟，请勿泄露给他人。", "如非本人操作，请忽略此邮件。", "此邮件由系统自动发送，请勿回复。"),
+	loginCodeTemplate(LocaleZhCN, "{{.AppName}} - 登录验证码", "登录验证码", "您正在登录{{.AppName}}，验证码为：",
+		"验证码和登录链接有效期为 {{.ExpiresMinutes}} 分钟，请勿泄露给他人。", "也可以点击下方链接直接登录（链接仅可使用一次）：", "立即登录",
+		"如非本人操作，请忽略此邮件。", "此邮件由系统自动发送，请勿回复。"),
+	codeTemplate(TemplateCodeDefault, LocaleZhCN, "{{.AppName}} - 验证码", "验证码", "您的验证码为：",
+		"验证码有效期为 {{.ExpiresMinutes}} 分钟，请勿泄露给他人。", "", "此邮件由系统自动发送，请勿回复。"),
+	codeTemplate(TemplateCodeRegister, LocaleEn, "{{.AppName}} - Registration code", "Welcome to {{.AppName}}", "Your registration code is:",
+		"The code expires in {{.ExpiresMinutes}} minutes. Do not share it with anyone.", "", "This is an automated message, please do not reply."),
+	codeTemplate(TemplateCodeForget, LocaleEn, "{{.AppName}} - Password reset code", "Password reset code", "You are resetting your password. Your code is:",
+		"The code expires in {{.ExpiresMinutes}} minutes. Do not share it with anyone.", "If you did not request this, please ignore this email.", "This is an automated message, please do not reply."),
+	loginCodeTemplate(LocaleEn, "{{.AppName}} - Sign-in code", "Sign-in code", "You are signing in to {{.AppName}}. Your code is:",
+		"The code and sign-in link expire in {{.ExpiresMinutes}} minutes. Do not share them with anyone.", "Or sign in directly with the link below (it can only be used once):", "Sign in",
+		"If you did not request this, please ignore this email.", "This is an automated message, please do not reply."),
+	codeTemplate(TemplateCodeDefault, LocaleEn, "{{.AppName}} - Verification code", "Verification code", "Your verification code is:",
+		"The code expires in {{.ExpiresMinutes}} minutes. Do not share it with anyone.", "", "This is an automated message, please do not reply."),
+	noticeTemplate(TemplateEmailChanged, LocaleZhCN, "{{.AppName}} - 登录邮箱已变更", "登录邮箱已变更",
+		"您的账户登录邮箱已于 {{.ChangedAt}} 由 {{.OldEmail}} 变更为 {{.NewEmail}}，此前的登录状态均已失效。",
+		"如非本人操作，请立即联系管理员。", "此邮件由系统自动发送，请勿回复。"),
+	noticeTemplate(TemplateEmailChanged, LocaleEn, "{{.AppName}} - Sign-in email changed", "Sign-in email changed",
+		"The sign-in email of your account was changed from {{.OldEmail}} to {{.NewEmail}} at {{.ChangedAt}}. All existing sessions have been signed out.",
+		"If you did not make this change, please contact an administrator immediately.", "This is an automated message, please do not reply."),
+	noticeTemplate(TemplateInvitation, LocaleZhCN, "{{.AppName}} - 注册邀请", "您已受邀注册{{.AppName}}",
+		"请使用邮箱 {{.Email}} 在 {{.ExpiresAt}} 前完成注册，邀请仅可使用一次。",
+		"{{if .InviteURL}}注册链接：{{.InviteURL}}{{else}}注册时请填写邀请码：{{.Token}}{{end}}", "此邮件由系统自动发送，请勿回复。"),
+	noticeTemplate(TemplateInvitation, LocaleEn, "{{.AppName}} - Invitation to register", "You are invited to join {{.AppName}}",
+		"Please register with {{.Email}} before {{.ExpiresAt}}. The invitation can only be used once.",
+		"{{if .InviteURL}}Register here: {{.InviteURL}}{{else}}Invitation code: {{.Token}}{{end}}", "This is an automated message, please do not reply."),
+	noticeTemplate(TemplateWelcome, LocaleZhCN, "欢迎使用{{.AppName}}", "欢迎使用{{.AppName}}",
+		"{{if .Name}}{{.Name}}，{{end}}您好！图书馆已为您开通账户，登录邮箱为 {{.Email}}。",
+		"{{if .SetPasswordURL}}请在 {{.LinkExpiresDays}} 天内通过以下链接设置密码：{{.SetPasswordURL}}{{else}}初始密码为 {{.Password}}，请登录后尽快修改密码。{{end}}", "此邮件由系统自动发送，请勿回复。"),
+	noticeTemplate(TemplateWelcome, LocaleEn, "Welcome to {{.AppName}}", "Welcome to {{.AppName}}",
+		"{{if .Name}}Hi {{.Name}}, y{{else}}Y{{end}}our library account has been created. Sign in with {{.Email}}.",
+		"{{if .SetPasswordURL}}Please set your password within {{.LinkExpiresDays}} days: {{.SetPasswordURL}}{{else}}Your initial password is {{.Password}}. Please change it after signing in.{{end}}", "This is an automated message, please do not reply."),
+}
+
+// templateSamples 各模板预览时使用的示例数据
+var templateSamples = map[string]map[string]interface{}{
+	TemplateCodeRegister: {"Code": "123456", "ExpiresMinutes": 30, "Email": "user@example.com"},
+	TemplateCodeForget:   {"Code": "123456", "ExpiresMinutes": 30, "Email": "user@example.com"},
+	TemplateCodeLogin:    {"Code": "123456", "ExpiresMinutes": 30, "Email": "user@example.com", "LoginURL": "https://example.com/login/link?token=xxxx"},
+	TemplateCodeDefault:  {"Code": "123456", "ExpiresMinutes": 30, "Email": "user@example.com"},
+	TemplateEmailChanged: {"OldEmail": "old@example.com", "NewEmail": "new@example.com", "ChangedAt": "2025-11-08 10:00:00"},
+	TemplateInvitation:   {"Email": "user@example.com", "Token": "xxxx", "InviteURL": "https://example.com/register?invite=xxxx", "ExpiresAt": "2025-11-15 10:00:00"},
+	TemplateWelcome:      {"Email": "user@example.com", "Name": "张三", "Password": "", "SetPasswordURL": "https://example.com/setPassword?email=user%40example.com&token=xxxx", "LinkExpiresDays": 7},
+}
+
+// appNames 各语言的系统名称，渲染时作为 AppName 变量
+var appNames = map[string]string{
+	LocaleZhCN: "图书管理系统",
+	LocaleEn:   "Library Management System",
+}
+
+// TemplateService 邮件模板服务
+type TemplateService struct {
+	templates repository.TemplateRepo
+}
+
+// NewTemplateService 创建邮件模板服务，templates 保存管理员自定义的模板
+func NewTemplateService(templates repository.TemplateRepo) *TemplateService {
+	return &TemplateService{templates: templates}
+}
+
+// NormalizeLocale 规范化语言标识，支持 Accept-Language 格式（如 en-US,en;q=0.9），不支持的语言返回默认语言
+func NormalizeLocale(locale string) string {
+	for _, part := range strings.Split(locale, ",") {
+		tag := strings.ToLower(strings.TrimSpace(strings.Split(part, ";")[0]))
+		switch {
+		case tag == "":
+			continue
+		case strings.HasPrefix(tag, "zh"):
+			return LocaleZhCN
+		case strings.HasPrefix(tag, "en"):
+			return LocaleEn
+		}
+	}
+	return DefaultLocale
+}
+
+// IsTemplateKey 检查模板标识是否存在
+func IsTemplateKey(key string) bool {
+	_, ok := templateSamples[key]
+	return ok
+}
+
+// IsSupportedLocale 检查语言是否支持
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// List 获取所有模板（管理员自定义的模板覆盖内置模板）
+func (s *TemplateService) List(ctx context.Context) ([]EmailTemplateContent, error) {
+	overrides, err := s.templates.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]EmailTemplateContent)
+	for _, t := range builtinTemplates {
+		merged[t.Key+"|"+t.Locale] = t
+	}
+	for _, t := range overrides {
+		merged[t.Key+"|"+t.Locale] = contentFromModel(&t)
+	}
+
+	list := make([]EmailTemplateContent, 0, len(merged))
+	for _, t := range merged {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Key != list[j].Key {
+			return list[i].Key < list[j].Key
+		}
+		return list[i].Locale < list[j].Locale
+	})
+	return list, nil
+}
+
+// Get 获取模板，查找顺序：自定义模板(locale) → 内置模板(locale) → 自定义模板(默认语言) → 内置模板(默认语言)
+func (s *TemplateService) Get(ctx context.Context, key, locale string) (*EmailTemplateContent, error) {
+	locales := []string{locale}
+	if locale != DefaultLocale {
+		locales = append(locales, DefaultLocale)
+	}
+
+	for _, l := range locales {
+		override, err := s.templates.Find(ctx, key, l)
+		if err == nil {
+			content := contentFromModel(override)
+			return &content, nil
+		}
+		if err != repository.ErrNotFound {
+			return nil, err
+		}
+		if builtin := findBuiltinTemplate(key, l); builtin != nil {
+			return builtin, nil
+		}
+	}
+
+	return nil, fmt.Errorf("email template not found: %s (%s)", key, locale)
+}
+
+// Render 使用模板渲染邮件，data 中未提供 AppName 时使用对应语言的系统名称
+func (s *TemplateService) Render(ctx context.Context, key, locale string, data map[string]interface{}) (*RenderedEmail, error) {
+	tpl, err := s.Get(ctx, key, locale)
+	if err != nil {
+		return nil, err
+	}
+	return RenderTemplate(tpl, data)
+}
+
+// Preview 使用示例数据渲染模板，content 非空时渲染未保存的模板内容
+func (s *TemplateService) Preview(ctx context.Context, key, locale string, content *EmailTemplateContent) (*RenderedEmail, error) {
+	if content == nil {
+		var err error
+		content, err = s.Get(ctx, key, locale)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		content.Key = key
+		content.Locale = locale
+	}
+	return RenderTemplate(content, templateSamples[key])
+}
+
+// Save 保存自定义模板（保存前校验模板语法）
+func (s *TemplateService) Save(ctx context.Context, content *EmailTemplateContent) error {
+	if _, err := RenderTemplate(content, templateSamples[content.Key]); err != nil {
+		return err
+	}
+
+	return s.templates.Save(ctx, &models.EmailTemplate{
+		Key:      content.Key,
+		Locale:   content.Locale,
+		Subject:  content.Subject,
+		HTMLBody: content.HTMLBody,
+		TextBody: content.TextBody,
+	})
+}
+
+// Reset 删除自定义模板，恢复为内置模板
+func (s *TemplateService) Reset(ctx context.Context, key, locale string) error {
+	return s.templates.Delete(ctx, key, locale)
+}
+
+// RenderTemplate 渲染模板内容
+// 主题和纯文本使用 text/template，HTML 使用 html/template 自动转义变量
+func RenderTemplate(tpl *EmailTemplateContent, data map[string]interface{}) (*RenderedEmail, error) {
+	vars := map[string]interface{}{"AppName": appNames[NormalizeLocale(tpl.Locale)]}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	subject, err := renderText(tpl.Key+".subject", tpl.Subject, vars)
+	if err != nil {
+		return nil, err
+	}
+	text, err := renderText(tpl.Key+".text", tpl.TextBody, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTpl, err := htmltemplate.New(tpl.Key + ".html").Option("missingkey=error").Parse(tpl.HTMLBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid html template: %w", err)
+	}
+	var html bytes.Buffer
+	if err := htmlTpl.Execute(&html, vars); err != nil {
+		return nil, fmt.Errorf("render html template failed: %w", err)
+	}
+
+	return &RenderedEmail{
+		Subject: strings.TrimSpace(subject),
+		HTML:    html.String(),
+		Text:    text,
+	}, nil
+}
+
+// renderText 渲染纯文本模板
+func renderText(name, content string, vars map[string]interface{}) (string, error) {
+	tpl, err := texttemplate.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render template %s failed: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// findBuiltinTemplate 查找内置模板（返回副本）
+func findBuiltinTemplate(key, locale string) *EmailTemplateContent {
+	for _, t := range builtinTemplates {
+		if t.Key == key && t.Locale == locale {
+			tpl := t
+			return &tpl
+		}
+	}
+	return nil
+}
+
+// contentFromModel 将数据库模板转换为模板内容
+func contentFromModel(t *models.EmailTemplate) EmailTemplateContent {
+	return EmailTemplateContent{
+		Key:      t.Key,
+		Locale:   t.Locale,
+		Subject:  t.Subject,
+		HTMLBody: t.HTMLBody,
+		TextBody: t.TextBody,
+		BuiltIn:  false,
+	}
+}
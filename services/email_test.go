@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCodeStatsByAction(t *testing.T) {
+	emails := newImportFixture(t).emails
+	ctx := context.Background()
+
+	for _, action := range []string{"register", "forget", "login", "change_email"} {
+		if _, err := emails.SendCode(ctx, action+"@example.com", action, "zh-CN"); err != nil {
+			t.Fatalf("send %s code: %v", action, err)
+		}
+	}
+	if _, err := emails.CreateSetPasswordToken(ctx, "imported@example.com"); err != nil {
+		t.Fatalf("create set-password token: %v", err)
+	}
+
+	stats, err := emails.CodeStats(ctx)
+	if err != nil {
+		t.Fatalf("code stats: %v", err)
+	}
+	want := CodeStats{TotalCount: 5, UnusedCount: 5, RegisterCount: 1, ForgetCount: 1,
+		LoginCount: 1, ChangeEmailCount: 1, SetPasswordCount: 1}
+	if *stats != want {
+		t.Fatalf("stats = %+v, want %+v", *stats, want)
+	}
+}
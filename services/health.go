@@ -0,0 +1,150 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/database"
+	"book-manage/logging"
+	"context"
+	"fmt"
+	"net/mail"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 依赖检查状态
+const (
+	HealthOK       = "ok"       // 正常
+	HealthDisabled = "disabled" // 未配置（可选依赖，不影响就绪）
+	HealthError    = "error"    // 异常
+)
+
+// healthCheckTimeout 单项依赖检查超时时间
+const healthCheckTimeout = 3 * time.Second
+
+var healthLog = logging.For("health")
+
+// HealthCheck 单项依赖检查结果
+type HealthCheck struct {
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// HealthService 依赖健康检查服务（就绪检查使用）
+type HealthService struct {
+	db       *gorm.DB
+	images   ImageStore // 为空或未启用时跳过检查
+	mailer   Mailer
+	emailCfg *config.EmailConfig
+}
+
+// NewHealthService 创建依赖健康检查服务
+func NewHealthService(db *gorm.DB, images ImageStore, mailer Mailer, emailCfg *config.EmailConfig) *HealthService {
+	return &HealthService{db: db, images: images, mailer: mailer, emailCfg: emailCfg}
+}
+
+// healthCheck 单项依赖检查，message 为检查失败时对外返回的说明
+type healthCheck struct {
+	name    string
+	message string
+	check   func(context.Context) (string, error)
+}
+
+// Ready 并行检查数据库连接、迁移状态、图片存储和邮件配置
+// 任一项为 error 时返回 false；接口无需登录，失败详情只写入日志，结果中仅包含通用说明
+func (s *HealthService) Ready(ctx context.Context) (bool, map[string]HealthCheck) {
+	checks := []healthCheck{
+		{"database", "database is unavailable", s.checkDatabase},
+		{"migrations", "migrations are pending or unreadable", s.checkMigrations},
+		{"storage", "image storage is unavailable", s.checkStorage},
+		{"mailer", "mailer is misconfigured", s.checkMailer},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]HealthCheck, len(checks))
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c healthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			status, err := c.check(checkCtx)
+			result := HealthCheck{Status: status, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				healthLog.WarnContext(ctx, "依赖检查失败", "check", c.name, "error", err)
+				result.Status = HealthError
+				result.Message = c.message
+			}
+
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range results {
+		if result.Status == HealthError {
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+// checkDatabase 检查数据库连接
+func (s *HealthService) checkDatabase(ctx context.Context) (string, error) {
+	if s.db == nil {
+		return HealthError, fmt.Errorf("database is not initialized")
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return HealthError, err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return HealthError, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return HealthOK, nil
+}
+
+// checkMigrations 检查是否存在未执行的迁移（只读查询 schema_migrations）
+func (s *HealthService) checkMigrations(ctx context.Context) (string, error) {
+	if s.db == nil {
+		return HealthError, fmt.Errorf("database is not initialized")
+	}
+	pending, err := database.PendingMigrations(s.db.WithContext(ctx))
+	if err != nil {
+		return HealthError, fmt.Errorf("failed to get migration status: %w", err)
+	}
+	if len(pending) > 0 {
+		return HealthError, fmt.Errorf("pending migration %04d_%s", pending[0].Version, pending[0].Name)
+	}
+	return HealthOK, nil
+}
+
+// checkStorage 检查图片存储桶是否可访问，未配置时跳过
+func (s *HealthService) checkStorage(ctx context.Context) (string, error) {
+	if s.images == nil || !s.images.IsEnabled() {
+		return HealthDisabled, nil
+	}
+	if err := s.images.CheckBucket(ctx); err != nil {
+		return HealthError, err
+	}
+	return HealthOK, nil
+}
+
+// checkMailer 检查邮件发送配置（不实际发送邮件）
+func (s *HealthService) checkMailer(ctx context.Context) (string, error) {
+	if s.mailer == nil {
+		return HealthError, fmt.Errorf("mailer is not initialized")
+	}
+	if _, err := mail.ParseAddress(fromAddress(s.emailCfg)); err != nil {
+		return HealthError, fmt.Errorf("invalid from address: %w", err)
+	}
+	return HealthOK, nil
+}
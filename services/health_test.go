@@ -0,0 +1,39 @@
+package services
+
+import (
+	"book-manage/config"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// brokenImageStore 存储桶始终不可访问
+type brokenImageStore struct{}
+
+func (brokenImageStore) IsEnabled() bool { return true }
+func (brokenImageStore) UploadImage(context.Context, int, []byte, string) (string, error) {
+	return "", errors.New("not implemented")
+}
+func (brokenImageStore) DeleteImage(context.Context, string) error {
+	return errors.New("not implemented")
+}
+func (brokenImageStore) CheckBucket(context.Context) error {
+	return errors.New("AccessDenied: bucket secret-bucket, account 0123456789")
+}
+
+func TestReadyHidesErrorDetails(t *testing.T) {
+	health := NewHealthService(nil, brokenImageStore{}, nil, &config.EmailConfig{From: "noreply@example.com"})
+	ready, checks := health.Ready(context.Background())
+	if ready {
+		t.Fatalf("ready should be false when checks fail")
+	}
+	for name, check := range checks {
+		if check.Status != HealthError {
+			t.Errorf("check %s status = %s, want error", name, check.Status)
+		}
+		if check.Message == "" || strings.Contains(check.Message, "secret-bucket") || strings.Contains(check.Message, "not initialized") {
+			t.Errorf("check %s message = %q, want a generic message", name, check.Message)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"book-manage/metrics"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 借阅规则
+const (
+	MaxOpenLoans = 5                   // 每位读者最多同时借阅的图书数量
+	LoanPeriod   = 30 * 24 * time.Hour // 借阅期限
+)
+
+// 借阅错误
+var (
+	ErrOutOfStock      = errors.New("库存不足")
+	ErrLoanLimit       = errors.New("借阅已达上限")
+	ErrAlreadyBorrowed = errors.New("该图书已存在未归还记录")
+	ErrLoanNotFound    = errors.New("不存在此借阅记录")
+)
+
+// BorrowSuspendedError 借阅权限已暂停，User 为按规则评估后的用户（含暂停原因）
+type BorrowSuspendedError struct {
+	User *models.User
+}
+
+func (e *BorrowSuspendedError) Error() string {
+	return "借阅权限已暂停：" + e.User.SuspendedReason
+}
+
+// SuspensionEvaluator 按规则评估用户借阅权限（由 SuspensionService 实现）
+type SuspensionEvaluator interface {
+	Evaluate(ctx context.Context, user *models.User) error
+}
+
+// LoanService 借阅服务
+type LoanService struct {
+	books      repository.BookRepo
+	loans      repository.LoanRepo
+	users      repository.UserRepo
+	suspension SuspensionEvaluator // 为空时不评估借阅权限，只检查用户当前状态
+}
+
+// NewLoanService 创建借阅服务
+func NewLoanService(books repository.BookRepo, loans repository.LoanRepo, users repository.UserRepo, suspension SuspensionEvaluator) *LoanService {
+	return &LoanService{books: books, loans: loans, users: users, suspension: suspension}
+}
+
+// Borrow 借书
+// 借阅权限被暂停时返回*BorrowSuspendedError；图书不存在、无库存、超过借阅上限或已借阅同一本书时返回对应错误
+func (s *LoanService) Borrow(ctx context.Context, userID, bookID uint) (*models.BorrowRecord, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	s.evaluate(ctx, user)
+	if user.Status == models.UserStatusSuspended {
+		return nil, &BorrowSuspendedError{User: user}
+	}
+
+	book, err := s.books.FindByID(ctx, bookID)
+	if err == repository.ErrNotFound {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if book.AvailableQuantity <= 0 {
+		return nil, ErrOutOfStock
+	}
+
+	openLoans, err := s.loans.CountOpenByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if openLoans >= MaxOpenLoans {
+		return nil, ErrLoanLimit
+	}
+
+	if _, err := s.loans.FindOpen(ctx, userID, bookID); err == nil {
+		return nil, ErrAlreadyBorrowed
+	} else if err != repository.ErrNotFound {
+		return nil, err
+	}
+
+	borrowDate := time.Now()
+	record := &models.BorrowRecord{
+		UserID:     userID,
+		BookID:     bookID,
+		BorrowDate: borrowDate,
+		DueDate:    borrowDate.Add(LoanPeriod),
+		Status:     models.BorrowStatusBorrowed,
+	}
+	if err := s.loans.Create(ctx, record); err != nil {
+		if err == repository.ErrOutOfStock {
+			return nil, ErrOutOfStock
+		}
+		return nil, err
+	}
+	metrics.Borrows.Inc()
+	return record, nil
+}
+
+// Return 还书，归还后重新评估借阅权限（归还逾期图书后可能解除暂停）
+func (s *LoanService) Return(ctx context.Context, userID, bookID uint) error {
+	record, err := s.loans.FindOpen(ctx, userID, bookID)
+	if err == repository.ErrNotFound {
+		return ErrLoanNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.loans.MarkReturned(ctx, record, time.Now()); err != nil {
+		if err == repository.ErrNotFound {
+			return ErrLoanNotFound
+		}
+		return err
+	}
+	metrics.Returns.Inc()
+
+	if user, err := s.users.FindByID(ctx, userID); err == nil {
+		s.evaluate(ctx, user)
+	}
+	return nil
+}
+
+// Records 分页查询用户的借阅记录，状态为空或 all 时查询全部
+func (s *LoanService) Records(ctx context.Context, userID uint, filter repository.LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	return s.loans.ListByUser(ctx, userID, filter)
+}
+
+// AllRecords 分页查询全部借阅记录，状态为空或 all 时查询全部
+func (s *LoanService) AllRecords(ctx context.Context, filter repository.LoanFilter) ([]models.BorrowRecordWithDetails, int64, error) {
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	return s.loans.List(ctx, filter)
+}
+
+// OverdueCount 统计当前逾期未还的借阅数量
+func (s *LoanService) OverdueCount(ctx context.Context) (int64, error) {
+	return s.loans.CountOverdue(ctx, time.Now())
+}
+
+// evaluate 按规则重新评估用户借阅权限（失败只记录日志）
+func (s *LoanService) evaluate(ctx context.Context, user *models.User) {
+	if s.suspension == nil {
+		return
+	}
+	if err := s.suspension.Evaluate(ctx, user); err != nil {
+		suspensionLog.ErrorContext(ctx, "评估借阅权限失败", "error", err, "user_id", user.ID)
+	}
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/repository/memory"
+	"book-manage/services"
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubSuspension 将指定用户标记为暂停借阅
+type stubSuspension struct {
+	suspended map[uint]string
+}
+
+func (s *stubSuspension) Evaluate(ctx context.Context, user *models.User) error {
+	if reason, ok := s.suspended[user.ID]; ok {
+		user.Status = models.UserStatusSuspended
+		user.SuspendedReason = reason
+	} else if user.Status == models.UserStatusSuspended {
+		user.Status = models.UserStatusNormal
+		user.SuspendedReason = ""
+	}
+	return nil
+}
+
+func newLoanFixture(t *testing.T, quantity int) (*memory.Store, *services.LoanService, *models.User, *models.Book) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+
+	user := &models.User{Email: "reader@example.com"}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	book := &models.Book{Title: "Go", Author: "A", ISBN: "9780000000001", Category: "cs", TotalQuantity: quantity}
+	if err := services.NewBookService(store.Books, store.Loans).Create(ctx, book); err != nil {
+		t.Fatalf("create book: %v", err)
+	}
+
+	loans := services.NewLoanService(store.Books, store.Loans, store.Users, nil)
+	return store, loans, user, book
+}
+
+func TestBorrowAndReturnUpdatesStock(t *testing.T) {
+	ctx := context.Background()
+	store, loans, user, book := newLoanFixture(t, 2)
+
+	record, err := loans.Borrow(ctx, user.ID, book.ID)
+	if err != nil {
+		t.Fatalf("borrow: %v", err)
+	}
+	if got := record.DueDate.Sub(record.BorrowDate); got != services.LoanPeriod {
+		t.Errorf("loan period = %v, want %v", got, services.LoanPeriod)
+	}
+	if stored, _ := store.Books.FindByID(ctx, book.ID); stored.AvailableQuantity != 1 {
+		t.Errorf("available after borrow = %d, want 1", stored.AvailableQuantity)
+	}
+
+	if _, err := loans.Borrow(ctx, user.ID, book.ID); err != services.ErrAlreadyBorrowed {
+		t.Errorf("second borrow err = %v, want ErrAlreadyBorrowed", err)
+	}
+
+	if err := loans.Return(ctx, user.ID, book.ID); err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if stored, _ := store.Books.FindByID(ctx, book.ID); stored.AvailableQuantity != 2 {
+		t.Errorf("available after return = %d, want 2", stored.AvailableQuantity)
+	}
+	if err := loans.Return(ctx, user.ID, book.ID); err != services.ErrLoanNotFound {
+		t.Errorf("second return err = %v, want ErrLoanNotFound", err)
+	}
+
+	records, total, err := loans.Records(ctx, user.ID, repository.LoanFilter{Status: "all", Page: 1, Limit: 10})
+	if err != nil || total != 1 || records[0].Status != models.BorrowStatusReturned || records[0].BookTitle != "Go" {
+		t.Errorf("records = %+v, total = %d, err = %v", records, total, err)
+	}
+}
+
+func TestBorrowRejections(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("out of stock", func(t *testing.T) {
+		store, loans, user, book := newLoanFixture(t, 1)
+		other := &models.User{Email: "other@example.com"}
+		store.Users.Create(ctx, other)
+		if _, err := loans.Borrow(ctx, other.ID, book.ID); err != nil {
+			t.Fatalf("borrow: %v", err)
+		}
+		if _, err := loans.Borrow(ctx, user.ID, book.ID); err != services.ErrOutOfStock {
+			t.Errorf("err = %v, want ErrOutOfStock", err)
+		}
+	})
+
+	t.Run("book not found", func(t *testing.T) {
+		_, loans, user, _ := newLoanFixture(t, 1)
+		if _, err := loans.Borrow(ctx, user.ID, 999); err != services.ErrBookNotFound {
+			t.Errorf("err = %v, want ErrBookNotFound", err)
+		}
+	})
+
+	t.Run("loan limit", func(t *testing.T) {
+		store, loans, user, _ := newLoanFixture(t, 1)
+		books := services.NewBookService(store.Books, store.Loans)
+		var last *models.Book
+		for i := 0; i <= services.MaxOpenLoans; i++ {
+			last = &models.Book{Title: "B", Author: "A", ISBN: string(rune('a' + i)), Category: "cs", TotalQuantity: 1}
+			if err := books.Create(ctx, last); err != nil {
+				t.Fatalf("create book: %v", err)
+			}
+			if i < services.MaxOpenLoans {
+				if _, err := loans.Borrow(ctx, user.ID, last.ID); err != nil {
+					t.Fatalf("borrow %d: %v", i, err)
+				}
+			}
+		}
+		if _, err := loans.Borrow(ctx, user.ID, last.ID); err != services.ErrLoanLimit {
+			t.Errorf("err = %v, want ErrLoanLimit", err)
+		}
+	})
+
+	t.Run("suspended", func(t *testing.T) {
+		store, _, user, book := newLoanFixture(t, 1)
+		suspension := &stubSuspension{suspended: map[uint]string{user.ID: "读者资格已到期"}}
+		loans := services.NewLoanService(store.Books, store.Loans, store.Users, suspension)
+
+		_, err := loans.Borrow(ctx, user.ID, book.ID)
+		var suspended *services.BorrowSuspendedError
+		if !errors.As(err, &suspended) || suspended.User.SuspendedReason != "读者资格已到期" {
+			t.Fatalf("err = %v, want BorrowSuspendedError", err)
+		}
+
+		delete(suspension.suspended, user.ID)
+		if _, err := loans.Borrow(ctx, user.ID, book.ID); err != nil {
+			t.Errorf("borrow after lift: %v", err)
+		}
+	})
+}
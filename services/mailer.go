@@ -0,0 +1,78 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/logging"
+	"context"
+	"fmt"
+)
+
+// defaultFromEmail 未配置发件人时使用的默认地址
+const defaultFromEmail = "noreply@yourdomain.com"
+
+var mailerLog = logging.For("mailer")
+
+// Message 邮件内容
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string // 纯文本版本（可选）
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	// Name 发送方式名称，如 resend、smtp、log
+	Name() string
+	// Send 发送邮件，返回服务商的消息ID（没有时返回空字符串）
+	Send(ctx context.Context, msg *Message) (string, error)
+}
+
+// NewMailer 根据配置创建邮件发送器
+func NewMailer(cfg *config.EmailConfig) (Mailer, error) {
+	if cfg == nil {
+		return NewLogMailer(""), nil
+	}
+
+	switch cfg.Provider {
+	case "resend":
+		apiKey := resendAPIKey(cfg)
+		if apiKey == "" {
+			return nil, fmt.Errorf("resend_api_key is required for resend provider")
+		}
+		return NewResendMailer(apiKey), nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("smtp_host is required for smtp provider")
+		}
+		return NewSMTPMailer(cfg), nil
+	case "log", "":
+		return NewLogMailer(cfg.OutboxDir), nil
+	default:
+		return nil, fmt.Errorf("unsupported email provider: %s", cfg.Provider)
+	}
+}
+
+// resendAPIKey 获取 Resend API Key
+// 未配置 resend_api_key 时兼容旧配置使用 smtp_password，并提示迁移
+func resendAPIKey(cfg *config.EmailConfig) string {
+	if cfg.ResendAPIKey != "" {
+		return cfg.ResendAPIKey
+	}
+	if cfg.SMTPPassword != "" {
+		mailerLog.Warn("使用 smtp_password 作为 Resend API Key 已弃用，请改为配置 resend_api_key（环境变量 RESEND_API_KEY）")
+	}
+	return cfg.SMTPPassword
+}
+
+// fromAddress 获取发件人地址
+func fromAddress(cfg *config.EmailConfig) string {
+	if cfg != nil && cfg.From != "" {
+		return cfg.From
+	}
+	if cfg != nil && cfg.SMTPUser != "" {
+		return cfg.SMTPUser
+	}
+	return defaultFromEmail
+}
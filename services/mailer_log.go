@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logMailerMaxMessages 内存中保留的最大邮件数量
+const logMailerMaxMessages = 100
+
+// LogMailer 不实际发送邮件，将邮件打印到控制台或写入目录（开发环境和测试使用）
+// 已发送的邮件同时保存在内存中，测试可通过 Messages 断言邮件内容
+type LogMailer struct {
+	dir      string
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewLogMailer 创建日志邮件发送器，dir 为空时仅打印到控制台
+func NewLogMailer(dir string) *LogMailer {
+	return &LogMailer{dir: dir}
+}
+
+// Name 发送方式名称
+func (m *LogMailer) Name() string {
+	return "log"
+}
+
+// Send 记录邮件
+func (m *LogMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	messageID := fmt.Sprintf("log-%s", id)
+
+	m.mu.Lock()
+	m.messages = append(m.messages, *msg)
+	if len(m.messages) > logMailerMaxMessages {
+		m.messages = m.messages[len(m.messages)-logMailerMaxMessages:]
+	}
+	m.mu.Unlock()
+
+	// 未配置目录时将邮件内容打印到控制台（开发环境查看验证码），这是邮件的投递方式而非日志，不经过日志脱敏
+	if m.dir == "" {
+		fmt.Printf("[Mailer] [log] To: %s, Subject: %s\n%s\n", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+		return messageID, nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create outbox dir: %w", err)
+	}
+	_, data, err := buildMIMEMessage(msg, "localhost")
+	if err != nil {
+		return "", err
+	}
+	filename := filepath.Join(m.dir, fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), id))
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	mailerLog.Info("邮件已写入文件", "mailer", "log", "file", filename, "to", strings.Join(msg.To, ", "), "subject", msg.Subject)
+	return messageID, nil
+}
+
+// Messages 获取已记录的邮件副本
+func (m *LogMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
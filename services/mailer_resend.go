@@ -0,0 +1,48 @@
+package services
+
+import (
+	"book-manage/tracing"
+	"context"
+	"fmt"
+
+	"github.com/resend/resend-go/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ResendMailer 使用 Resend API 发送邮件
+type ResendMailer struct {
+	client *resend.Client
+}
+
+// NewResendMailer 创建 Resend 邮件发送器
+func NewResendMailer(apiKey string) *ResendMailer {
+	return &ResendMailer{client: resend.NewClient(apiKey)}
+}
+
+// Name 发送方式名称
+func (m *ResendMailer) Name() string {
+	return "resend"
+}
+
+// Send 发送邮件
+func (m *ResendMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	ctx, span := tracing.Start(ctx, "resend.send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("email.recipients", len(msg.To))))
+	resp, err := m.client.Emails.SendWithContext(ctx, &resend.SendEmailRequest{
+		From:    msg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+	})
+	if err != nil {
+		err = fmt.Errorf("resend send failed: %w", err)
+		tracing.End(span, err)
+		return "", err
+	}
+	span.SetAttributes(attribute.String("email.message_id", resp.Id))
+	tracing.End(span, nil)
+	return resp.Id, nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"book-manage/config"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout SMTP连接及会话超时时间
+const smtpTimeout = 15 * time.Second
+
+// SMTPMailer 使用 SMTP 服务器发送邮件
+type SMTPMailer struct {
+	host     string
+	port     string
+	user     string
+	password string
+	tlsMode  string // starttls、tls 或 none
+}
+
+// NewSMTPMailer 创建 SMTP 邮件发送器
+func NewSMTPMailer(cfg *config.EmailConfig) *SMTPMailer {
+	tlsMode := strings.ToLower(cfg.SMTPTLS)
+	if tlsMode == "" {
+		tlsMode = "starttls"
+	}
+	port := cfg.SMTPPort
+	if port == "" {
+		if tlsMode == "tls" {
+			port = "465"
+		} else {
+			port = "587"
+		}
+	}
+
+	return &SMTPMailer{
+		host:     cfg.SMTPHost,
+		port:     port,
+		user:     cfg.SMTPUser,
+		password: cfg.SMTPPassword,
+		tlsMode:  tlsMode,
+	}
+}
+
+// Name 发送方式名称
+func (m *SMTPMailer) Name() string {
+	return "smtp"
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) (string, error) {
+	messageID, data, err := buildMIMEMessage(msg, m.host)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if m.user != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", m.user, m.password, m.host)); err != nil {
+				return "", fmt.Errorf("smtp auth failed: %w", err)
+			}
+		}
+	}
+
+	if err := client.Mail(extractAddress(msg.From)); err != nil {
+		return "", fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(extractAddress(to)); err != nil {
+			return "", fmt.Errorf("smtp RCPT TO %s failed: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", fmt.Errorf("smtp write failed: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("smtp send failed: %w", err)
+	}
+
+	return messageID, client.Quit()
+}
+
+// dial 建立SMTP连接并根据配置启用TLS
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.host, m.port)
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	tlsConfig := &tls.Config{ServerName: m.host}
+
+	var conn net.Conn
+	var err error
+	if m.tlsMode == "tls" {
+		// 隐式TLS：连接建立即进行TLS握手
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp connect %s failed: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp handshake failed: %w", err)
+	}
+
+	if m.tlsMode == "starttls" {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok {
+			client.Close()
+			return nil, fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp STARTTLS failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// buildMIMEMessage 构建MIME邮件，同时包含纯文本和HTML时使用 multipart/alternative
+func buildMIMEMessage(msg *Message, domain string) (string, []byte, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return "", nil, err
+	}
+	messageID := fmt.Sprintf("<%s@%s>", id, domain)
+
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	writeHeader("From", msg.From)
+	writeHeader("To", strings.Join(msg.To, ", "))
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID)
+	writeHeader("MIME-Version", "1.0")
+
+	if msg.Text != "" && msg.HTML != "" {
+		boundary := "alt_" + id
+		writeHeader("Content-Type", fmt.Sprintf(`multipart/alternative; boundary="%s"`, boundary))
+		buf.WriteString("\r\n")
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", msg.Text},
+			{"text/html", msg.HTML},
+		} {
+			buf.WriteString("--" + boundary + "\r\n")
+			if err := writeQuotedPrintablePart(&buf, part.contentType, part.body); err != nil {
+				return "", nil, err
+			}
+		}
+		buf.WriteString("--" + boundary + "--\r\n")
+	} else {
+		contentType, body := "text/html", msg.HTML
+		if msg.HTML == "" {
+			contentType, body = "text/plain", msg.Text
+		}
+		if err := writeQuotedPrintablePart(&buf, contentType, body); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return messageID, buf.Bytes(), nil
+}
+
+// writeQuotedPrintablePart 写入一个使用 quoted-printable 编码的正文部分
+func writeQuotedPrintablePart(buf *bytes.Buffer, contentType, body string) error {
+	buf.WriteString("Content-Type: " + contentType + "; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	return nil
+}
+
+// extractAddress 从 "名称 <地址>" 格式中提取邮箱地址
+func extractAddress(addr string) string {
+	if start := strings.LastIndex(addr, "<"); start >= 0 {
+		if end := strings.LastIndex(addr, ">"); end > start {
+			return addr[start+1 : end]
+		}
+	}
+	return strings.TrimSpace(addr)
+}
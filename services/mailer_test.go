@@ -0,0 +1,27 @@
+package services
+
+import (
+	"book-manage/config"
+	"testing"
+)
+
+func TestResendAPIKey(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.EmailConfig
+		want string
+	}{
+		{"dedicated key", config.EmailConfig{ResendAPIKey: "re_new", SMTPPassword: "re_old"}, "re_new"},
+		{"legacy smtp_password", config.EmailConfig{SMTPPassword: "re_old"}, "re_old"},
+		{"missing", config.EmailConfig{}, ""},
+	}
+	for _, c := range cases {
+		if got := resendAPIKey(&c.cfg); got != c.want {
+			t.Errorf("%s: resendAPIKey = %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	if _, err := NewMailer(&config.EmailConfig{Provider: "resend"}); err == nil {
+		t.Errorf("resend provider without an API key should fail")
+	}
+}
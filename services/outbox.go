@@ -0,0 +1,251 @@
+package services
+
+import (
+	"book-manage/logging"
+	"book-manage/metrics"
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 发件箱worker参数
+const (
+	outboxPollInterval    = 5 * time.Second  // 轮询间隔
+	outboxBatchSize       = 10               // 每次领取的邮件数量
+	outboxMaxAttempts     = 6                // 最大发送次数
+	outboxBaseBackoff     = 30 * time.Second // 首次重试间隔，之后每次翻倍
+	outboxMaxBackoff      = time.Hour        // 最大重试间隔
+	outboxSendingTimeout  = 5 * time.Minute  // 发送中状态超时（实例崩溃后由其他实例重新领取）
+	outboxSendTimeout     = 30 * time.Second // 单封邮件发送超时
+	outboxPurgeInterval   = time.Hour        // 清除邮件正文的间隔
+	outboxFailedRetention = 24 * time.Hour   // 发送失败的邮件保留正文的时间（期间管理员可重新发送）
+)
+
+// ErrOutboxRetryNotAllowed 邮件不存在、未发送失败或正文已被清除，不能重新发送
+var ErrOutboxRetryNotAllowed = errors.New("邮件不存在或不可重新发送")
+
+// OutboxService 邮件发件箱服务
+type OutboxService struct {
+	outbox repository.OutboxRepo
+	mailer Mailer
+	wake   chan struct{}
+}
+
+var outboxLog = logging.For("outbox")
+
+// NewOutboxService 创建发件箱服务，后台发送worker由 Run 启动
+func NewOutboxService(outbox repository.OutboxRepo, mailer Mailer) *OutboxService {
+	return &OutboxService{
+		outbox: outbox,
+		mailer: mailer,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// MailerName 实际发送邮件的方式名称
+func (s *OutboxService) MailerName() string {
+	return s.mailer.Name()
+}
+
+// Enqueue 将邮件写入发件箱，由后台worker异步发送
+func (s *OutboxService) Enqueue(ctx context.Context, category string, msg *Message) (*models.EmailOutbox, error) {
+	item := models.EmailOutbox{
+		Category:      category,
+		FromEmail:     msg.From,
+		ToEmail:       strings.Join(msg.To, ","),
+		Subject:       msg.Subject,
+		HTMLBody:      msg.HTML,
+		TextBody:      msg.Text,
+		Status:        models.OutboxStatusPending,
+		MaxAttempts:   outboxMaxAttempts,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	}
+	if err := s.outbox.Create(ctx, &item); err != nil {
+		return nil, fmt.Errorf("failed to enqueue email: %w", err)
+	}
+
+	// 唤醒worker立即发送，无需等待下一次轮询
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return &item, nil
+}
+
+// List 分页查询发件箱，按创建时间倒序
+func (s *OutboxService) List(ctx context.Context, filter repository.OutboxFilter) ([]models.EmailOutbox, int64, error) {
+	return s.outbox.List(ctx, filter)
+}
+
+// StatusCounts 各状态的邮件数量，便于管理员发现积压或失败
+func (s *OutboxService) StatusCounts(ctx context.Context) ([]repository.OutboxStatusCount, error) {
+	return s.outbox.StatusCounts(ctx)
+}
+
+// Retry 将发送失败的邮件重新放回发件箱（正文已被清除的邮件无法重新发送）
+// 邮件不存在、未发送失败或正文已被清除时返回ErrOutboxRetryNotAllowed
+func (s *OutboxService) Retry(ctx context.Context, id uint) error {
+	err := s.outbox.Retry(ctx, id, time.Now())
+	if err == repository.ErrNotFound {
+		return ErrOutboxRetryNotAllowed
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run 后台worker：轮询发件箱并发送到期的邮件，定期清除已结束邮件的正文，ctx 取消后返回
+// 已领取的邮件发送完后才返回，未领取的邮件留在发件箱中，由下次启动的实例继续发送
+func (s *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	purgeTicker := time.NewTicker(outboxPurgeInterval)
+	defer purgeTicker.Stop()
+
+	s.purgeBodies(ctx)
+	for {
+		s.processBatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		case <-purgeTicker.C:
+			s.purgeBodies(ctx)
+		}
+	}
+}
+
+// purgeBodies 清除已发送邮件，以及失败超过保留时间的邮件的正文
+// 正文中包含验证码、登录链接、设置密码链接和初始密码等敏感信息，不应长期保存
+func (s *OutboxService) purgeBodies(ctx context.Context) {
+	purged, err := s.outbox.PurgeBodies(ctx, time.Now().Add(-outboxFailedRetention))
+	if err != nil {
+		outboxLog.Error("清除邮件正文失败", "error", err)
+		return
+	}
+	if purged > 0 {
+		outboxLog.Info("已清除邮件正文", "count", purged)
+	}
+}
+
+// processBatch 领取并发送一批邮件，直到没有到期的邮件或 ctx 已取消
+func (s *OutboxService) processBatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		items, err := s.outbox.Claim(ctx, now, now.Add(-outboxSendingTimeout), outboxBatchSize)
+		if err != nil {
+			outboxLog.Error("领取待发送邮件失败", "error", err)
+			return
+		}
+		if len(items) == 0 {
+			return
+		}
+		for i := range items {
+			s.deliver(&items[i])
+		}
+	}
+}
+
+// deliver 发送单封邮件并记录结果
+// 每次发送是一条独立的链路（与写入发件箱的请求无关），发送和更新状态的span都属于该链路
+func (s *OutboxService) deliver(item *models.EmailOutbox) {
+	ctx, span := tracing.Start(context.Background(), "outbox.deliver", trace.WithAttributes(
+		attribute.Int64("app.outbox_id", int64(item.ID)),
+		attribute.String("app.email_category", item.Category),
+		attribute.String("app.mailer", s.mailer.Name()),
+		attribute.Int("app.attempt", item.Attempts+1),
+	))
+	defer span.End()
+
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	defer cancel()
+
+	sendStart := time.Now()
+	messageID, err := s.mailer.Send(sendCtx, &Message{
+		From:    item.FromEmail,
+		To:      strings.Split(item.ToEmail, ","),
+		Subject: item.Subject,
+		HTML:    item.HTMLBody,
+		Text:    item.TextBody,
+	})
+	attempts := item.Attempts + 1
+
+	if err == nil {
+		now := time.Now()
+		metrics.Emails.WithLabelValues(item.Category, metrics.EmailSent).Inc()
+		outboxLog.Info("邮件发送成功", "mailer", s.mailer.Name(), "outbox_id", item.ID, "category", item.Category,
+			"elapsed_ms", time.Since(sendStart).Milliseconds(), "message_id", messageID)
+		s.update(ctx, item, map[string]interface{}{
+			"status":              models.OutboxStatusSent,
+			"attempts":            attempts,
+			"provider":            s.mailer.Name(),
+			"provider_message_id": messageID,
+			"sent_at":             &now,
+			"locked_at":           nil,
+			"last_error":          "",
+			"html_body":           "", // 发送成功后立即清除正文（含验证码等敏感信息）
+			"text_body":           "",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"provider":   s.mailer.Name(),
+		"last_error": err.Error(),
+		"locked_at":  nil,
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if attempts >= item.MaxAttempts {
+		outboxLog.Error("邮件发送失败，已达最大重试次数", "error", err, "mailer", s.mailer.Name(), "outbox_id", item.ID, "category", item.Category)
+		updates["status"] = models.OutboxStatusFailed
+		metrics.Emails.WithLabelValues(item.Category, metrics.EmailFailed).Inc()
+	} else {
+		backoff := outboxBackoff(attempts)
+		outboxLog.Warn("邮件发送失败，稍后重试", "error", err, "mailer", s.mailer.Name(), "outbox_id", item.ID, "category", item.Category,
+			"attempts", attempts, "retry_in", backoff.String())
+		updates["status"] = models.OutboxStatusPending
+		updates["next_attempt_at"] = time.Now().Add(backoff)
+		metrics.Emails.WithLabelValues(item.Category, metrics.EmailRetry).Inc()
+	}
+	s.update(ctx, item, updates)
+}
+
+// update 记录发送结果（失败只写日志，邮件在发送中状态超时后会被重新领取）
+func (s *OutboxService) update(ctx context.Context, item *models.EmailOutbox, updates map[string]interface{}) {
+	if err := s.outbox.Update(ctx, item, updates); err != nil {
+		outboxLog.ErrorContext(ctx, "更新邮件状态失败", "error", err, "outbox_id", item.ID)
+	}
+}
+
+// outboxBackoff 计算第attempts次失败后的重试间隔（指数退避）
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"book-manage/config"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// argon2id 盐和哈希长度
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrUnknownHashFormat 无法识别的密码哈希格式
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// argon2Params argon2id 参数
+type argon2Params struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+}
+
+// PasswordHasher 密码哈希服务
+// 哈希值中自带算法和参数：bcrypt 为 $2a$/$2b$ 格式，argon2id 为 PHC 格式
+// （$argon2id$v=19$m=65536,t=3,p=2$salt$hash），切换算法或调整参数后旧哈希仍可校验
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+// NewPasswordHasher 根据配置创建密码哈希服务，未配置的参数使用默认值
+func NewPasswordHasher(cfg *config.PasswordHashConfig) (*PasswordHasher, error) {
+	hasher := &PasswordHasher{
+		algorithm:  HashBcrypt,
+		bcryptCost: bcrypt.DefaultCost,
+		argon2: argon2Params{
+			memory:      64 * 1024,
+			iterations:  3,
+			parallelism: 2,
+		},
+	}
+	if cfg == nil {
+		return hasher, nil
+	}
+
+	if cfg.Algorithm != "" {
+		hasher.algorithm = strings.ToLower(cfg.Algorithm)
+	}
+	if hasher.algorithm != HashBcrypt && hasher.algorithm != HashArgon2id {
+		return nil, fmt.Errorf("unsupported password hash algorithm: %s", cfg.Algorithm)
+	}
+
+	if cfg.BcryptCost != 0 {
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		hasher.bcryptCost = cfg.BcryptCost
+	}
+	if cfg.Argon2Memory > 0 {
+		hasher.argon2.memory = cfg.Argon2Memory
+	}
+	if cfg.Argon2Iterations > 0 {
+		hasher.argon2.iterations = cfg.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism > 0 {
+		hasher.argon2.parallelism = cfg.Argon2Parallelism
+	}
+
+	return hasher, nil
+}
+
+// Algorithm 当前用于生成新哈希的算法
+func (h *PasswordHasher) Algorithm() string {
+	return h.algorithm
+}
+
+// Hash 使用当前配置的算法和参数生成密码哈希
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == HashArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		return encodeArgon2id(h.argon2, salt, argon2.IDKey([]byte(password), salt, h.argon2.iterations, h.argon2.memory, h.argon2.parallelism, argon2KeyLength)), nil
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify 校验密码是否与哈希匹配（根据哈希格式自动选择算法）
+func (h *PasswordHasher) Verify(hash, password string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(actual, key) == 1
+	case strings.HasPrefix(hash, "$2"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	default:
+		return false
+	}
+}
+
+// NeedsRehash 检查哈希是否使用了过时的算法或参数（应在密码校验成功后重新哈希）
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		if h.algorithm != HashArgon2id {
+			return true
+		}
+		params, _, key, err := decodeArgon2id(hash)
+		return err != nil || params != h.argon2 || len(key) != argon2KeyLength
+	case strings.HasPrefix(hash, "$2"):
+		if h.algorithm != HashBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.bcryptCost
+	default:
+		return true
+	}
+}
+
+// encodeArgon2id 编码为 PHC 格式
+func encodeArgon2id(params argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// decodeArgon2id 解析 PHC 格式的 argon2id 哈希
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownHashFormat
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"book-manage/config"
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码不符合策略的原因
+const (
+	PasswordTooShort      = "too_short"       // 长度不足
+	PasswordTooLong       = "too_long"        // 超过最大长度
+	PasswordTooFewClasses = "too_few_classes" // 字符类别不足
+	PasswordContainsEmail = "contains_email"  // 包含邮箱用户名
+	PasswordCommon        = "common"          // 常见或已泄露的密码
+	PasswordReused        = "reused"          // 与最近使用过的密码相同
+)
+
+// passwordMaxBytes 密码最大字节数（bcrypt 只处理前72字节，超出部分会被忽略或拒绝）
+const passwordMaxBytes = 72
+
+//go:embed data/common_passwords.txt
+var commonPasswordsFile string
+
+// PasswordViolation 密码不符合策略的具体原因
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicy 密码策略服务
+type PasswordPolicy struct {
+	cfg    config.PasswordPolicyConfig
+	common map[string]bool
+}
+
+// NewPasswordPolicy 创建密码策略（加载内置的常见/已泄露密码列表）
+func NewPasswordPolicy(cfg *config.PasswordPolicyConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		cfg:    *cfg,
+		common: make(map[string]bool),
+	}
+	if policy.cfg.MinLength <= 0 {
+		policy.cfg.MinLength = 8
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.common[strings.ToLower(line)] = true
+	}
+	return policy
+}
+
+// MinLength 密码最小长度
+func (p *PasswordPolicy) MinLength() int {
+	return p.cfg.MinLength
+}
+
+// Check 校验密码是否符合策略，返回全部不符合的原因（符合时返回空列表）
+func (p *PasswordPolicy) Check(password, email string) []PasswordViolation {
+	violations := []PasswordViolation{}
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("密码长度不能少于%d位", p.cfg.MinLength),
+		})
+	}
+
+	if len(password) > passwordMaxBytes {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("密码不能超过%d字节（每个汉字占3字节）", passwordMaxBytes),
+		})
+	}
+
+	if p.cfg.MinCharClasses > 1 && charClasses(password) < p.cfg.MinCharClasses {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooFewClasses,
+			Message: fmt.Sprintf("密码需至少包含大写字母、小写字母、数字、符号中的%d类", p.cfg.MinCharClasses),
+		})
+	}
+
+	if !p.cfg.AllowEmail && containsEmail(password, email) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordContainsEmail,
+			Message: "密码不能包含邮箱用户名",
+		})
+	}
+
+	if !p.cfg.SkipBreachedCheck && p.common[strings.ToLower(password)] {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordCommon,
+			Message: "密码过于常见或已在泄露事件中出现，请更换",
+		})
+	}
+
+	return violations
+}
+
+// HistorySize 禁止重复使用的历史密码数量（0表示只禁止与当前密码相同，小于0表示不检查）
+func (p *PasswordPolicy) HistorySize() int {
+	return p.cfg.HistorySize
+}
+
+// CheckReuse 检查新密码是否与当前密码或最近使用过的密码（hashes）相同
+// 不符合时返回 reused 原因，未启用历史检查时始终通过
+func (p *PasswordPolicy) CheckReuse(hasher *PasswordHasher, hashes []string, password string) []PasswordViolation {
+	if p.cfg.HistorySize < 0 {
+		return nil
+	}
+
+	for _, hash := range hashes {
+		if hash != "" && hasher.Verify(hash, password) {
+			return []PasswordViolation{{
+				Code:    PasswordReused,
+				Message: fmt.Sprintf("不能使用最近%d次使用过的密码", p.cfg.HistorySize+1),
+			}}
+		}
+	}
+	return nil
+}
+
+// charClasses 统计密码包含的字符类别数（大写字母、小写字母、数字、符号）
+func charClasses(password string) int {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, ok := range []bool{upper, lower, digit, symbol} {
+		if ok {
+			count++
+		}
+	}
+	return count
+}
+
+// containsEmail 检查密码是否包含邮箱用户名（@之前的部分，至少3个字符时才检查）
+func containsEmail(password, email string) bool {
+	local := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	if utf8.RuneCountInString(local) < 3 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(password), local)
+}
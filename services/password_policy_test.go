@@ -0,0 +1,38 @@
+package services
+
+import (
+	"book-manage/config"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, MinCharClasses: 2})
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"valid", "Library-2025", nil},
+		{"too short", "Ab1", []string{PasswordTooShort}},
+		{"too few classes", "abcdefghij", []string{PasswordTooFewClasses}},
+		{"contains email", "Reader2025!", []string{PasswordContainsEmail}},
+		{"common", "Password1", []string{PasswordCommon}},
+		{"72 bytes", "Aa1" + strings.Repeat("x", 69), nil},
+		{"73 bytes", "Aa1" + strings.Repeat("x", 70), []string{PasswordTooLong}},
+		{"multibyte over 72 bytes", "Aa1" + strings.Repeat("书", 24), []string{PasswordTooLong}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := policy.Check(tt.password, "reader@example.com")
+			codes := make([]string, len(violations))
+			for i, v := range violations {
+				codes[i] = v.Code
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("violations = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
@@ -8,29 +8,43 @@ import (
 	"strings"
 
 	"book-manage/config"
+	"book-manage/metrics"
+	"book-manage/tracing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ImageStore 图片存储接口（由 R2Service 实现，测试时可替换为内存实现）
+type ImageStore interface {
+	// IsEnabled 存储服务是否可用
+	IsEnabled() bool
+	// UploadImage 上传图书封面，返回公开访问URL
+	UploadImage(ctx context.Context, bookID int, imageData []byte, filename string) (string, error)
+	// DeleteImage 按公开访问URL删除图片
+	DeleteImage(ctx context.Context, imageURL string) error
+	// CheckBucket 检查存储桶是否可访问（就绪检查使用）
+	CheckBucket(ctx context.Context) error
+}
+
 // R2Service R2服务
 type R2Service struct {
-	client   *s3.Client
-	bucket   string
+	client    *s3.Client
+	bucket    string
 	publicURL string
 }
 
-var r2Service *R2Service
-
-// InitR2Service 初始化R2服务
-func InitR2Service(cfg *config.CloudflareR2Config) error {
+// NewR2Service 创建R2服务，配置不完整时返回未启用的服务（图片上传为可选功能）
+func NewR2Service(cfg *config.CloudflareR2Config) (*R2Service, error) {
 	// 检查配置是否完整
 	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" || cfg.BucketName == "" {
-		// 配置不完整，不初始化服务（允许可选）
-		return nil
+		// 配置不完整，不初始化客户端（允许可选）
+		return &R2Service{}, nil
 	}
 
 	// 创建S3兼容的配置
@@ -52,23 +66,16 @@ func InitR2Service(cfg *config.CloudflareR2Config) error {
 		awsconfig.WithRegion(cfg.Region),
 	}
 
-	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO(), cfgOptions...)
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), cfgOptions...)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	r2Service = &R2Service{
+	return &R2Service{
 		client:    s3.NewFromConfig(awsCfg),
 		bucket:    cfg.BucketName,
 		publicURL: cfg.PublicURL,
-	}
-
-	return nil
-}
-
-// GetR2Service 获取R2服务实例
-func GetR2Service() *R2Service {
-	return r2Service
+	}, nil
 }
 
 // IsEnabled 检查R2服务是否已启用
@@ -77,7 +84,7 @@ func (s *R2Service) IsEnabled() bool {
 }
 
 // UploadImage 上传图片到R2
-func (s *R2Service) UploadImage(bookID int, imageData []byte, filename string) (string, error) {
+func (s *R2Service) UploadImage(ctx context.Context, bookID int, imageData []byte, filename string) (string, error) {
 	if !s.IsEnabled() {
 		return "", fmt.Errorf("R2 service is not enabled")
 	}
@@ -88,15 +95,18 @@ func (s *R2Service) UploadImage(bookID int, imageData []byte, filename string) (
 	key := fmt.Sprintf("book-covers/%s", uniqueFilename)
 
 	// 上传到R2
-	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+	ctx, span := s.startSpan(ctx, "PutObject", key)
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
 		Key:         aws.String(key),
 		Body:        bytes.NewReader(imageData),
 		ContentType: aws.String(getContentType(ext)),
 	})
+	tracing.End(span, err)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload image: %w", err)
 	}
+	metrics.R2UploadBytes.Add(float64(len(imageData)))
 
 	// 返回公开URL
 	imageURL := fmt.Sprintf("%s/%s", s.publicURL, key)
@@ -104,7 +114,7 @@ func (s *R2Service) UploadImage(bookID int, imageData []byte, filename string) (
 }
 
 // DeleteImage 从R2删除图片
-func (s *R2Service) DeleteImage(imageURL string) error {
+func (s *R2Service) DeleteImage(ctx context.Context, imageURL string) error {
 	if !s.IsEnabled() {
 		return fmt.Errorf("R2 service is not enabled")
 	}
@@ -123,10 +133,12 @@ func (s *R2Service) DeleteImage(imageURL string) error {
 		}
 	}
 
-	_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	ctx, span := s.startSpan(ctx, "DeleteObject", key)
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(key),
 	})
+	tracing.End(span, err)
 	if err != nil {
 		return fmt.Errorf("failed to delete image: %w", err)
 	}
@@ -134,6 +146,40 @@ func (s *R2Service) DeleteImage(imageURL string) error {
 	return nil
 }
 
+// CheckBucket 检查存储桶是否存在且凭证有权访问
+func (s *R2Service) CheckBucket(ctx context.Context) error {
+	if !s.IsEnabled() {
+		return fmt.Errorf("R2 service is not enabled")
+	}
+
+	ctx, span := s.startSpan(ctx, "HeadBucket", "")
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucket),
+	})
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to access bucket %s: %w", s.bucket, err)
+	}
+	return nil
+}
+
+// startSpan 创建R2调用的客户端span，调用方需调用 tracing.End 结束
+func (s *R2Service) startSpan(ctx context.Context, operation, key string) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemKey.String("aws-api"),
+			semconv.RPCService("S3"),
+			semconv.RPCMethod(operation),
+			semconv.AWSS3Bucket(s.bucket),
+		),
+	}
+	if key != "" {
+		opts = append(opts, trace.WithAttributes(semconv.AWSS3Key(key)))
+	}
+	return tracing.Start(ctx, "r2."+operation, opts...)
+}
+
 // getContentType 根据文件扩展名获取Content-Type
 func getContentType(ext string) string {
 	ext = strings.ToLower(ext)
@@ -150,4 +196,3 @@ func getContentType(ext string) string {
 		return "application/octet-stream"
 	}
 }
-
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 权限码
+const (
+	PermBookEdit       = "book:edit"             // 添加/编辑/删除图书、管理封面
+	PermBorrowManage   = "borrow:manage"         // 查看全量借阅记录
+	PermEmailCodeView  = "email_code:view"       // 查看验证码记录及统计
+	PermRoleManage     = "role:manage"           // 管理角色与用户角色分配
+	PermTemplateManage = "email_template:manage" // 管理邮件模板
+	PermOutboxManage   = "email_outbox:manage"   // 查看发件箱及重新发送失败邮件
+	PermInviteManage   = "invitation:manage"     // 创建、查看、吊销注册邀请
+	PermUserManage     = "user:manage"           // 批量导入及编辑读者信息
+)
+
+// 角色管理错误
+var (
+	ErrRoleExists        = errors.New("角色已存在")
+	ErrRoleNotFound      = errors.New("角色不存在")
+	ErrBuiltinRole       = errors.New("内置角色不可修改")
+	ErrRoleInUse         = errors.New("角色仍有用户使用")
+	ErrUnknownPermission = errors.New("权限不存在")
+)
+
+// 内置角色
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// builtinPermissions 系统内置权限及说明
+var builtinPermissions = []models.Permission{
+	{Code: PermBookEdit, Description: "添加、编辑、删除图书及管理封面"},
+	{Code: PermBorrowManage, Description: "查看全量借阅记录"},
+	{Code: PermEmailCodeView, Description: "查看验证码记录及统计"},
+	{Code: PermRoleManage, Description: "管理角色及用户角色分配"},
+	{Code: PermTemplateManage, Description: "管理邮件模板"},
+	{Code: PermOutboxManage, Description: "查看发件箱及重新发送失败邮件"},
+	{Code: PermInviteManage, Description: "创建、查看、吊销注册邀请"},
+	{Code: PermUserManage, Description: "批量导入及编辑读者信息"},
+}
+
+// rolePermissionsTTL 角色权限缓存时间（本实例修改角色时立即失效，多实例部署时最多延迟该时长生效）
+const rolePermissionsTTL = 30 * time.Second
+
+// cachedPermissions 缓存的角色权限
+type cachedPermissions struct {
+	codes     []string
+	expiresAt time.Time
+}
+
+// RBACService 角色权限服务
+type RBACService struct {
+	cfg   *config.Config
+	roles repository.RoleRepo
+	users repository.UserRepo
+
+	mu    sync.Mutex
+	cache map[string]cachedPermissions // 角色名 -> 权限码
+}
+
+// NewRBACService 创建角色权限服务，并确保内置权限和角色存在
+func NewRBACService(ctx context.Context, cfg *config.Config, roles repository.RoleRepo, users repository.UserRepo) (*RBACService, error) {
+	s := &RBACService{
+		cfg:   cfg,
+		roles: roles,
+		users: users,
+		cache: make(map[string]cachedPermissions),
+	}
+
+	builtinRoles := []models.Role{
+		{Name: RoleAdmin, Description: "管理员（拥有全部权限）", BuiltIn: true},
+		{Name: RoleUser, Description: "普通读者", BuiltIn: true},
+	}
+	if err := roles.Seed(ctx, builtinPermissions, builtinRoles); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AllPermissionCodes 获取所有权限码
+func (s *RBACService) AllPermissionCodes(ctx context.Context) ([]string, error) {
+	return s.roles.PermissionCodes(ctx)
+}
+
+// PermissionsForRole 获取角色拥有的权限码（短时缓存）
+// admin 角色始终拥有全部权限，不存在的角色没有任何权限
+func (s *RBACService) PermissionsForRole(ctx context.Context, roleName string) ([]string, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[roleName]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.codes, nil
+	}
+
+	codes, err := s.loadPermissions(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[roleName] = cachedPermissions{codes: codes, expiresAt: time.Now().Add(rolePermissionsTTL)}
+	s.mu.Unlock()
+	return codes, nil
+}
+
+// loadPermissions 从数据库读取角色拥有的权限码
+func (s *RBACService) loadPermissions(ctx context.Context, roleName string) ([]string, error) {
+	if roleName == RoleAdmin {
+		return s.AllPermissionCodes(ctx)
+	}
+
+	role, err := s.roles.FindRoleByName(ctx, roleName)
+	if err == repository.ErrNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		codes = append(codes, p.Code)
+	}
+	return codes, nil
+}
+
+// InvalidateRole 清除角色的权限缓存（修改或删除角色后调用）
+func (s *RBACService) InvalidateRole(roleName string) {
+	s.mu.Lock()
+	delete(s.cache, roleName)
+	s.mu.Unlock()
+}
+
+// ResolvePermissions 解析用户的有效权限
+// 邮箱白名单中的用户视为 admin，拥有全部权限
+func (s *RBACService) ResolvePermissions(ctx context.Context, email, roleName string) ([]string, error) {
+	if s.cfg != nil && s.cfg.IsAdminEmail(email) {
+		return s.AllPermissionCodes(ctx)
+	}
+	return s.PermissionsForRole(ctx, roleName)
+}
+
+// RoleExists 检查角色是否存在
+func (s *RBACService) RoleExists(ctx context.Context, roleName string) (bool, error) {
+	_, err := s.roles.FindRoleByName(ctx, roleName)
+	if err == repository.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CanAssignRole 检查操作者能否为他人分配角色：角色的全部权限须在操作者的权限范围内
+// 防止仅有邀请等权限的操作者借助角色分配获得更高权限
+func (s *RBACService) CanAssignRole(ctx context.Context, callerPermissions []string, roleName string) (bool, error) {
+	rolePermissions, err := s.PermissionsForRole(ctx, roleName)
+	if err != nil {
+		return false, err
+	}
+	for _, perm := range rolePermissions {
+		if !HasPermission(callerPermissions, perm) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FindPermissions 按权限码查找权限，存在未定义的权限码时返回错误
+func (s *RBACService) FindPermissions(ctx context.Context, codes []string) ([]models.Permission, error) {
+	perms, err := s.roles.FindPermissions(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(perms) != len(uniqueStrings(codes)) {
+		return nil, ErrUnknownPermission
+	}
+	return perms, nil
+}
+
+// ListPermissions 获取全部权限
+func (s *RBACService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.roles.ListPermissions(ctx)
+}
+
+// ListRoles 获取全部角色及其权限
+func (s *RBACService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.roles.ListRoles(ctx)
+}
+
+// CreateRole 创建角色，名称已存在时返回ErrRoleExists，权限码未定义时返回ErrUnknownPermission
+func (s *RBACService) CreateRole(ctx context.Context, name, description string, codes []string) (*models.Role, error) {
+	exists, err := s.RoleExists(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrRoleExists
+	}
+
+	permissions, err := s.FindPermissions(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+	}
+	if err := s.roles.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	s.InvalidateRole(role.Name)
+	return role, nil
+}
+
+// UpdateRole 更新角色描述或权限（codes 不为空时整体替换角色权限）
+// 角色不存在时返回ErrRoleNotFound；admin 角色始终拥有全部权限，修改其权限时返回ErrBuiltinRole
+func (s *RBACService) UpdateRole(ctx context.Context, id uint, description *string, codes *[]string) (*models.Role, error) {
+	role, err := s.findRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == RoleAdmin && codes != nil {
+		return nil, ErrBuiltinRole
+	}
+
+	var permissions *[]models.Permission
+	if codes != nil {
+		found, err := s.FindPermissions(ctx, *codes)
+		if err != nil {
+			return nil, err
+		}
+		permissions = &found
+	}
+
+	if err := s.roles.UpdateRole(ctx, role, description, permissions); err != nil {
+		return nil, err
+	}
+	s.InvalidateRole(role.Name)
+	return role, nil
+}
+
+// DeleteRole 删除角色
+// 角色不存在时返回ErrRoleNotFound，内置角色返回ErrBuiltinRole，仍有用户使用时返回ErrRoleInUse
+func (s *RBACService) DeleteRole(ctx context.Context, id uint) error {
+	role, err := s.findRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrBuiltinRole
+	}
+
+	userCount, err := s.users.CountBy(ctx, "role", role.Name, 0)
+	if err != nil {
+		return err
+	}
+	if userCount > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := s.roles.DeleteRole(ctx, role); err != nil {
+		return err
+	}
+	s.InvalidateRole(role.Name)
+	return nil
+}
+
+// SetUserRole 设置用户角色，权限按请求时的角色解析，对用户已签发的token立即生效
+// 角色不存在时返回ErrRoleNotFound，用户不存在时返回ErrUserNotFound
+func (s *RBACService) SetUserRole(ctx context.Context, userID uint, roleName string) error {
+	exists, err := s.RoleExists(ctx, roleName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRoleNotFound
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.users.Update(ctx, user, map[string]interface{}{"role": roleName})
+}
+
+// findRole 按ID查找角色，不存在时返回ErrRoleNotFound
+func (s *RBACService) findRole(ctx context.Context, id uint) (*models.Role, error) {
+	role, err := s.roles.FindRoleByID(ctx, id)
+	if err == repository.ErrNotFound {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+// HasPermission 检查权限列表中是否包含指定权限
+func HasPermission(permissions []string, perm string) bool {
+	for _, p := range permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueStrings 去重
+func uniqueStrings(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	return result
+}
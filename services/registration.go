@@ -0,0 +1,156 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 注册模式
+const (
+	RegistrationOpen   = "open"   // 任意邮箱均可注册
+	RegistrationDomain = "domain" // 仅限指定邮箱域名
+	RegistrationInvite = "invite" // 仅限受邀注册
+)
+
+// ErrInvitationInvalid 邀请不存在、已使用、已吊销、已过期或邮箱不一致
+var ErrInvitationInvalid = errors.New("邀请无效或已过期")
+
+// RegistrationService 注册策略与邀请服务
+type RegistrationService struct {
+	cfg         config.RegistrationConfig
+	invitations repository.InvitationRepo
+}
+
+// NewRegistrationService 创建注册服务，注册模式或允许的邮箱域名配置错误时返回错误
+func NewRegistrationService(cfg *config.RegistrationConfig, invitations repository.InvitationRepo) (*RegistrationService, error) {
+	switch cfg.Mode {
+	case RegistrationOpen, RegistrationInvite:
+	case RegistrationDomain:
+		if len(cfg.AllowedDomains) == 0 {
+			return nil, fmt.Errorf("allowed_domains is required for domain registration mode")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported registration mode: %s", cfg.Mode)
+	}
+
+	return &RegistrationService{cfg: *cfg, invitations: invitations}, nil
+}
+
+// Mode 当前注册模式
+func (s *RegistrationService) Mode() string {
+	return s.cfg.Mode
+}
+
+// DomainAllowed 检查邮箱域名是否允许注册（包含子域名），非 domain 模式下始终允许
+func (s *RegistrationService) DomainAllowed(email string) bool {
+	if s.cfg.Mode != RegistrationDomain {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range s.cfg.AllowedDomains {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateInvitation 创建邀请，返回的明文邀请码仅在创建时可见
+// ttl 为0时使用配置的默认有效期
+func (s *RegistrationService) CreateInvitation(ctx context.Context, createdBy uint, email, role, group string, ttl time.Duration) (*models.Invitation, string, error) {
+	if ttl <= 0 {
+		ttl = time.Duration(s.cfg.InviteTTLDays) * 24 * time.Hour
+	}
+
+	token, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := models.Invitation{
+		Email:     email,
+		TokenHash: hashSecret(token),
+		Role:      role,
+		Group:     group,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.invitations.Create(ctx, &invitation); err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return &invitation, token, nil
+}
+
+// InviteURL 生成邀请邮件中的注册链接，未配置注册页面地址时返回空字符串
+func (s *RegistrationService) InviteURL(token string) string {
+	if s.cfg.InviteURL == "" {
+		return ""
+	}
+	separator := "?"
+	if strings.Contains(s.cfg.InviteURL, "?") {
+		separator = "&"
+	}
+	return s.cfg.InviteURL + separator + "invite=" + url.QueryEscape(token)
+}
+
+// HasSetPasswordURL 是否配置了设置密码页面地址
+func (s *RegistrationService) HasSetPasswordURL() bool {
+	return s.cfg.SetPasswordURL != ""
+}
+
+// SetPasswordURL 生成设置密码链接，未配置设置密码页面地址时返回空字符串
+func (s *RegistrationService) SetPasswordURL(email, token string) string {
+	if s.cfg.SetPasswordURL == "" {
+		return ""
+	}
+	separator := "?"
+	if strings.Contains(s.cfg.SetPasswordURL, "?") {
+		separator = "&"
+	}
+	return s.cfg.SetPasswordURL + separator + "email=" + url.QueryEscape(email) + "&token=" + url.QueryEscape(token)
+}
+
+// FindInvitation 查找可用的邀请，邀请邮箱必须与注册邮箱一致
+func (s *RegistrationService) FindInvitation(ctx context.Context, token, email string) (*models.Invitation, error) {
+	invitation, err := s.invitations.FindByTokenHash(ctx, hashSecret(token))
+	if err == repository.ErrNotFound {
+		return nil, ErrInvitationInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.UsedAt != nil || invitation.RevokedAt != nil || time.Now().After(invitation.ExpiresAt) ||
+		!strings.EqualFold(invitation.Email, email) {
+		return nil, ErrInvitationInvalid
+	}
+	return invitation, nil
+}
+
+// ListInvitations 分页获取邀请列表，按创建时间倒序
+func (s *RegistrationService) ListInvitations(ctx context.Context, filter repository.InvitationFilter) ([]models.Invitation, int64, error) {
+	return s.invitations.List(ctx, filter, time.Now())
+}
+
+// RevokeInvitation 吊销未使用的邀请，邀请不存在或已使用/已吊销时返回ErrInvitationInvalid
+func (s *RegistrationService) RevokeInvitation(ctx context.Context, id uint) error {
+	err := s.invitations.Revoke(ctx, id, time.Now())
+	if err == repository.ErrNotFound {
+		return ErrInvitationInvalid
+	}
+	return err
+}
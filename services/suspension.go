@@ -0,0 +1,245 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/logging"
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SuspensionManual 管理员手动暂停（不会被规则自动解除，到期或管理员解除后恢复）
+const SuspensionManual = "manual"
+
+// 自动暂停规则
+const (
+	RuleMembershipExpired = "membership_expired" // 读者资格到期
+	RuleLongOverdue       = "long_overdue"       // 图书长期逾期未还
+	RuleFineLimit         = "fine_limit"         // 未缴罚款超过上限
+)
+
+// suspensionSweepBatch 后台巡检每批处理的用户数
+const suspensionSweepBatch = 200
+
+// SuspensionRule 借阅权限自动暂停规则
+type SuspensionRule interface {
+	// Code 规则标识，记录在 user.suspension_rule 中
+	Code() string
+	// Check 检查用户是否触发规则，触发时返回暂停原因
+	Check(ctx context.Context, user *models.User, now time.Time) (reason string, triggered bool, err error)
+}
+
+// SuspensionService 借阅权限暂停服务
+// 触发规则时自动暂停借阅，规则不再触发（续期、归还图书）时自动解除
+type SuspensionService struct {
+	users    repository.UserRepo
+	rules    []SuspensionRule
+	interval time.Duration
+}
+
+var suspensionLog = logging.For("suspension")
+
+// NewSuspensionService 创建借阅权限暂停服务，后台巡检由 Run 启动
+func NewSuspensionService(cfg *config.SuspensionConfig, users repository.UserRepo, loans repository.LoanRepo) *SuspensionService {
+	rules := []SuspensionRule{membershipExpiredRule{}}
+	if cfg.OverdueDays > 0 {
+		rules = append(rules, longOverdueRule{loans: loans, days: cfg.OverdueDays})
+	}
+	if cfg.FineLimit > 0 {
+		// 系统暂未记录罚款，未缴罚款恒为0，该规则不会触发
+		rules = append(rules, fineLimitRule{fines: noFines{}, limit: cfg.FineLimit})
+	}
+
+	return &SuspensionService{
+		users:    users,
+		rules:    rules,
+		interval: time.Duration(cfg.CheckIntervalMinutes) * time.Minute,
+	}
+}
+
+// Evaluate 按规则重新评估用户的借阅权限，并更新传入的用户
+// 已禁用或已注销的用户不处理；手动暂停优先于规则，到期后才按规则评估
+func (s *SuspensionService) Evaluate(ctx context.Context, user *models.User) error {
+	if user.Status == models.UserStatusDisabled || user.AnonymizedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if user.Status == models.UserStatusSuspended && user.SuspensionRule == SuspensionManual {
+		if user.SuspendedUntil == nil || now.Before(endOfDay(*user.SuspendedUntil)) {
+			return nil
+		}
+	}
+
+	for _, rule := range s.rules {
+		reason, triggered, err := rule.Check(ctx, user, now)
+		if err != nil {
+			return fmt.Errorf("failed to check rule %s: %w", rule.Code(), err)
+		}
+		if !triggered {
+			continue
+		}
+		if user.Status == models.UserStatusSuspended && user.SuspensionRule == rule.Code() && user.SuspendedReason == reason {
+			return nil
+		}
+		suspensionLog.InfoContext(ctx, "暂停借阅", "user_id", user.ID, "rule", rule.Code(), "reason", reason)
+		return s.apply(ctx, user, models.UserStatusSuspended, reason, nil, rule.Code())
+	}
+
+	if user.Status == models.UserStatusSuspended {
+		suspensionLog.InfoContext(ctx, "恢复借阅", "user_id", user.ID, "rule", user.SuspensionRule)
+		return s.apply(ctx, user, models.UserStatusNormal, "", nil, "")
+	}
+	return nil
+}
+
+// Suspend 管理员手动暂停用户借阅，until为截止日期（含当天），为空表示直到管理员解除
+func (s *SuspensionService) Suspend(ctx context.Context, user *models.User, reason string, until *time.Time) error {
+	return s.apply(ctx, user, models.UserStatusSuspended, reason, until, SuspensionManual)
+}
+
+// Lift 管理员解除暂停，随后按规则重新评估（仍触发规则时会再次暂停）
+func (s *SuspensionService) Lift(ctx context.Context, user *models.User) error {
+	if user.Status == models.UserStatusSuspended {
+		if err := s.apply(ctx, user, models.UserStatusNormal, "", nil, ""); err != nil {
+			return err
+		}
+	}
+	return s.Evaluate(ctx, user)
+}
+
+// apply 更新用户的暂停状态
+func (s *SuspensionService) apply(ctx context.Context, user *models.User, status, reason string, until *time.Time, rule string) error {
+	err := s.users.Update(ctx, user, map[string]interface{}{
+		"status":           status,
+		"suspended_reason": reason,
+		"suspended_until":  until,
+		"suspension_rule":  rule,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update suspension: %w", err)
+	}
+	return nil
+}
+
+// Run 后台worker：定期巡检可能需要暂停或解除暂停的用户，ctx 取消后返回
+func (s *SuspensionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep 评估已暂停、资格已到期或有逾期图书的用户
+func (s *SuspensionService) sweep(ctx context.Context) {
+	now := time.Now()
+	var overdueBefore *time.Time
+	for _, rule := range s.rules {
+		if overdue, ok := rule.(longOverdueRule); ok {
+			cutoff := overdue.cutoff(now)
+			overdueBefore = &cutoff
+		}
+	}
+
+	var lastID uint
+	for {
+		users, err := s.users.ListSuspensionCandidates(ctx, lastID, now, overdueBefore, suspensionSweepBatch)
+		if err != nil {
+			suspensionLog.Error("巡检查询失败", "error", err)
+			return
+		}
+
+		for i := range users {
+			if err := s.Evaluate(ctx, &users[i]); err != nil {
+				suspensionLog.Error("评估借阅权限失败", "error", err, "user_id", users[i].ID)
+			}
+		}
+
+		if len(users) < suspensionSweepBatch {
+			return
+		}
+		lastID = users[len(users)-1].ID
+	}
+}
+
+// endOfDay 日期当天结束（次日零点）
+func endOfDay(date time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, date.Location())
+}
+
+// membershipExpiredRule 读者资格到期规则（到期日当天仍可借阅）
+type membershipExpiredRule struct{}
+
+func (membershipExpiredRule) Code() string { return RuleMembershipExpired }
+
+func (membershipExpiredRule) Check(ctx context.Context, user *models.User, now time.Time) (string, bool, error) {
+	if user.MembershipExpiresAt == nil || now.Before(endOfDay(*user.MembershipExpiresAt)) {
+		return "", false, nil
+	}
+	return fmt.Sprintf("读者资格已于%s到期", user.MembershipExpiresAt.Format(MembershipDateLayout)), true, nil
+}
+
+// longOverdueRule 图书逾期超过指定天数未归还规则
+type longOverdueRule struct {
+	loans repository.LoanRepo
+	days  int
+}
+
+func (longOverdueRule) Code() string { return RuleLongOverdue }
+
+func (r longOverdueRule) cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -r.days)
+}
+
+func (r longOverdueRule) Check(ctx context.Context, user *models.User, now time.Time) (string, bool, error) {
+	record, err := r.loans.OldestOverdue(ctx, user.ID, r.cutoff(now))
+	if err == repository.ErrNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("《%s》逾期超过%d天未归还", record.BookTitle, r.days), true, nil
+}
+
+// FineSource 用户未缴罚款查询
+type FineSource interface {
+	// UnpaidFines 用户未缴罚款总额（元）
+	UnpaidFines(ctx context.Context, userID uint) (float64, error)
+}
+
+// noFines 系统暂未记录罚款，未缴罚款恒为0（引入罚款记录后替换为实际查询）
+type noFines struct{}
+
+func (noFines) UnpaidFines(ctx context.Context, userID uint) (float64, error) { return 0, nil }
+
+// fineLimitRule 未缴罚款超过上限规则
+// 后台巡检只处理已暂停、资格到期或有逾期图书的用户，其他用户在借书时评估
+type fineLimitRule struct {
+	fines FineSource
+	limit float64
+}
+
+func (fineLimitRule) Code() string { return RuleFineLimit }
+
+func (r fineLimitRule) Check(ctx context.Context, user *models.User, now time.Time) (string, bool, error) {
+	amount, err := r.fines.UnpaidFines(ctx, user.ID)
+	if err != nil {
+		return "", false, err
+	}
+	if amount <= r.limit {
+		return "", false, nil
+	}
+	return fmt.Sprintf("未缴罚款%.2f元，超过上限%.2f元", amount, r.limit), true, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/models"
+	"book-manage/repository/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+// stubFines 固定金额的未缴罚款
+type stubFines float64
+
+func (f stubFines) UnpaidFines(ctx context.Context, userID uint) (float64, error) {
+	return float64(f), nil
+}
+
+func TestFineLimitRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		fines     FineSource
+		limit     float64
+		triggered bool
+	}{
+		{"no fines recorded", noFines{}, 10, false},
+		{"below limit", stubFines(5), 10, false},
+		{"at limit", stubFines(10), 10, false},
+		{"over limit", stubFines(10.5), 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := fineLimitRule{fines: tt.fines, limit: tt.limit}
+			reason, triggered, err := rule.Check(context.Background(), &models.User{ID: 1}, time.Now())
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if triggered != tt.triggered {
+				t.Fatalf("triggered = %v, want %v (reason %q)", triggered, tt.triggered, reason)
+			}
+		})
+	}
+}
+
+func TestFineLimitRuleConfigured(t *testing.T) {
+	store := memory.NewStore()
+	for _, tt := range []struct {
+		limit   float64
+		enabled bool
+	}{{0, false}, {20, true}} {
+		service := NewSuspensionService(&config.SuspensionConfig{FineLimit: tt.limit, CheckIntervalMinutes: 60},
+			store.Users, store.Loans)
+		enabled := false
+		for _, rule := range service.rules {
+			if rule.Code() == RuleFineLimit {
+				enabled = true
+			}
+		}
+		if enabled != tt.enabled {
+			t.Errorf("fine_limit=%v: rule enabled = %v, want %v", tt.limit, enabled, tt.enabled)
+		}
+	}
+
+	// 系统暂未记录罚款，启用规则后也不会暂停借阅
+	ctx := context.Background()
+	user := &models.User{Email: "reader@example.com", Status: models.UserStatusNormal}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	service := NewSuspensionService(&config.SuspensionConfig{FineLimit: 0.01}, store.Users, store.Loans)
+	if err := service.Evaluate(ctx, user); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if user.Status != models.UserStatusNormal {
+		t.Fatalf("status = %q, want normal", user.Status)
+	}
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"book-manage/repository"
+	"context"
+	"time"
+)
+
+// TokenService 登录token吊销服务
+// JWT本身无状态，吊销通过记录邮箱的吊销时间实现，认证时比较token的签发时间
+type TokenService struct {
+	revocations repository.TokenRepo
+}
+
+// NewTokenService 创建token吊销服务
+func NewTokenService(revocations repository.TokenRepo) *TokenService {
+	return &TokenService{revocations: revocations}
+}
+
+// RevokeEmail 吊销所有携带该邮箱、且在当前时间之前签发的token
+func (s *TokenService) RevokeEmail(ctx context.Context, email string) error {
+	return s.revocations.RevokeEmail(ctx, email, time.Now())
+}
+
+// IsRevoked 检查在issuedAt签发、携带该邮箱的token是否已被吊销
+// token签发时间精确到秒，吊销时间同一秒内签发的token也视为已吊销
+func (s *TokenService) IsRevoked(ctx context.Context, email string, issuedAt time.Time) (bool, error) {
+	return s.revocations.RevokedSince(ctx, email, issuedAt)
+}
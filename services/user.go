@@ -0,0 +1,117 @@
+package services
+
+import (
+	"book-manage/models"
+	"book-manage/repository"
+	"context"
+	"errors"
+)
+
+// ErrUserNotFound 用户不存在
+var ErrUserNotFound = errors.New("用户不存在")
+
+// ErrStudentIDTaken 学号已被其他用户使用
+var ErrStudentIDTaken = errors.New("学号已被使用")
+
+// ErrCardNumberTaken 借书卡号已被其他用户使用
+var ErrCardNumberTaken = errors.New("借书卡号已被使用")
+
+// UserService 用户资料服务
+type UserService struct {
+	users      repository.UserRepo
+	loans      repository.LoanRepo
+	suspension SuspensionEvaluator // 为空时不评估借阅权限
+}
+
+// NewUserService 创建用户资料服务
+func NewUserService(users repository.UserRepo, loans repository.LoanRepo, suspension SuspensionEvaluator) *UserService {
+	return &UserService{users: users, loans: loans, suspension: suspension}
+}
+
+// Get 获取用户，不存在时返回ErrUserNotFound
+func (s *UserService) Get(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err == repository.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// Profile 获取个人信息及当前借阅数量
+// 返回前按规则重新评估借阅权限，确保暂停原因是最新的
+func (s *UserService) Profile(ctx context.Context, userID uint) (*models.User, int64, error) {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if s.suspension != nil {
+		if err := s.suspension.Evaluate(ctx, user); err != nil {
+			suspensionLog.ErrorContext(ctx, "评估借阅权限失败", "error", err, "user_id", user.ID)
+		}
+	}
+
+	openLoans, err := s.loans.CountOpenByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return user, openLoans, nil
+}
+
+// GetManaged 获取未注销的用户（管理员维护读者资料时使用），不存在或已注销时返回ErrUserNotFound
+func (s *UserService) GetManaged(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Search 分页查询未注销的读者
+func (s *UserService) Search(ctx context.Context, filter repository.UserFilter) ([]models.User, int64, error) {
+	return s.users.Search(ctx, filter)
+}
+
+// Update 按列名更新用户资料，updates 为空时不更新
+// 学号、借书卡号已被其他用户使用时返回ErrStudentIDTaken、ErrCardNumberTaken；
+// 修改资格到期日后重新评估借阅权限（续期后可能解除暂停）
+func (s *UserService) Update(ctx context.Context, user *models.User, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	unique := []struct {
+		column string
+		err    error
+	}{
+		{"student_id", ErrStudentIDTaken},
+		{"card_number", ErrCardNumberTaken},
+	}
+	for _, u := range unique {
+		value, ok := updates[u.column].(string)
+		if !ok || value == "" {
+			continue
+		}
+		count, err := s.users.CountBy(ctx, u.column, value, user.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return u.err
+		}
+	}
+
+	if err := s.users.Update(ctx, user, updates); err != nil {
+		return err
+	}
+
+	if _, ok := updates["membership_expires_at"]; ok && s.suspension != nil {
+		if err := s.suspension.Evaluate(ctx, user); err != nil {
+			suspensionLog.ErrorContext(ctx, "评估借阅权限失败", "error", err, "user_id", user.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"book-manage/logging"
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/utils"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 批量导入参数
+const (
+	ImportMaxRows          = 1000 // 单次导入的最大行数（不含表头）
+	importBatchSize        = 100  // 每批查重、哈希和创建的行数
+	importPasswordLength   = 12   // 随机初始密码长度（密码策略要求更长时按策略最小长度生成）
+	importPasswordAttempts = 10   // 生成随机密码的最大尝试次数
+)
+
+// 初始密码方式
+const (
+	ImportPasswordRandom = "random" // 生成随机初始密码
+	ImportPasswordLink   = "link"   // 不设置密码，通过欢迎邮件中的链接设置密码
+)
+
+// 单行导入结果
+const (
+	ImportRowCreated = "created" // 已创建（dry_run 时表示校验通过）
+	ImportRowExists  = "exists"  // 邮箱或学号已存在，跳过
+	ImportRowInvalid = "invalid" // 数据错误，跳过
+	ImportRowFailed  = "failed"  // 创建失败
+)
+
+// importLog 批量导入日志
+var importLog = logging.For("import")
+
+// importColumns CSV表头与字段的对应关系（支持中英文表头）
+var importColumns = map[string]string{
+	"email":      "email",
+	"邮箱":         "email",
+	"name":       "name",
+	"姓名":         "name",
+	"student_id": "student_id",
+	"学号":         "student_id",
+	"group":      "group",
+	"分组":         "group",
+}
+
+// ImportOptions 批量导入选项
+type ImportOptions struct {
+	PasswordMode string // random 或 link
+	SendWelcome  bool   // 是否发送欢迎邮件（link 模式下必须发送）
+	DryRun       bool   // 仅校验，不创建用户
+	Locale       string // 欢迎邮件语言
+}
+
+// ImportRowResult 单行导入结果
+type ImportRowResult struct {
+	Row       int    `json:"row"` // CSV行号（表头为第1行）
+	Email     string `json:"email"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	UserID    uint   `json:"user_id,omitempty"`
+	Password  string `json:"password,omitempty"` // random 模式下的初始密码（仅本次返回）
+	EmailSent bool   `json:"email_sent"`
+}
+
+// ImportResult 批量导入结果
+type ImportResult struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// importRow 解析后的CSV行
+type importRow struct {
+	line      int
+	email     string
+	name      string
+	studentID string
+	group     string
+	password  string
+	hash      string
+}
+
+// ImportService 批量导入读者服务
+type ImportService struct {
+	users        repository.UserRepo
+	hasher       *PasswordHasher
+	policy       *PasswordPolicy
+	emails       *EmailService
+	registration *RegistrationService
+}
+
+// NewImportService 创建批量导入读者服务
+func NewImportService(users repository.UserRepo, hasher *PasswordHasher, policy *PasswordPolicy,
+	emails *EmailService, registration *RegistrationService) *ImportService {
+	return &ImportService{users: users, hasher: hasher, policy: policy, emails: emails, registration: registration}
+}
+
+// LinkModeAvailable 是否配置了设置密码页面地址（link 模式需要在欢迎邮件中附带设置密码链接）
+func (s *ImportService) LinkModeAvailable() bool {
+	return s.registration.HasSetPasswordURL()
+}
+
+// Import 从CSV批量导入读者
+// CSV需包含表头，email列必填，name、student_id、group列可选；每行独立校验和创建，单行失败不影响其他行
+func (s *ImportService) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	rows, result, err := parseImportCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = opts.DryRun
+
+	if err := s.validateRows(ctx, rows, result); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return finishImport(result), nil
+	}
+
+	pending := make([]*importRow, 0, len(rows))
+	for _, row := range rows {
+		if result.Rows[row.line-2].Status == ImportRowCreated {
+			pending = append(pending, row)
+		}
+	}
+
+	// 分批哈希密码并创建用户，避免一次性为全部行生成密码哈希
+	for start := 0; start < len(pending); start += importBatchSize {
+		batch := pending[start:min(start+importBatchSize, len(pending))]
+		if opts.PasswordMode == ImportPasswordRandom {
+			if err := s.hashPasswords(batch); err != nil {
+				return nil, err
+			}
+		}
+		for _, row := range batch {
+			s.createRow(ctx, row, &result.Rows[row.line-2], opts)
+		}
+	}
+
+	return finishImport(result), nil
+}
+
+// createRow 创建一行对应的用户，并按选项发送欢迎邮件
+func (s *ImportService) createRow(ctx context.Context, row *importRow, rowResult *ImportRowResult, opts ImportOptions) {
+	user := models.User{
+		Email:        row.email,
+		Password:     row.hash, // link 模式下为空，无法登录，需通过链接设置密码
+		Name:         row.name,
+		Group:        row.group,
+		Role:         RoleUser,
+		RegisterTime: time.Now(),
+		Status:       models.UserStatusNormal,
+	}
+	if row.studentID != "" {
+		studentID := row.studentID
+		user.StudentID = &studentID
+	}
+	if err := s.users.Create(ctx, &user); err != nil {
+		importLog.WarnContext(ctx, "创建用户失败", "error", err, "row", row.line)
+		rowResult.Status = ImportRowFailed
+		rowResult.Message = "创建用户失败（邮箱或学号可能已存在）"
+		return
+	}
+	rowResult.UserID = user.ID
+	rowResult.Password = row.password
+
+	if !opts.SendWelcome {
+		return
+	}
+	setPasswordURL := ""
+	if opts.PasswordMode == ImportPasswordLink {
+		token, err := s.emails.CreateSetPasswordToken(ctx, user.Email)
+		if err != nil {
+			importLog.ErrorContext(ctx, "生成设置密码链接失败", "error", err, "row", row.line)
+			rowResult.Message = "用户已创建，但生成设置密码链接失败"
+			return
+		}
+		setPasswordURL = s.registration.SetPasswordURL(user.Email, token)
+	}
+	if err := s.emails.SendWelcome(ctx, &user, row.password, setPasswordURL, opts.Locale); err != nil {
+		importLog.ErrorContext(ctx, "欢迎邮件入队失败", "error", err, "row", row.line)
+		rowResult.Message = "用户已创建，但欢迎邮件发送失败"
+		return
+	}
+	rowResult.EmailSent = true
+}
+
+// parseImportCSV 解析CSV，返回数据行及初始化的导入结果
+func parseImportCSV(r io.Reader) ([]*importRow, *ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV文件为空或格式错误")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := importColumns[name]; ok {
+			columns[field] = i
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, nil, fmt.Errorf("CSV表头缺少email列")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := []*importRow{}
+	result := &ImportResult{Rows: []ImportRowResult{}}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("CSV第%d行格式错误", line)
+		}
+		if len(rows) >= ImportMaxRows {
+			return nil, nil, fmt.Errorf("单次最多导入%d行", ImportMaxRows)
+		}
+
+		row := &importRow{
+			line:      line,
+			email:     field(record, "email"),
+			name:      field(record, "name"),
+			studentID: field(record, "student_id"),
+			group:     field(record, "group"),
+		}
+		rows = append(rows, row)
+		result.Rows = append(result.Rows, ImportRowResult{Row: line, Email: row.email, Status: ImportRowCreated})
+	}
+	result.Total = len(rows)
+
+	return rows, result, nil
+}
+
+// validateRows 校验每一行（格式、文件内重复），再分批检查数据库中是否已存在
+func (s *ImportService) validateRows(ctx context.Context, rows []*importRow, result *ImportResult) error {
+	seenEmails := make(map[string]int)
+	seenStudentIDs := make(map[string]int)
+	valid := make([]*importRow, 0, len(rows))
+
+	for _, row := range rows {
+		rowResult := &result.Rows[row.line-2]
+		fail := func(message string) {
+			rowResult.Status = ImportRowInvalid
+			rowResult.Message = message
+		}
+
+		switch {
+		case row.email == "" || !utils.ValidateEmail(row.email):
+			fail("邮箱格式错误")
+			continue
+		case utf8.RuneCountInString(row.name) > 100:
+			fail("姓名不能超过100个字符")
+			continue
+		case utf8.RuneCountInString(row.studentID) > 50:
+			fail("学号不能超过50个字符")
+			continue
+		case utf8.RuneCountInString(row.group) > 100:
+			fail("分组不能超过100个字符")
+			continue
+		}
+
+		if first, ok := seenEmails[row.email]; ok {
+			fail(fmt.Sprintf("邮箱与第%d行重复", first))
+			continue
+		}
+		seenEmails[row.email] = row.line
+		if row.studentID != "" {
+			if first, ok := seenStudentIDs[row.studentID]; ok {
+				fail(fmt.Sprintf("学号与第%d行重复", first))
+				continue
+			}
+			seenStudentIDs[row.studentID] = row.line
+		}
+		valid = append(valid, row)
+	}
+
+	for start := 0; start < len(valid); start += importBatchSize {
+		if err := s.markExisting(ctx, valid[start:min(start+importBatchSize, len(valid))], result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markExisting 将邮箱或学号已存在的行标记为跳过（每批每列一次查询）
+func (s *ImportService) markExisting(ctx context.Context, rows []*importRow, result *ImportResult) error {
+	emails := make([]string, 0, len(rows))
+	studentIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		emails = append(emails, row.email)
+		if row.studentID != "" {
+			studentIDs = append(studentIDs, row.studentID)
+		}
+	}
+
+	existingEmails, err := s.existing(ctx, "email", emails)
+	if err != nil {
+		return err
+	}
+	existingStudentIDs, err := s.existing(ctx, "student_id", studentIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		rowResult := &result.Rows[row.line-2]
+		switch {
+		case existingEmails[row.email]:
+			rowResult.Status = ImportRowExists
+			rowResult.Message = "邮箱已注册"
+		case row.studentID != "" && existingStudentIDs[row.studentID]:
+			rowResult.Status = ImportRowExists
+			rowResult.Message = "学号已存在"
+		}
+	}
+	return nil
+}
+
+// existing 查询指定列中已被使用的值
+func (s *ImportService) existing(ctx context.Context, column string, values []string) (map[string]bool, error) {
+	found, err := s.users.ExistingValues(ctx, column, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing %s: %w", column, err)
+	}
+	existing := make(map[string]bool, len(found))
+	for _, value := range found {
+		existing[value] = true
+	}
+	return existing, nil
+}
+
+// hashPasswords 为每行生成随机初始密码并并发计算哈希（bcrypt/argon2id 较慢）
+func (s *ImportService) hashPasswords(rows []*importRow) error {
+	for _, row := range rows {
+		password, err := randomPassword(s.policy, row.email)
+		if err != nil {
+			return err
+		}
+		row.password = password
+	}
+
+	jobs := make(chan *importRow)
+	errs := make(chan error, len(rows))
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range jobs {
+				hash, err := s.hasher.Hash(row.password)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				row.hash = hash
+			}
+		}()
+	}
+	for _, row := range rows {
+		jobs <- row
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return nil
+}
+
+// randomPassword 生成符合密码策略的随机初始密码（包含大小写字母、数字和符号）
+func randomPassword(policy *PasswordPolicy, email string) (string, error) {
+	const (
+		upper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		lower   = "abcdefghijkmnpqrstuvwxyz"
+		digits  = "23456789"
+		symbols = "!@#$%^&*-_"
+	)
+	classes := []string{upper, lower, digits, symbols}
+	all := upper + lower + digits + symbols
+
+	length := importPasswordLength
+	if policy != nil && policy.MinLength() > length {
+		length = policy.MinLength()
+	}
+
+	for attempt := 0; attempt < importPasswordAttempts; attempt++ {
+		buf := make([]byte, length)
+		for i := range buf {
+			charset := all
+			if i < len(classes) {
+				charset = classes[i] // 前4位保证每类字符至少出现一次
+			}
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+			if err != nil {
+				return "", fmt.Errorf("failed to generate password: %w", err)
+			}
+			buf[i] = charset[n.Int64()]
+		}
+		// 打乱顺序
+		for i := len(buf) - 1; i > 0; i-- {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+			if err != nil {
+				return "", fmt.Errorf("failed to generate password: %w", err)
+			}
+			j := n.Int64()
+			buf[i], buf[j] = buf[j], buf[i]
+		}
+
+		password := string(buf)
+		if policy == nil || len(policy.Check(password, email)) == 0 {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate password satisfying the password policy after %d attempts", importPasswordAttempts)
+}
+
+// finishImport 统计导入结果
+func finishImport(result *ImportResult) *ImportResult {
+	for _, row := range result.Rows {
+		switch row.Status {
+		case ImportRowCreated:
+			result.Created++
+		case ImportRowFailed:
+			result.Failed++
+		default:
+			result.Skipped++
+		}
+	}
+	return result
+}
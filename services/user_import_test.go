@@ -0,0 +1,318 @@
+package services
+
+import (
+	"book-manage/config"
+	"book-manage/models"
+	"book-manage/repository"
+	"book-manage/repository/memory"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRandomPasswordFollowsPolicyMinLength(t *testing.T) {
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 20, MinCharClasses: 4})
+	password, err := randomPassword(policy, "reader@example.com")
+	if err != nil {
+		t.Fatalf("randomPassword: %v", err)
+	}
+	if n := utf8.RuneCountInString(password); n != 20 {
+		t.Fatalf("password length = %d, want 20", n)
+	}
+	if violations := policy.Check(password, "reader@example.com"); len(violations) != 0 {
+		t.Fatalf("password violates policy: %v", violations)
+	}
+}
+
+// importFixture 基于内存仓储的批量导入服务
+type importFixture struct {
+	store   *memory.Store
+	outbox  *memory.OutboxRepo
+	hasher  *PasswordHasher
+	emails  *EmailService
+	imports *ImportService
+}
+
+func newImportFixture(t *testing.T) *importFixture {
+	t.Helper()
+	hasher, err := NewPasswordHasher(&config.PasswordHashConfig{BcryptCost: 4})
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	policy := NewPasswordPolicy(&config.PasswordPolicyConfig{MinLength: 8, MinCharClasses: 2, SkipBreachedCheck: true})
+	registration, err := NewRegistrationService(&config.RegistrationConfig{
+		Mode:           RegistrationOpen,
+		SetPasswordURL: "https://example.com/setPassword",
+	}, nil)
+	if err != nil {
+		t.Fatalf("registration: %v", err)
+	}
+
+	store := memory.NewStore()
+	outboxRepo := memory.NewOutboxRepo()
+	outbox := NewOutboxService(outboxRepo, NewLogMailer(t.TempDir()))
+	emails := NewEmailService(&config.EmailConfig{From: "noreply@example.com"}, outbox,
+		NewTemplateService(memory.NewTemplateRepo()), memory.NewCodeRepo(), memory.NewCodeRecordRepo(), 5)
+
+	return &importFixture{
+		store:   store,
+		outbox:  outboxRepo,
+		hasher:  hasher,
+		emails:  emails,
+		imports: NewImportService(store.Users, hasher, policy, emails, registration),
+	}
+}
+
+// run 导入CSV，失败时终止测试
+func (f *importFixture) run(t *testing.T, csv string, opts ImportOptions) *ImportResult {
+	t.Helper()
+	result, err := f.imports.Import(context.Background(), strings.NewReader(csv), opts)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	return result
+}
+
+// statuses 每行的导入结果状态
+func statuses(result *ImportResult) []string {
+	out := make([]string, len(result.Rows))
+	for i, row := range result.Rows {
+		out[i] = row.Status
+	}
+	return out
+}
+
+func TestParseImportCSV(t *testing.T) {
+	manyRows := func(n int) string {
+		var b strings.Builder
+		b.WriteString("email\n")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&b, "reader%d@example.com\n", i)
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		csv     string
+		want    []importRow // 只比较 email、name、studentID、group、line
+		wantErr string
+	}{
+		{
+			name: "english header with BOM",
+			csv:  "\ufeffEmail,Name,Student_ID,Group\nzhang@example.com, 张三 ,2025001,计算机学院\n",
+			want: []importRow{{line: 2, email: "zhang@example.com", name: "张三", studentID: "2025001", group: "计算机学院"}},
+		},
+		{
+			name: "chinese header in any order, optional columns missing",
+			csv:  "姓名,邮箱\n李四,li@example.com\n王五,wang@example.com\n",
+			want: []importRow{
+				{line: 2, email: "li@example.com", name: "李四"},
+				{line: 3, email: "wang@example.com", name: "王五"},
+			},
+		},
+		{
+			name: "short rows",
+			csv:  "name,email,group\n赵六\n",
+			want: []importRow{{line: 2, name: "赵六"}},
+		},
+		{name: "max rows", csv: manyRows(ImportMaxRows), want: nil},
+		{name: "too many rows", csv: manyRows(ImportMaxRows + 1), wantErr: fmt.Sprintf("单次最多导入%d行", ImportMaxRows)},
+		{name: "missing email column", csv: "name,group\n张三,计算机学院\n", wantErr: "CSV表头缺少email列"},
+		{name: "empty file", csv: "", wantErr: "CSV文件为空或格式错误"},
+		{name: "malformed quote", csv: "email\n\"broken@example.com\n", wantErr: "CSV第2行格式错误"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, result, err := parseImportCSV(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if result.Total != len(rows) || len(result.Rows) != len(rows) {
+				t.Fatalf("total = %d, result rows = %d, parsed rows = %d", result.Total, len(result.Rows), len(rows))
+			}
+			if tt.want == nil {
+				return
+			}
+			if len(rows) != len(tt.want) {
+				t.Fatalf("rows = %d, want %d", len(rows), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := *rows[i]
+				got.password, got.hash = "", ""
+				if got != want {
+					t.Errorf("row %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []string
+	}{
+		{
+			name: "invalid email and overlong fields",
+			csv:  "email,name\nnot-an-email,A\nok@example.com," + strings.Repeat("名", 101) + "\n",
+			want: []string{ImportRowInvalid, ImportRowInvalid},
+		},
+		{
+			name: "duplicate email in file",
+			csv:  "email\na@example.com\nb@example.com\na@example.com\n",
+			want: []string{ImportRowCreated, ImportRowCreated, ImportRowInvalid},
+		},
+		{
+			name: "duplicate student id in file",
+			csv:  "email,student_id\na@example.com,001\nb@example.com,001\nc@example.com,\nd@example.com,\n",
+			want: []string{ImportRowCreated, ImportRowInvalid, ImportRowCreated, ImportRowCreated},
+		},
+		{
+			name: "existing email and student id",
+			csv:  "email,student_id\nexisting@example.com,999\nnew@example.com,S-EXISTING\nfresh@example.com,002\n",
+			want: []string{ImportRowExists, ImportRowExists, ImportRowCreated},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newImportFixture(t)
+			studentID := "S-EXISTING"
+			if err := f.store.Users.Create(context.Background(), &models.User{Email: "existing@example.com", StudentID: &studentID}); err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+
+			result := f.run(t, tt.csv, ImportOptions{PasswordMode: ImportPasswordRandom, DryRun: true})
+			if got := statuses(result); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("statuses = %v, want %v (rows %+v)", got, tt.want, result.Rows)
+			}
+		})
+	}
+}
+
+func TestImportDryRunCreatesNothing(t *testing.T) {
+	f := newImportFixture(t)
+	result := f.run(t, "email\na@example.com\nbad\n", ImportOptions{PasswordMode: ImportPasswordRandom, SendWelcome: true, DryRun: true})
+
+	if !result.DryRun || result.Total != 2 || result.Created != 1 || result.Skipped != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.Rows[0].UserID != 0 || result.Rows[0].Password != "" || result.Rows[0].EmailSent {
+		t.Fatalf("dry run row has side effects: %+v", result.Rows[0])
+	}
+	if count, _ := f.store.Users.CountBy(context.Background(), "email", "a@example.com", 0); count != 0 {
+		t.Fatalf("dry run created user")
+	}
+	if _, total, _ := f.outbox.List(context.Background(), repository.OutboxFilter{}); total != 0 {
+		t.Fatalf("dry run queued %d emails", total)
+	}
+}
+
+func TestImportPasswordModes(t *testing.T) {
+	tokenPattern := regexp.MustCompile(`token=([0-9a-f]+)`)
+
+	tests := []struct {
+		name        string
+		mode        string
+		sendWelcome bool
+	}{
+		{"random without welcome", ImportPasswordRandom, false},
+		{"random with welcome", ImportPasswordRandom, true},
+		{"link", ImportPasswordLink, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newImportFixture(t)
+			ctx := context.Background()
+			result := f.run(t, "email,name\nreader@example.com,读者\n", ImportOptions{PasswordMode: tt.mode, SendWelcome: tt.sendWelcome})
+
+			row := result.Rows[0]
+			if result.Created != 1 || row.Status != ImportRowCreated || row.UserID == 0 {
+				t.Fatalf("result = %+v", result)
+			}
+			if row.EmailSent != tt.sendWelcome {
+				t.Fatalf("email_sent = %v, want %v", row.EmailSent, tt.sendWelcome)
+			}
+			user, err := f.store.Users.FindByID(ctx, row.UserID)
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+			if user.Status != models.UserStatusNormal || user.Role != RoleUser || user.Name != "读者" {
+				t.Fatalf("user = %+v", user)
+			}
+
+			mails, _, _ := f.outbox.List(ctx, repository.OutboxFilter{})
+			if tt.sendWelcome != (len(mails) == 1) {
+				t.Fatalf("queued %d emails, send_welcome=%v", len(mails), tt.sendWelcome)
+			}
+
+			switch tt.mode {
+			case ImportPasswordRandom:
+				if row.Password == "" || !f.hasher.Verify(user.Password, row.Password) {
+					t.Fatalf("returned password does not match the stored hash")
+				}
+				if tt.sendWelcome && !strings.Contains(mails[0].TextBody, row.Password) {
+					t.Fatalf("welcome email does not contain the initial password")
+				}
+			case ImportPasswordLink:
+				if row.Password != "" || user.Password != "" {
+					t.Fatalf("link mode set a password: row=%q hash=%q", row.Password, user.Password)
+				}
+				match := tokenPattern.FindStringSubmatch(mails[0].TextBody)
+				if match == nil {
+					t.Fatalf("welcome email has no set-password link: %s", mails[0].TextBody)
+				}
+				// 错误的token不会使链接作废
+				for i := 0; i < 10; i++ {
+					if f.emails.VerifySetPasswordToken(ctx, user.Email, "wrong") {
+						t.Fatalf("wrong token accepted")
+					}
+				}
+				if !f.emails.VerifySetPasswordToken(ctx, user.Email, match[1]) {
+					t.Fatalf("set-password token rejected after wrong attempts")
+				}
+				if f.emails.VerifySetPasswordToken(ctx, user.Email, match[1]) {
+					t.Fatalf("set-password token reused")
+				}
+			}
+		})
+	}
+}
+
+func TestImportSpansBatches(t *testing.T) {
+	f := newImportFixture(t)
+	ctx := context.Background()
+	if err := f.store.Users.Create(ctx, &models.User{Email: "reader150@example.com"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("email\n")
+	n := importBatchSize*2 + 50
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "reader%d@example.com\n", i)
+	}
+	result := f.run(t, b.String(), ImportOptions{PasswordMode: ImportPasswordRandom})
+
+	if result.Total != n || result.Created != n-1 || result.Skipped != 1 {
+		t.Fatalf("result: total=%d created=%d skipped=%d", result.Total, result.Created, result.Skipped)
+	}
+	if row := result.Rows[150]; row.Status != ImportRowExists {
+		t.Fatalf("row 150 = %+v, want exists", row)
+	}
+	for _, row := range result.Rows {
+		if row.Status == ImportRowCreated && row.Password == "" {
+			t.Fatalf("row %d created without a password", row.Row)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"book-manage/models"
+	"time"
+)
+
+// MembershipDateLayout 读者资格到期日格式
+const MembershipDateLayout = "2006-01-02"
+
+// UserProfile 用户资料（个人信息、管理员读者列表和个人数据导出共用）
+func UserProfile(user *models.User) map[string]interface{} {
+	var membershipExpiresAt, suspendedUntil string
+	if user.MembershipExpiresAt != nil {
+		membershipExpiresAt = user.MembershipExpiresAt.Format(MembershipDateLayout)
+	}
+	if user.SuspendedUntil != nil {
+		suspendedUntil = user.SuspendedUntil.Format(MembershipDateLayout)
+	}
+
+	return map[string]interface{}{
+		"id":                    user.ID,
+		"email":                 user.Email,
+		"role":                  user.Role,
+		"register_time":         user.RegisterTime.Format("2006-01-02 15:04:05"),
+		"status":                user.Status,
+		"name":                  user.Name,
+		"phone":                 user.Phone,
+		"student_id":            user.StudentID,
+		"card_number":           user.CardNumber,
+		"group":                 user.Group,
+		"membership_expires_at": membershipExpiresAt,
+		"suspended_reason":      user.SuspendedReason,
+		"suspended_until":       suspendedUntil,
+		"suspension_rule":       user.SuspensionRule,
+	}
+}
+
+// ParseMembershipExpiry 解析读者资格到期日（或暂停截止日期），空字符串表示长期有效
+func ParseMembershipExpiry(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(MembershipDateLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
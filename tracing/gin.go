@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"book-manage/logging"
+	"book-manage/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths 探针和指标采集接口，请求频繁且没有排查价值，不创建span
+var untracedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Middleware 为每个请求创建服务端span，名称为 "方法 路由模板"（如 POST /api/borrow/borrow）
+// 请求头中携带 traceparent 时作为上游span的子span；span 写入 c.Request 的 context，供后续处理传递
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if untracedPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+			semconv.UserAgentOriginal(c.Request.UserAgent()),
+		}
+		if route != "" {
+			attrs = append(attrs, semconv.HTTPRoute(route))
+		}
+		if id := logging.RequestID(ctx); id != "" {
+			attrs = append(attrs, attribute.String("app.request_id", id))
+		}
+
+		ctx, span := Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if code, ok := c.Get(utils.ResponseCodeKey); ok {
+			span.SetAttributes(attribute.Int("app.response.code", code.(int)))
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(uint); ok {
+				span.SetAttributes(attribute.Int64("app.user_id", int64(id)))
+			}
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
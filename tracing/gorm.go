@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey span 在 gorm.Statement 中的存储键
+const gormSpanKey = "tracing:span"
+
+// gormSpan 执行中的SQL对应的span，以及创建span前的 context（执行结束后恢复）
+type gormSpan struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// GormPlugin GORM 插件，为每条SQL创建客户端span（db.query.text 为带占位符的SQL，不包含参数值）
+// 只在已有span的 context 中创建（即通过 db.WithContext 传入请求或后台任务的 context），
+// 启动迁移、后台轮询等不属于任何链路的查询不产生span
+type GormPlugin struct{}
+
+// Name 插件名称
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize 在 create、query、update、delete、row、raw 回调前后注册span的创建和结束
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []struct {
+		name   string
+		before func(name string, fn func(*gorm.DB)) error
+		after  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range registrations {
+		if err := r.before("tracing:before_"+r.name, startGormSpan(r.name)); err != nil {
+			return err
+		}
+		if err := r.after("tracing:after_"+r.name, endGormSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startGormSpan 创建SQL的span，并将其写入 Statement 的 context（驱动层的调用可作为子span）
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if parent == nil || !trace.SpanContextFromContext(parent).IsValid() {
+			return
+		}
+
+		ctx, span := Tracer().Start(parent, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, &gormSpan{span: span, parent: parent})
+	}
+}
+
+// endGormSpan 记录SQL、表名和影响行数后结束span（记录不存在不视为错误）
+func endGormSpan(db *gorm.DB) {
+	value, _ := db.InstanceGet(gormSpanKey)
+	s, ok := value.(*gormSpan)
+	if !ok || s == nil {
+		return
+	}
+	// 同一个 Statement 可能被再次执行，清除已结束的span
+	db.InstanceSet(gormSpanKey, (*gormSpan)(nil))
+	db.Statement.Context = s.parent
+
+	attrs := []attribute.KeyValue{
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	}
+	if db.Statement.Table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(db.Statement.Table))
+	}
+	s.span.SetAttributes(attrs...)
+
+	var err error
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		err = db.Error
+	}
+	End(s.span, err)
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newRecorder 使用内存记录器作为全局 TracerProvider，测试结束后恢复
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// spanAttr 获取span的属性值
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRequestAndQuerySpans(t *testing.T) {
+	recorder := newRecorder(t)
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatalf("register plugin: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE book (id INTEGER PRIMARY KEY, title TEXT)").Error; err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/books/:id", func(c *gin.Context) {
+		tx := db.WithContext(c.Request.Context())
+		var count int64
+		tx.Table("book").Where("id = ?", c.Param("id")).Count(&count)
+		tx.Exec("SELECT * FROM missing_table")
+		c.Status(http.StatusInternalServerError)
+	})
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans (request, count, raw), got %d", len(spans))
+	}
+	server := spans[2]
+	if server.Name() != "GET /books/:id" {
+		t.Errorf("server span name = %q", server.Name())
+	}
+	if server.Parent().SpanID().String() != "00f067aa0ba902b7" || server.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("server span should continue the incoming trace, parent = %s", server.Parent().SpanID())
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("5xx response should mark the span as error")
+	}
+	if route, _ := spanAttr(server, "http.route"); route.AsString() != "/books/:id" {
+		t.Errorf("http.route = %q", route.AsString())
+	}
+
+	count, raw := spans[0], spans[1]
+	for _, span := range []sdktrace.ReadOnlySpan{count, raw} {
+		if span.Parent().SpanID() != server.SpanContext().SpanID() {
+			t.Errorf("%s should be a child of the request span", span.Name())
+		}
+	}
+	if count.Name() != "gorm.query" || count.Status().Code == codes.Error {
+		t.Errorf("count span = %s %v", count.Name(), count.Status())
+	}
+	if table, _ := spanAttr(count, "db.collection.name"); table.AsString() != "book" {
+		t.Errorf("db.collection.name = %q", table.AsString())
+	}
+	if query, _ := spanAttr(count, "db.query.text"); query.AsString() == "" {
+		t.Errorf("db.query.text should be recorded")
+	}
+	if raw.Name() != "gorm.raw" || raw.Status().Code != codes.Error {
+		t.Errorf("failed query span = %s %v", raw.Name(), raw.Status())
+	}
+
+	// 不在链路中的查询不创建span
+	db.Exec("SELECT 1")
+	if got := len(recorder.Ended()); got != 3 {
+		t.Errorf("queries without a parent span should not be traced, got %d spans", got)
+	}
+}
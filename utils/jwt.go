@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/sha256"
 	"errors"
 	"time"
 
@@ -18,6 +19,7 @@ type Claims struct {
 }
 
 // GenerateToken 生成JWT token
+// token 中不携带权限，权限在每次请求时按用户当前角色解析，修改角色后立即生效
 func GenerateToken(userID uint, email, role string) (string, error) {
 	claims := Claims{
 		UserID: userID,
@@ -54,3 +56,50 @@ func ParseToken(tokenString string) (*Claims, error) {
 func SetJWTSecret(secret string) {
 	jwtSecret = []byte(secret)
 }
+
+// LoginLinkClaims 一次性登录链接的claims
+// Nonce 保存在验证码存储中，链接被使用后即失效
+type LoginLinkClaims struct {
+	Email string `json:"email"`
+	Nonce string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// loginLinkSecret 登录链接的签名密钥（由JWT密钥派生）
+// 与登录token使用不同的密钥，登录链接无法被当作登录token使用，反之亦然
+func loginLinkSecret() []byte {
+	sum := sha256.Sum256(append([]byte("login-link:"), jwtSecret...))
+	return sum[:]
+}
+
+// GenerateLoginLinkToken 生成一次性登录链接token
+func GenerateLoginLinkToken(email, nonce string, ttl time.Duration) (string, error) {
+	claims := LoginLinkClaims{
+		Email: email,
+		Nonce: nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(loginLinkSecret())
+}
+
+// ParseLoginLinkToken 解析并校验登录链接token的签名和有效期
+func ParseLoginLinkToken(tokenString string) (*LoginLinkClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &LoginLinkClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return loginLinkSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*LoginLinkClaims); ok && token.Valid && claims.Email != "" && claims.Nonce != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid login link")
+}
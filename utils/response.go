@@ -6,6 +6,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ResponseCodeKey 响应的业务码在 gin.Context 中的键（请求指标使用）
+const ResponseCodeKey = "response_code"
+
 // Response 统一响应结构
 type Response struct {
 	Code    int         `json:"code"`
@@ -15,6 +18,7 @@ type Response struct {
 
 // Success 成功响应
 func Success(c *gin.Context, data interface{}) {
+	c.Set(ResponseCodeKey, 0)
 	c.JSON(http.StatusOK, Response{
 		Code:    0,
 		Message: "success",
@@ -24,6 +28,7 @@ func Success(c *gin.Context, data interface{}) {
 
 // Error 错误响应
 func Error(c *gin.Context, code int, message string) {
+	c.Set(ResponseCodeKey, code)
 	c.JSON(http.StatusOK, Response{
 		Code:    code,
 		Message: message,
@@ -33,6 +38,7 @@ func Error(c *gin.Context, code int, message string) {
 
 // ErrorWithData 带数据的错误响应
 func ErrorWithData(c *gin.Context, code int, message string, data interface{}) {
+	c.Set(ResponseCodeKey, code)
 	c.JSON(http.StatusOK, Response{
 		Code:    code,
 		Message: message,
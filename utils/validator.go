@@ -3,6 +3,7 @@ package utils
 import (
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // ValidateEmail 验证邮箱格式
@@ -11,9 +12,9 @@ func ValidateEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
-// ValidatePassword 验证密码长度
+// ValidatePassword 验证密码长度（按字符计，完整的密码策略见 services.PasswordPolicy）
 func ValidatePassword(password string) bool {
-	return len(password) >= 8
+	return utf8.RuneCountInString(password) >= 8
 }
 
 // ValidateKeyword 验证搜索关键词长度
@@ -21,3 +22,15 @@ func ValidateKeyword(keyword string) bool {
 	keyword = strings.TrimSpace(keyword)
 	return keyword == "" || len(keyword) >= 2
 }
+
+// ValidatePhone 验证联系电话格式（允许国际区号前缀 +、数字、空格和短横线）
+func ValidatePhone(phone string) bool {
+	phoneRegex := regexp.MustCompile(`^\+?[0-9][0-9 \-]{4,19}$`)
+	return phoneRegex.MatchString(phone)
+}
+
+// ValidateCardNumber 验证借书卡号格式（字母、数字和短横线）
+func ValidateCardNumber(cardNumber string) bool {
+	cardRegex := regexp.MustCompile(`^[A-Za-z0-9\-]{1,50}$`)
+	return cardRegex.MatchString(cardNumber)
+}